@@ -2,17 +2,26 @@ package app
 
 import (
 	"context"
+	gwruntime "github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	rkboot "github.com/rookie-ninja/rk-boot"
 	rkgrpc "github.com/rookie-ninja/rk-grpc/boot"
 	"google.golang.org/grpc"
 	"log/slog"
 	grpcapp "sso/internal/app/grpc"
 	"sso/internal/config"
+	admintransport "sso/internal/grpc_transport/admin"
 	authtransport "sso/internal/grpc_transport/auth"
+	oauth2transport "sso/internal/http_transport/oauth2"
+	"sso/internal/lib/gateway"
+	jwtlib "sso/internal/lib/jwt"
+	"sso/internal/lib/mailer"
+	"sso/internal/lib/ratelimit"
 	"sso/internal/services/auth_service"
 	authgen "sso/protos/proto/sso/gen"
+	"sso/storage"
 	patroni "sso/storage/patroni"
-	redis "sso/storage/redis-sentinel"
+	redis "sso/storage/redis"
+	redissentinel "sso/storage/redis-sentinel"
 )
 
 type App struct {
@@ -32,41 +41,82 @@ func New(
 		panic(err)
 	}
 	//init cache
-	tokenCache := redis.New(cfg)
+	tokenCache := newCache(cfg)
+	//init keys to sign tokens
+	keyRing, err := jwtlib.NewKeyRing(cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	//init outbound email, it is shared by every instance of the service
+	mail, err := mailer.New(cfg, log)
+	if err != nil {
+		panic(err)
+	}
 
 	//init auth_service service (auth_service)
-	authService := auth_service.New(log, storage, tokenCache, cfg)
+	authService := auth_service.New(log, auth_service.Storages{
+		User:     storage,
+		App:      storage,
+		Token:    tokenCache,
+		Session:  tokenCache,
+		AuthCode: tokenCache,
+		Role:     storage,
+		Tuple:    storage,
+		Org:      storage,
+		Group:    storage,
+		Attempt:  tokenCache,
+	}, mail, keyRing, cfg)
 
 	boot := rkboot.NewBoot()
 	// Get grpc entry with name
 	grpcEntry := boot.GetEntry("sso").(*rkgrpc.GrpcEntry)
 	// Register grpc registration function
-	registerAuth := registerGreeterFunc(log, cfg)
+	registerAuth := registerGreeterFunc(authService)
 	grpcEntry.AddRegFuncGrpc(registerAuth)
+	// limit calls per method, buckets are shared by instances through redis
+	limiter := ratelimit.New(log, cfg.RateLimit, tokenCache, authService.Keyfunc, gateway.MustTrustedProxies(cfg.TrustedProxies))
+	grpcEntry.AddUnaryInterceptors(limiter.UnaryServerInterceptor())
 	// Register grpc-gateway registration function
 	grpcEntry.AddRegFuncGw(authgen.RegisterAuthHandlerFromEndpoint)
+	grpcEntry.AddRegFuncGw(authgen.RegisterAdminHandlerFromEndpoint)
+	// OAuth 2.0 endpoints accept form encoded bodies
+	grpcEntry.GwMuxOptions = append(grpcEntry.GwMuxOptions,
+		gwruntime.WithMarshalerOption(gateway.MIMEForm, gateway.NewFormMarshaler()))
+	// Register OAuth 2.0 authorization code flow, it needs redirects and html pages
+	oauth2transport.Register(grpcEntry.HttpMux, log, authService, cfg)
 
 	// Bootstrap
 	boot.Bootstrap(context.Background())
 
 	// Wait for shutdown sig
 	boot.WaitForShutdownSig(context.Background())
+	// deliver emails queued before shutdown
+	mail.Close()
 
-	grpcApp := grpcapp.New(log, authService, cfg)
+	grpcApp := grpcapp.New(log, authService, authService, cfg)
 	return &App{
 		GRPCSrv: grpcApp,
 	}
 }
 
-func registerGreeterFunc(log *slog.Logger, cfg *config.Config) func(server *grpc.Server) {
+// newCache connects to redis chosen by redis_mode
+func newCache(cfg *config.Config) storage.Cache {
+	switch cfg.RedisMode {
+	case "standalone":
+		return redis.New(cfg)
+	case "sentinel", "":
+		return redissentinel.New(cfg)
+	default:
+		panic("unknown redis_mode: " + cfg.RedisMode)
+	}
+}
+
+// registerGreeterFunc registers services on the grpc server. The same Auth
+// serves grpc and OAuth 2.0 pages, so both share its decision cache.
+func registerGreeterFunc(authService *auth_service.Auth) func(server *grpc.Server) {
 	return func(server *grpc.Server) { // Use the provided server
-		storage, err := patroni.New(cfg) // Use cfg from the closure
-		if err != nil {
-			log.Error("Failed to create storage", "error", err) // Use log from the closure
-			panic(err)
-		}
-		tokenCache := redis.New(cfg)                                   // Use cfg from the closure
-		authService := auth_service.New(log, storage, tokenCache, cfg) // Use log and cfg from the closure
-		authtransport.Register(server, authService)                    // Register the service on the provided server
+		authtransport.Register(server, authService) // Register the service on the provided server
+		admintransport.Register(server, authService)
 	}
 }
@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"net"
 	"sso/internal/config"
+	admintransport "sso/internal/grpc_transport/admin"
 	authtransport "sso/internal/grpc_transport/auth"
 	authservice "sso/internal/services/auth_service"
 )
@@ -20,10 +21,12 @@ type App struct {
 func New(
 	log *slog.Logger,
 	authService authservice.AuthorizationInterface,
+	adminService authservice.AdminInterface,
 	cfg *config.Config,
 ) *App {
 	gRPCServer := grpc.NewServer()
 	authtransport.Register(gRPCServer, authService)
+	admintransport.Register(gRPCServer, adminService)
 	return &App{
 		log:        log,
 		gRPCServer: gRPCServer,
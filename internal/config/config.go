@@ -25,12 +25,163 @@ type StoragePatroniConfig struct {
 	Slave  string `yaml:"slave"`
 }
 
+// SigningKeyConfig describes a key used to sign jwt tokens.
+// HS256 keys use secret (service_secret if empty), asymmetric keys
+// (RS256, ES256, EdDSA) are read from a PEM encoded private key file.
+// The key signs tokens from not_before and verifies them until not_after,
+// zero values mean no limit.
+type SigningKeyConfig struct {
+	Kid            string    `yaml:"kid"`
+	Algorithm      string    `yaml:"algorithm"`
+	Secret         string    `yaml:"secret"`
+	PrivateKeyPath string    `yaml:"private_key_path"`
+	NotBefore      time.Time `yaml:"not_before"`
+	NotAfter       time.Time `yaml:"not_after"`
+}
+
+// OIDCConfig describes the service as an OpenID Connect provider.
+type OIDCConfig struct {
+	// public base url of the service, "iss" claim of every token
+	// and prefix of endpoints published in discovery document
+	Issuer string `yaml:"issuer" env-default:"http://localhost:44044"`
+	// "aud" claim of tokens issued without a particular app
+	Audience string `yaml:"audience" env-default:"sso"`
+	// lifetime of authorization codes, RFC 6749 recommends at most 10 minutes
+	AuthCodeTtl time.Duration `yaml:"auth_code_ttl" env-default:"1m"`
+}
+
+// AuthzConfig tunes permission checks.
+type AuthzConfig struct {
+	// decisions are invalidated by role changes made on this instance,
+	// ttl bounds how long other instances may serve stale decisions
+	DecisionCacheTtl time.Duration `yaml:"decision_cache_ttl" env-default:"1m"`
+	// cache is dropped as a whole when it grows over this number of users
+	DecisionCacheSize int `yaml:"decision_cache_size" env-default:"10000"`
+	// userset rewrites of relation tuples: object type -> relation ->
+	// relations implying it, e.g. calc_task: {viewer: [editor]} means
+	// every editor of a task is its viewer as well
+	RelationRewrites map[string]map[string][]string `yaml:"relation_rewrites"`
+}
+
+// EmailVerificationConfig describes verification of emails of new accounts.
+type EmailVerificationConfig struct {
+	// refuse Login of accounts which haven't verified their email yet
+	RequireVerified bool `yaml:"require_verified" env-default:"false"`
+	// lifetime of verification links
+	TokenTtl time.Duration `yaml:"token_ttl" env-default:"24h"`
+	// verification email is sent to an account at most once per interval
+	ResendInterval time.Duration `yaml:"resend_interval" env-default:"1m"`
+}
+
+// EmailChangeConfig describes change of email of an account, it is
+// confirmed by a link sent to the new address.
+type EmailChangeConfig struct {
+	// lifetime of confirmation links
+	TokenTtl time.Duration `yaml:"token_ttl" env-default:"1h"`
+	// confirmation email is sent to an account at most once per interval
+	RequestInterval time.Duration `yaml:"request_interval" env-default:"1m"`
+}
+
+// PasswordResetConfig describes recovery of accounts by email.
+type PasswordResetConfig struct {
+	// page of the frontend with new password form, token of the reset
+	// link is passed to it as "token" query parameter
+	PageUrl string `yaml:"page_url" env-default:"http://localhost:44044/password/reset"`
+	// lifetime of reset links
+	TokenTtl time.Duration `yaml:"token_ttl" env-default:"1h"`
+	// reset email is sent to an account at most once per interval
+	RequestInterval time.Duration `yaml:"request_interval" env-default:"1m"`
+}
+
+// LockoutConfig describes protection of Login against password guessing.
+// Failed attempts are counted per account and per client ip, once a
+// counter reaches its threshold logins are locked for BaseDelay, the
+// lock doubles with every further failure up to MaxDelay.
+type LockoutConfig struct {
+	Enabled bool `yaml:"enabled" env-default:"true"`
+	// failures of an account before it is locked
+	AccountThreshold int `yaml:"account_threshold" env-default:"5"`
+	// failures from an ip before it is locked, many users may share an ip
+	IPThreshold int `yaml:"ip_threshold" env-default:"50"`
+	// counters are forgotten after window without failures
+	Window    time.Duration `yaml:"window" env-default:"15m"`
+	BaseDelay time.Duration `yaml:"base_delay" env-default:"1m"`
+	MaxDelay  time.Duration `yaml:"max_delay" env-default:"1h"`
+}
+
+// RateLimitConfig describes limits of rpc calls, methods are keyed by full
+// grpc method name, e.g. /auth.Auth/Login. Methods without a rule aren't
+// limited.
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled" env-default:"true"`
+	// redis shares limits between instances, memory limits every instance
+	// on its own. Memory is used as fallback while redis is unavailable.
+	Backend string                   `yaml:"backend" env-default:"redis"`
+	Methods map[string]RateLimitRule `yaml:"methods"`
+}
+
+// RateLimitRule is a token bucket refilled with Requests tokens per Per
+type RateLimitRule struct {
+	Requests int           `yaml:"requests"`
+	Per      time.Duration `yaml:"per"`
+	// size of the bucket, Requests if empty
+	Burst int `yaml:"burst"`
+	// ip, client_id or user_id, calls without client id or user fall back to ip
+	Key string `yaml:"key"`
+}
+
+// PasswordPolicyConfig describes rules new passwords must satisfy
+type PasswordPolicyConfig struct {
+	MinLength int `yaml:"min_length" env-default:"8"`
+	// bcrypt ignores everything past 72 bytes, so larger values are lowered to 72
+	MaxBytes       int  `yaml:"max_bytes" env-default:"72"`
+	RequireLower   bool `yaml:"require_lower" env-default:"true"`
+	RequireUpper   bool `yaml:"require_upper" env-default:"true"`
+	RequireDigit   bool `yaml:"require_digit" env-default:"true"`
+	RequireSpecial bool `yaml:"require_special" env-default:"false"`
+	// rejects passwords containing local part of the email
+	RejectEmail bool `yaml:"reject_email" env-default:"true"`
+	// SHA-1 hashes of breached passwords, one per line, as downloaded from
+	// haveibeenpwned. Empty disables the check.
+	BreachedFile string `yaml:"breached_file"`
+}
+
+// SMTPConfig describes SMTP server emails are sent through.
+type SMTPConfig struct {
+	Host string `yaml:"host" env-default:"localhost"`
+	Port int    `yaml:"port" env-default:"1025"`
+	// no authentication if empty, e.g. for MailHog
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// MailerConfig describes delivery of emails. Emails are sent in background,
+// failed deliveries are retried with exponential backoff.
+type MailerConfig struct {
+	// smtp, file (one .eml file per message in dir) or log (subjects only)
+	Driver string     `yaml:"driver" env-default:"log"`
+	From   string     `yaml:"from" env-default:"sso@localhost"`
+	SMTP   SMTPConfig `yaml:"smtp"`
+	Dir    string     `yaml:"dir" env-default:"./mail"`
+	// locale of emails to clients whose Accept-Language isn't supported
+	DefaultLocale string        `yaml:"default_locale" env-default:"en"`
+	QueueSize     int           `yaml:"queue_size" env-default:"100"`
+	Workers       int           `yaml:"workers" env-default:"2"`
+	MaxAttempts   int           `yaml:"max_attempts" env-default:"5"`
+	RetryBackoff  time.Duration `yaml:"retry_backoff" env-default:"1s"`
+	// notify users about every new sign-in
+	NotifyNewLogin bool `yaml:"notify_new_login" env-default:"false"`
+}
+
 type Config struct {
 	// without this param will be used "local" as param value
 	Env             string        `yaml:"env" env-default:"local"`
 	AccessTokenTtl  time.Duration `yaml:"access_token_ttl"  env-required:"true"`
 	RefreshTokenTtl time.Duration `yaml:"refresh_token_ttl"  env-required:"true"`
-	RedisAddress    string        `yaml:"redis_address"`
+	// sentinel or standalone, standalone redis is reached at RedisAddress
+	RedisMode     string `yaml:"redis_mode" env-default:"sentinel"`
+	RedisAddress  string `yaml:"redis_address"`
+	RedisPassword string `yaml:"redis_password"`
 	// without this param can't work
 	StoragePath    string               `yaml:"storage_path"`
 	ServiceSecret  string               `yaml:"service_secret" env-required:"true"`
@@ -38,6 +189,24 @@ type Config struct {
 	RedisSentinel  RedisSentinelConfig  `yaml:"redis_sentinel"`
 	StoragePatroni StoragePatroniConfig `yaml:"storage_patroni"`
 	JaegerUrl      string               `yaml:"jaeger_url"`
+	// proxies allowed to pass address of the client in X-Forwarded-For,
+	// grpc-gateway calls the service over loopback
+	TrustedProxies []string `yaml:"trusted_proxies" env-default:"127.0.0.1/32,::1/128"`
+	// key ring, the newest key in its validity window signs tokens,
+	// the rest are kept to verify tokens issued before rotation
+	SigningKeys []SigningKeyConfig `yaml:"signing_keys"`
+	// tokens without kid, signed with service_secret before key rotation
+	// was introduced, are verified until this moment, zero means no limit
+	LegacyNotAfter    time.Time               `yaml:"legacy_not_after"`
+	OIDC              OIDCConfig              `yaml:"oidc"`
+	Authz             AuthzConfig             `yaml:"authz"`
+	EmailVerification EmailVerificationConfig `yaml:"email_verification"`
+	PasswordReset     PasswordResetConfig     `yaml:"password_reset"`
+	EmailChange       EmailChangeConfig       `yaml:"email_change"`
+	Lockout           LockoutConfig           `yaml:"lockout"`
+	RateLimit         RateLimitConfig         `yaml:"rate_limit"`
+	PasswordPolicy    PasswordPolicyConfig    `yaml:"password_policy"`
+	Mailer            MailerConfig            `yaml:"mailer"`
 }
 
 func MustLoad() *Config {
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Login is a successful sign-in of the user.
+type Login struct {
+	UserID    int64     `json:"-"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AccountExport is personal data of the user handed out on their access
+// request.
+type AccountExport struct {
+	ExportedAt    time.Time      `json:"exported_at"`
+	Profile       AccountProfile `json:"profile"`
+	Sessions      []Session      `json:"sessions"`
+	LoginHistory  []Login        `json:"login_history"`
+	Roles         []ExportedRole `json:"roles"`
+	Organizations []ExportedOrg  `json:"organizations"`
+	Groups        []string       `json:"groups"`
+}
+
+// AccountProfile is the account itself, without password hash.
+type AccountProfile struct {
+	ID            int64  `json:"id"`
+	TenantID      int64  `json:"tenant_id"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	IsAdmin       bool   `json:"is_admin"`
+}
+
+// ExportedRole is a role bound to the user.
+type ExportedRole struct {
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions"`
+}
+
+// ExportedOrg is membership of the user in an organization.
+type ExportedOrg struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+	Role string `json:"role"`
+}
@@ -1,7 +1,16 @@
 package models
 
 type App struct {
-	ID     int
-	Name   string
-	Secret string
+	ID   int
+	Name string
+	// SecretHash is sha256 of client secret, the secret itself is shown
+	// only once when the app is registered
+	SecretHash []byte
+	// RedirectURIs are the only urls authorization codes may be sent to
+	RedirectURIs []string
+	// AllowedScopes are scopes tokens issued for the app may carry
+	AllowedScopes []string
+	// TenantID is the tenant of the admin who registered the app, tokens
+	// of the app act in it
+	TenantID int64
 }
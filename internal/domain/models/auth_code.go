@@ -0,0 +1,30 @@
+package models
+
+// AuthCode is an OAuth 2.0 authorization code issued to the app after the
+// user has logged in, it is exchanged for tokens of the session.
+type AuthCode struct {
+	ClientID            int    `json:"client_id"`
+	RedirectURI         string `json:"redirect_uri"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+	Nonce               string `json:"nonce"`
+	Scope               string `json:"scope"`
+	UserID              int64  `json:"user_id"`
+	TenantID            int64  `json:"tenant_id"`
+	SessionID           string `json:"session_id"`
+}
+
+// AuthorizationRequest holds parameters of OAuth 2.0 authorization request
+// (RFC 6749 section 4.1.1) extended with PKCE (RFC 7636) and OpenID Connect nonce.
+type AuthorizationRequest struct {
+	ResponseType        string
+	ClientID            int
+	RedirectURI         string
+	Scope               string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Nonce               string
+	// TenantID selects the tenant the user logs in to, 0 means default tenant
+	TenantID int64
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Group is a team of users within a tenant. Groups may contain other
+// groups, members of a subgroup are members of every group above it.
+type Group struct {
+	ID        int64
+	TenantID  int64
+	Name      string
+	CreatedAt time.Time
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// DefaultTenantID is the organization users belong to unless they
+// registered in another one.
+const DefaultTenantID int64 = 1
+
+// Roles of users within an organization.
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleAdmin  = "admin"
+	OrgRoleMember = "member"
+)
+
+// Organization is a customer (tenant) of the service. Every user account
+// belongs to exactly one tenant and may be a member of other organizations.
+type Organization struct {
+	ID        int64
+	Name      string
+	Slug      string
+	CreatedAt time.Time
+}
+
+// OrgMembership is membership of a user in an organization.
+type OrgMembership struct {
+	Organization Organization
+	UserID       int64
+	Role         string
+}
+
+// ValidOrgRole reports whether role is a known organization role.
+func ValidOrgRole(role string) bool {
+	return role == OrgRoleOwner || role == OrgRoleAdmin || role == OrgRoleMember
+}
+
+// CanManageMembers reports whether role allows adding and removing members.
+func CanManageMembers(role string) bool {
+	return role == OrgRoleOwner || role == OrgRoleAdmin
+}
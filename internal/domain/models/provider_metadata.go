@@ -0,0 +1,19 @@
+package models
+
+// ProviderMetadata is OpenID Connect discovery document of the service
+// (OpenID Connect Discovery 1.0, section 3).
+type ProviderMetadata struct {
+	Issuer                           string
+	AuthorizationEndpoint            string
+	TokenEndpoint                    string
+	UserinfoEndpoint                 string
+	JwksURI                          string
+	IntrospectionEndpoint            string
+	ResponseTypesSupported           []string
+	GrantTypesSupported              []string
+	SubjectTypesSupported            []string
+	IDTokenSigningAlgValuesSupported []string
+	ScopesSupported                  []string
+	ClaimsSupported                  []string
+	CodeChallengeMethodsSupported    []string
+}
@@ -0,0 +1,65 @@
+package models
+
+import "strings"
+
+// AdminRole is the role the legacy is_admin flag is mapped to.
+const AdminRole = "admin"
+
+// Permission allows action on resource, "*" matches any action or resource.
+type Permission struct {
+	Action   string
+	Resource string
+}
+
+// String returns permission as "action:resource", the format of
+// "permissions" claim of access tokens.
+func (p Permission) String() string {
+	return p.Action + ":" + p.Resource
+}
+
+// ParsePermission parses "action:resource", actions never contain ':'.
+func ParsePermission(value string) (Permission, bool) {
+	action, resource, ok := strings.Cut(value, ":")
+	if !ok || action == "" || resource == "" {
+		return Permission{}, false
+	}
+	return Permission{Action: action, Resource: resource}, true
+}
+
+// Allows reports whether permission grants action on resource. Resources
+// are ':' separated paths, "*" segment matches exactly one segment, trailing
+// "*" matches the rest of the path: "calc:tasks:*" allows "calc:tasks:42".
+func (p Permission) Allows(action string, resource string) bool {
+	if p.Action != "*" && p.Action != action {
+		return false
+	}
+	patternSegments := strings.Split(p.Resource, ":")
+	resourceSegments := strings.Split(resource, ":")
+	for i, segment := range patternSegments {
+		if i == len(patternSegments)-1 && segment == "*" {
+			return len(resourceSegments) >= len(patternSegments)
+		}
+		if i >= len(resourceSegments) {
+			return false
+		}
+		if segment != "*" && segment != resourceSegments[i] {
+			return false
+		}
+	}
+	return len(resourceSegments) == len(patternSegments)
+}
+
+// PermissionCheck asks whether user may perform action on resource.
+type PermissionCheck struct {
+	UserID   int64
+	Action   string
+	Resource string
+}
+
+// Role is a named set of permissions granted to users.
+type Role struct {
+	ID          int64
+	Name        string
+	Description string
+	Permissions []Permission
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Session is a login of the user on some device. Session id is stored in
+// tokens as "sid" claim and shared by all refresh tokens of the session.
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     int64     `json:"user_id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
@@ -0,0 +1,14 @@
+package models
+
+// TokenInfo is token metadata returned by introspection (RFC 7662).
+// Only Active is set for inactive tokens.
+type TokenInfo struct {
+	Active    bool
+	Subject   string
+	Email     string
+	ExpiresAt int64
+	IssuedAt  int64
+	TokenType string
+	Scope     string
+	ClientID  string
+}
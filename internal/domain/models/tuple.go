@@ -0,0 +1,55 @@
+package models
+
+import "strings"
+
+// Tuple is a relation between object and subject, written as
+// object#relation@subject, e.g. "calc_task:42#viewer@user:7". Subject is
+// either a user ("user:7") or a userset: everyone having relation to
+// another object ("calc_project:1#editor").
+type Tuple struct {
+	Object   string
+	Relation string
+	Subject  string
+}
+
+// String returns tuple in object#relation@subject format.
+func (t Tuple) String() string {
+	return t.Object + "#" + t.Relation + "@" + t.Subject
+}
+
+// ObjectType returns type of the object, the part before ':'.
+func (t Tuple) ObjectType() string {
+	objectType, _, _ := strings.Cut(t.Object, ":")
+	return objectType
+}
+
+// Userset returns object#relation of the tuple, the set of subjects it
+// grants relation to.
+func (t Tuple) Userset() string {
+	return t.Object + "#" + t.Relation
+}
+
+// ValidObject reports whether object is in "type:id" format.
+func ValidObject(object string) bool {
+	objectType, id, ok := strings.Cut(object, ":")
+	return ok && objectType != "" && id != "" && !strings.ContainsAny(object, "#@")
+}
+
+// ValidRelation reports whether relation can be used in tuples.
+func ValidRelation(relation string) bool {
+	return relation != "" && !strings.ContainsAny(relation, ":#@")
+}
+
+// SplitUserset splits "object#relation" subject, ok is false for subjects
+// which are not usersets.
+func SplitUserset(subject string) (object string, relation string, ok bool) {
+	return strings.Cut(subject, "#")
+}
+
+// ValidSubject reports whether subject is an object or a userset.
+func ValidSubject(subject string) bool {
+	if object, relation, ok := SplitUserset(subject); ok {
+		return ValidObject(object) && ValidRelation(relation)
+	}
+	return ValidObject(subject)
+}
@@ -1,10 +1,28 @@
 package models
 
 type User struct {
-	ID       int64
+	ID int64
+	// TenantID is the organization the account belongs to, email is
+	// unique only within the tenant
+	TenantID int64
 	Email    string
 	PassHash []byte
 	IsAdmin  bool
+	// EmailVerified is set once the user has proven they own the email
+	EmailVerified bool
+	// Disabled accounts can't sign in or refresh tokens
+	Disabled bool
+}
+
+// UserFilter selects users of a tenant for admins, page after AfterID
+// ordered by id. Nil flags match any value.
+type UserFilter struct {
+	TenantID    int64
+	EmailPrefix string
+	IsAdmin     *bool
+	Disabled    *bool
+	AfterID     int64
+	Limit       int
 }
 
 func (u *User) IsUserAmin() bool {
@@ -0,0 +1,199 @@
+package admin
+
+//TRANSPORT LAYER
+// serverAPI(transport layer) encapsulates user management of auth_service(service layer)
+
+import (
+	"context"
+	"errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sso/internal/domain/models"
+	"sso/internal/lib/gateway"
+	"sso/internal/services/auth_service"
+	ssov1 "sso/protos/proto/sso/gen"
+)
+
+// serverAPI TRANSPORT layer
+type serverAPI struct {
+	ssov1.UnimplementedAdminServer
+	// service layer
+	admin  auth_service.AdminInterface
+	tracer trace.Tracer
+}
+
+func Register(gRPC *grpc.Server, admin auth_service.AdminInterface) {
+	ssov1.RegisterAdminServer(gRPC, &serverAPI{admin: admin, tracer: otel.Tracer("sso service")})
+}
+
+const emptyId = 0
+
+func (s *serverAPI) ListUsers(
+	ctx context.Context,
+	req *ssov1.ListUsersRequest,
+) (*ssov1.ListUsersResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: list users",
+		trace.WithAttributes(attribute.String("handler", "listUsers")))
+	defer span.End()
+
+	token := gateway.BearerToken(ctx, req.GetToken())
+	if token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+	if req.GetPageSize() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "page_size must not be negative")
+	}
+	filter := models.UserFilter{
+		EmailPrefix: req.GetEmailPrefix(),
+		IsAdmin:     req.IsAdmin,
+		Disabled:    req.Disabled,
+		Limit:       int(req.GetPageSize()),
+	}
+	users, nextCursor, err := s.admin.ListUsers(ctx, token, filter, req.GetCursor())
+	if err != nil {
+		if errors.Is(err, auth_service.ErrInvalidCursor) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, adminError(err)
+	}
+	resp := &ssov1.ListUsersResponse{
+		Users:      make([]*ssov1.User, 0, len(users)),
+		NextCursor: nextCursor,
+	}
+	for _, user := range users {
+		resp.Users = append(resp.Users, userToProto(user))
+	}
+	return resp, nil
+}
+
+func (s *serverAPI) GetUser(
+	ctx context.Context,
+	req *ssov1.GetUserRequest,
+) (*ssov1.GetUserResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: get user",
+		trace.WithAttributes(attribute.String("handler", "getUser")))
+	defer span.End()
+
+	token, err := validateUserRequest(ctx, req.GetToken(), req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.admin.GetUser(ctx, token, req.GetUserId())
+	if err != nil {
+		return nil, adminError(err)
+	}
+	return &ssov1.GetUserResponse{User: userToProto(user)}, nil
+}
+
+func (s *serverAPI) DisableUser(
+	ctx context.Context,
+	req *ssov1.DisableUserRequest,
+) (*ssov1.DisableUserResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: disable user",
+		trace.WithAttributes(attribute.String("handler", "disableUser")))
+	defer span.End()
+
+	token, err := validateUserRequest(ctx, req.GetToken(), req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	success, err := s.admin.DisableUser(ctx, token, req.GetUserId())
+	if err != nil {
+		return nil, adminError(err)
+	}
+	return &ssov1.DisableUserResponse{Success: success}, nil
+}
+
+func (s *serverAPI) EnableUser(
+	ctx context.Context,
+	req *ssov1.EnableUserRequest,
+) (*ssov1.EnableUserResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: enable user",
+		trace.WithAttributes(attribute.String("handler", "enableUser")))
+	defer span.End()
+
+	token, err := validateUserRequest(ctx, req.GetToken(), req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	success, err := s.admin.EnableUser(ctx, token, req.GetUserId())
+	if err != nil {
+		return nil, adminError(err)
+	}
+	return &ssov1.EnableUserResponse{Success: success}, nil
+}
+
+func (s *serverAPI) UnlockUser(
+	ctx context.Context,
+	req *ssov1.UnlockUserRequest,
+) (*ssov1.UnlockUserResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: unlock user",
+		trace.WithAttributes(attribute.String("handler", "unlockUser")))
+	defer span.End()
+
+	token, err := validateUserRequest(ctx, req.GetToken(), req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	success, err := s.admin.UnlockUser(ctx, token, req.GetUserId())
+	if err != nil {
+		return nil, adminError(err)
+	}
+	return &ssov1.UnlockUserResponse{Success: success}, nil
+}
+
+func (s *serverAPI) SetAdmin(
+	ctx context.Context,
+	req *ssov1.SetAdminRequest,
+) (*ssov1.SetAdminResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: set admin",
+		trace.WithAttributes(attribute.String("handler", "setAdmin")))
+	defer span.End()
+
+	token, err := validateUserRequest(ctx, req.GetToken(), req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	success, err := s.admin.SetAdmin(ctx, token, req.GetUserId(), req.GetIsAdmin())
+	if err != nil {
+		return nil, adminError(err)
+	}
+	return &ssov1.SetAdminResponse{Success: success}, nil
+}
+
+func validateUserRequest(ctx context.Context, fallbackToken string, userID int64) (string, error) {
+	token := gateway.BearerToken(ctx, fallbackToken)
+	if token == "" {
+		return "", status.Error(codes.InvalidArgument, "token is required")
+	}
+	if userID == emptyId {
+		return "", status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	return token, nil
+}
+
+func adminError(err error) error {
+	switch {
+	case errors.Is(err, auth_service.ErrPermissionDenied):
+		return status.Error(codes.PermissionDenied, "permission denied")
+	case errors.Is(err, auth_service.ErrUserNotFound):
+		return status.Error(codes.NotFound, "user not found")
+	default:
+		return status.Error(codes.Unauthenticated, "bad token")
+	}
+}
+
+func userToProto(user models.User) *ssov1.User {
+	return &ssov1.User{
+		UserId:        user.ID,
+		TenantId:      user.TenantID,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		IsAdmin:       user.IsAdmin,
+		Disabled:      user.Disabled,
+	}
+}
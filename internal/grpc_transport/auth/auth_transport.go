@@ -5,6 +5,7 @@ package auth
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"github.com/prometheus/common/log"
@@ -15,9 +16,14 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"net/mail"
+	"net/url"
+	"sso/internal/lib/gateway"
 	"sso/internal/services/auth_service"
 	ssov1 "sso/protos/proto/sso/gen"
 	"sso/storage"
+	"strconv"
+	"strings"
 )
 
 // serverAPI TRANSPORT layer
@@ -61,19 +67,35 @@ func (s *serverAPI) Login(
 	if err := validateLogin(req); err != nil {
 		return nil, err
 	}
-	accessToken, refreshToken, err := s.auth.Login(
-		ctx, req.GetEmail(), req.GetPassword(),
+	accessToken, refreshToken, idToken, err := s.auth.Login(
+		ctx, req.GetEmail(), req.GetPassword(), int(req.GetAppId()), req.GetScopes(), req.GetTenantId(),
 	)
 	if err != nil {
 		fmt.Println(err.Error())
 		if errors.Is(err, auth_service.ErrInvalidCredentials) {
 			return nil, status.Error(codes.InvalidArgument, "invalid credentials")
 		}
+		if errors.Is(err, auth_service.ErrEmailNotVerified) {
+			return nil, status.Error(codes.FailedPrecondition, "email is not verified")
+		}
+		if errors.Is(err, auth_service.ErrUserDisabled) {
+			return nil, status.Error(codes.PermissionDenied, "user is disabled")
+		}
+		if errors.Is(err, auth_service.ErrTooManyAttempts) {
+			return nil, lockoutStatus(ctx, err)
+		}
+		if errors.Is(err, auth_service.ErrInvalidClient) {
+			return nil, status.Error(codes.InvalidArgument, "unknown app")
+		}
+		if errors.Is(err, auth_service.ErrInvalidScope) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
 		return nil, status.Error(codes.Internal, "internal error")
 	}
 	return &ssov1.LoginResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
+		IdToken:      idToken,
 	}, nil
 }
 
@@ -96,7 +118,7 @@ func (s *serverAPI) Refresh(
 	defer span.End()
 
 	accessToken, refreshToken, err := s.auth.Refresh(
-		ctx, req.GetRefreshToken(),
+		ctx, req.GetRefreshToken(), int(req.GetAppId()), req.GetScopes(),
 	)
 	if err != nil {
 		if errors.Is(err, auth_service.ErrTokenWrongType) {
@@ -105,6 +127,18 @@ func (s *serverAPI) Refresh(
 		if errors.Is(err, auth_service.ErrTokenRevoked) {
 			return nil, status.Error(codes.Unauthenticated, "Provide valid refresh token")
 		}
+		if errors.Is(err, auth_service.ErrTokenReused) {
+			return nil, status.Error(codes.Unauthenticated, "Refresh token reuse detected, please login again")
+		}
+		if errors.Is(err, auth_service.ErrUserDisabled) {
+			return nil, status.Error(codes.PermissionDenied, "user is disabled")
+		}
+		if errors.Is(err, auth_service.ErrInvalidClient) {
+			return nil, status.Error(codes.InvalidArgument, "refresh token was issued for another app")
+		}
+		if errors.Is(err, auth_service.ErrInvalidScope) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
 		return nil, status.Error(codes.Internal, "internal error")
 	}
 
@@ -134,15 +168,21 @@ func (s *serverAPI) Register(
 	}
 	// call RegisterNewUser from service layer
 	userID, err := s.auth.Register(
-		ctx, req.GetEmail(), req.GetPassword(),
+		ctx, req.GetEmail(), req.GetPassword(), req.GetTenantId(),
 	)
 	if err != nil {
 		// TODO: add error processing depends on the type of error
+		if errors.Is(err, auth_service.ErrWeakPassword) {
+			return nil, weakPasswordStatus(err, "password")
+		}
 		if errors.Is(err, storage.ErrUserExists) {
 			return nil, status.Error(
 				codes.AlreadyExists, "user already exists",
 			)
 		}
+		if errors.Is(err, storage.ErrOrgNotFound) {
+			return nil, status.Error(codes.InvalidArgument, "tenant not found")
+		}
 		return nil, status.Error(codes.Internal, "internal error")
 	}
 	return &ssov1.RegisterResponse{
@@ -158,7 +198,7 @@ func (s *serverAPI) IsAdmin(
 		return nil, err
 	}
 	// call IsAdmin from service layer
-	IsAdmin, err := s.auth.IsAdmin(ctx, int(req.GetUserId()))
+	IsAdmin, err := s.auth.IsAdmin(ctx, int(req.GetUserId()), req.GetTenantId())
 	if err != nil {
 		// TODO: add error processing depends on the type of error
 		return nil, status.Error(codes.Internal, "internal error")
@@ -185,14 +225,288 @@ func (s *serverAPI) Validate(
 	ctx context.Context,
 	req *ssov1.ValidateRequest,
 ) (*ssov1.ValidateResponse, error) {
-	success, err := s.auth.Validate(ctx, req.GetToken())
+	success, err := s.auth.Validate(ctx, req.GetToken(), req.GetAudience())
 	if err != nil {
+		if errors.Is(err, auth_service.ErrTokenWrongAudience) {
+			return nil, status.Error(codes.PermissionDenied, "token issued for another audience")
+		}
 		// TODO: add error processing depends on the type of error
 		return nil, err
 	}
 	return &ssov1.ValidateResponse{Success: success}, nil
 }
 
+func (s *serverAPI) Jwks(
+	ctx context.Context,
+	req *ssov1.JwksRequest,
+) (*ssov1.JwksResponse, error) {
+	keys, err := s.auth.Jwks(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	resp := &ssov1.JwksResponse{Keys: make([]*ssov1.Jwk, 0, len(keys))}
+	for _, key := range keys {
+		resp.Keys = append(resp.Keys, &ssov1.Jwk{
+			Kty: key.Kty,
+			Use: key.Use,
+			Alg: key.Alg,
+			Kid: key.Kid,
+			N:   key.N,
+			E:   key.E,
+			Crv: key.Crv,
+			X:   key.X,
+			Y:   key.Y,
+		})
+	}
+	return resp, nil
+}
+
+func (s *serverAPI) RevokeAllSessions(
+	ctx context.Context,
+	req *ssov1.RevokeAllSessionsRequest,
+) (*ssov1.RevokeAllSessionsResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: revoke all sessions",
+		trace.WithAttributes(attribute.String("handler", "revokeAllSessions")))
+	defer span.End()
+
+	if err := validateRevokeAllSessions(req); err != nil {
+		return nil, err
+	}
+	success, err := s.auth.RevokeAllSessions(ctx, req.GetToken(), int(req.GetUserId()))
+	if err != nil {
+		if errors.Is(err, auth_service.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "permission denied")
+		}
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return nil, status.Error(codes.Unauthenticated, "bad token")
+	}
+	return &ssov1.RevokeAllSessionsResponse{Success: success}, nil
+}
+
+func (s *serverAPI) ListSessions(
+	ctx context.Context,
+	req *ssov1.ListSessionsRequest,
+) (*ssov1.ListSessionsResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: list sessions",
+		trace.WithAttributes(attribute.String("handler", "listSessions")))
+	defer span.End()
+
+	if err := validateListSessions(req); err != nil {
+		return nil, err
+	}
+	sessions, currentSessionID, err := s.auth.ListSessions(ctx, req.GetToken(), int(req.GetUserId()))
+	if err != nil {
+		if errors.Is(err, auth_service.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "permission denied")
+		}
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return nil, status.Error(codes.Unauthenticated, "bad token")
+	}
+	resp := &ssov1.ListSessionsResponse{Sessions: make([]*ssov1.Session, 0, len(sessions))}
+	for _, session := range sessions {
+		resp.Sessions = append(resp.Sessions, &ssov1.Session{
+			SessionId:  session.ID,
+			UserAgent:  session.UserAgent,
+			Ip:         session.IP,
+			CreatedAt:  session.CreatedAt.Unix(),
+			LastSeenAt: session.LastSeenAt.Unix(),
+			Current:    session.ID == currentSessionID,
+		})
+	}
+	return resp, nil
+}
+
+func (s *serverAPI) RevokeSession(
+	ctx context.Context,
+	req *ssov1.RevokeSessionRequest,
+) (*ssov1.RevokeSessionResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: revoke session",
+		trace.WithAttributes(attribute.String("handler", "revokeSession")))
+	defer span.End()
+
+	if err := validateRevokeSession(req); err != nil {
+		return nil, err
+	}
+	success, err := s.auth.RevokeSession(ctx, req.GetToken(), req.GetSessionId())
+	if err != nil {
+		if errors.Is(err, auth_service.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "permission denied")
+		}
+		if errors.Is(err, auth_service.ErrSessionNotFound) {
+			return nil, status.Error(codes.NotFound, "session not found")
+		}
+		return nil, status.Error(codes.Unauthenticated, "bad token")
+	}
+	return &ssov1.RevokeSessionResponse{Success: success}, nil
+}
+
+func (s *serverAPI) Introspect(
+	ctx context.Context,
+	req *ssov1.IntrospectRequest,
+) (*ssov1.IntrospectResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: introspect",
+		trace.WithAttributes(attribute.String("handler", "introspect")))
+	defer span.End()
+
+	if err := validateIntrospect(req); err != nil {
+		return nil, err
+	}
+	clientID, clientSecret, err := clientCredentials(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	info, err := s.auth.Introspect(ctx, clientID, clientSecret, req.GetToken(), req.GetAudience())
+	if err != nil {
+		if errors.Is(err, auth_service.ErrInvalidClient) {
+			return nil, status.Error(codes.Unauthenticated, "invalid client")
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.IntrospectResponse{
+		Active:    info.Active,
+		Sub:       info.Subject,
+		Email:     info.Email,
+		Exp:       info.ExpiresAt,
+		Iat:       info.IssuedAt,
+		TokenType: info.TokenType,
+		Scope:     info.Scope,
+		ClientId:  info.ClientID,
+	}, nil
+}
+
+func (s *serverAPI) OpenIDConfiguration(
+	ctx context.Context,
+	req *ssov1.OpenIDConfigurationRequest,
+) (*ssov1.OpenIDConfigurationResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: openid configuration",
+		trace.WithAttributes(attribute.String("handler", "openidConfiguration")))
+	defer span.End()
+
+	provider, err := s.auth.OpenIDConfiguration(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.OpenIDConfigurationResponse{
+		Issuer:                           provider.Issuer,
+		AuthorizationEndpoint:            provider.AuthorizationEndpoint,
+		TokenEndpoint:                    provider.TokenEndpoint,
+		UserinfoEndpoint:                 provider.UserinfoEndpoint,
+		JwksUri:                          provider.JwksURI,
+		IntrospectionEndpoint:            provider.IntrospectionEndpoint,
+		ResponseTypesSupported:           provider.ResponseTypesSupported,
+		GrantTypesSupported:              provider.GrantTypesSupported,
+		SubjectTypesSupported:            provider.SubjectTypesSupported,
+		IdTokenSigningAlgValuesSupported: provider.IDTokenSigningAlgValuesSupported,
+		ScopesSupported:                  provider.ScopesSupported,
+		ClaimsSupported:                  provider.ClaimsSupported,
+		CodeChallengeMethodsSupported:    provider.CodeChallengeMethodsSupported,
+	}, nil
+}
+
+func (s *serverAPI) UserInfo(
+	ctx context.Context,
+	req *ssov1.UserInfoRequest,
+) (*ssov1.UserInfoResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: userinfo",
+		trace.WithAttributes(attribute.String("handler", "userinfo")))
+	defer span.End()
+
+	token := gateway.BearerToken(ctx, req.GetAccessToken())
+	if token == "" {
+		return nil, status.Error(codes.Unauthenticated, "access token is required")
+	}
+	user, err := s.auth.UserInfo(ctx, token)
+	if err != nil {
+		if errors.Is(err, auth_service.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return nil, status.Error(codes.Unauthenticated, "bad token")
+	}
+	return &ssov1.UserInfoResponse{
+		Sub:           strconv.FormatInt(user.ID, 10),
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+	}, nil
+}
+
+func (s *serverAPI) RegisterApp(
+	ctx context.Context,
+	req *ssov1.RegisterAppRequest,
+) (*ssov1.RegisterAppResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: register app",
+		trace.WithAttributes(attribute.String("handler", "registerApp")))
+	defer span.End()
+
+	if err := validateRegisterApp(req); err != nil {
+		return nil, err
+	}
+	clientID, clientSecret, err := s.auth.RegisterApp(ctx, req.GetToken(), req.GetName(), req.GetRedirectUris(), req.GetAllowedScopes())
+	if err != nil {
+		switch {
+		case errors.Is(err, auth_service.ErrPermissionDenied):
+			return nil, status.Error(codes.PermissionDenied, "permission denied")
+		case errors.Is(err, auth_service.ErrAppExists):
+			return nil, status.Error(codes.AlreadyExists, "app already exists")
+		case errors.Is(err, auth_service.ErrInvalidRedirectURI):
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, status.Error(codes.Unauthenticated, "bad token")
+	}
+	return &ssov1.RegisterAppResponse{
+		ClientId:     int64(clientID),
+		ClientSecret: clientSecret,
+	}, nil
+}
+
+// clientCredentials returns app credentials passed with HTTP Basic
+// authentication (forwarded by gateway as metadata) or in the request body.
+func clientCredentials(ctx context.Context, req *ssov1.IntrospectRequest) (int, string, error) {
+	rawID, secret := req.GetClientId(), req.GetClientSecret()
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			if basicID, basicSecret, ok := parseBasicAuth(values[0]); ok {
+				rawID, secret = basicID, basicSecret
+			}
+		}
+	}
+	if rawID == "" || secret == "" {
+		return 0, "", status.Error(codes.Unauthenticated, "client credentials are required")
+	}
+	clientID, err := strconv.Atoi(rawID)
+	if err != nil {
+		return 0, "", status.Error(codes.Unauthenticated, "invalid client")
+	}
+	return clientID, secret, nil
+}
+
+// parseBasicAuth parses HTTP Basic authentication header value.
+func parseBasicAuth(header string) (username, password string, ok bool) {
+	const prefix = "Basic "
+	if len(header) < len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(header[len(prefix):])
+	if err != nil {
+		return "", "", false
+	}
+	username, password, ok = strings.Cut(string(decoded), ":")
+	if !ok {
+		return "", "", false
+	}
+	// RFC 6749 2.3.1: credentials are form-urlencoded before encoding
+	if username, err = url.QueryUnescape(username); err != nil {
+		return "", "", false
+	}
+	if password, err = url.QueryUnescape(password); err != nil {
+		return "", "", false
+	}
+	return username, password, true
+}
+
 func validateLogin(req *ssov1.LoginRequest) error {
 	//TODO: use special packet for data validation
 	if req.GetEmail() == "" {
@@ -209,12 +523,21 @@ func validateRegister(req *ssov1.RegisterRequest) error {
 	if req.GetEmail() == "" {
 		return status.Error(codes.InvalidArgument, "email is required")
 	}
+	if !validEmail(req.GetEmail()) {
+		return status.Error(codes.InvalidArgument, "email is invalid")
+	}
 	if req.GetPassword() == "" {
 		return status.Error(codes.InvalidArgument, "password is required")
 	}
 	return nil
 }
 
+// validEmail accepts bare addresses only, "Name <addr>" forms are refused.
+func validEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email
+}
+
 func validateIsAdmin(req *ssov1.IsAdminRequest) error {
 	//TODO: use special packet for data validation
 	if req.GetUserId() == emptyId {
@@ -223,6 +546,53 @@ func validateIsAdmin(req *ssov1.IsAdminRequest) error {
 	return nil
 }
 
+func validateRevokeAllSessions(req *ssov1.RevokeAllSessionsRequest) error {
+	if req.GetToken() == "" {
+		return status.Error(codes.InvalidArgument, "token is required")
+	}
+	if req.GetUserId() == emptyId {
+		return status.Error(codes.InvalidArgument, "userid is required")
+	}
+	return nil
+}
+
+func validateListSessions(req *ssov1.ListSessionsRequest) error {
+	if req.GetToken() == "" {
+		return status.Error(codes.InvalidArgument, "token is required")
+	}
+	if req.GetUserId() == emptyId {
+		return status.Error(codes.InvalidArgument, "userid is required")
+	}
+	return nil
+}
+
+func validateRevokeSession(req *ssov1.RevokeSessionRequest) error {
+	if req.GetToken() == "" {
+		return status.Error(codes.InvalidArgument, "token is required")
+	}
+	if req.GetSessionId() == "" {
+		return status.Error(codes.InvalidArgument, "session id is required")
+	}
+	return nil
+}
+
+func validateRegisterApp(req *ssov1.RegisterAppRequest) error {
+	if req.GetToken() == "" {
+		return status.Error(codes.InvalidArgument, "token is required")
+	}
+	if req.GetName() == "" {
+		return status.Error(codes.InvalidArgument, "name is required")
+	}
+	return nil
+}
+
+func validateIntrospect(req *ssov1.IntrospectRequest) error {
+	if req.GetToken() == "" {
+		return status.Error(codes.InvalidArgument, "token is required")
+	}
+	return nil
+}
+
 func getContextWithTraceId(ctx context.Context) (context.Context, error) {
 
 	md, _ := metadata.FromIncomingContext(ctx)
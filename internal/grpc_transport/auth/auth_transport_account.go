@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sso/internal/lib/gateway"
+	"sso/internal/services/auth_service"
+	ssov1 "sso/protos/proto/sso/gen"
+)
+
+func (s *serverAPI) DeleteAccount(
+	ctx context.Context,
+	req *ssov1.DeleteAccountRequest,
+) (*ssov1.DeleteAccountResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: delete account",
+		trace.WithAttributes(attribute.String("handler", "deleteAccount")))
+	defer span.End()
+
+	token := gateway.BearerToken(ctx, req.GetToken())
+	if token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+	success, err := s.auth.DeleteAccount(ctx, token, req.GetUserId(), req.GetPassword())
+	if err != nil {
+		switch {
+		case errors.Is(err, auth_service.ErrInvalidCredentials):
+			return nil, status.Error(codes.InvalidArgument, "invalid credentials")
+		case errors.Is(err, auth_service.ErrPermissionDenied):
+			return nil, status.Error(codes.PermissionDenied, "permission denied")
+		case errors.Is(err, auth_service.ErrUserNotFound):
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return nil, status.Error(codes.Unauthenticated, "bad token")
+	}
+	return &ssov1.DeleteAccountResponse{Success: success}, nil
+}
+
+func (s *serverAPI) ExportMyData(
+	ctx context.Context,
+	req *ssov1.ExportMyDataRequest,
+) (*ssov1.ExportMyDataResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: export my data",
+		trace.WithAttributes(attribute.String("handler", "exportMyData")))
+	defer span.End()
+
+	token := gateway.BearerToken(ctx, req.GetToken())
+	if token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+	archive, err := s.auth.ExportMyData(ctx, token)
+	if err != nil {
+		if errors.Is(err, auth_service.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return nil, status.Error(codes.Unauthenticated, "bad token")
+	}
+	return &ssov1.ExportMyDataResponse{Archive: string(archive)}, nil
+}
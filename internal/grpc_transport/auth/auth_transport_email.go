@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sso/internal/lib/gateway"
+	"sso/internal/services/auth_service"
+	ssov1 "sso/protos/proto/sso/gen"
+)
+
+func (s *serverAPI) RequestEmailChange(
+	ctx context.Context,
+	req *ssov1.RequestEmailChangeRequest,
+) (*ssov1.RequestEmailChangeResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: request email change",
+		trace.WithAttributes(attribute.String("handler", "requestEmailChange")))
+	defer span.End()
+
+	token := gateway.BearerToken(ctx, req.GetToken())
+	if token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+	if !validEmail(req.GetNewEmail()) {
+		return nil, status.Error(codes.InvalidArgument, "new_email is invalid")
+	}
+	success, err := s.auth.RequestEmailChange(ctx, token, req.GetNewEmail())
+	if err != nil {
+		switch {
+		case errors.Is(err, auth_service.ErrEmailTaken):
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		case errors.Is(err, auth_service.ErrSameEmail):
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		case errors.Is(err, auth_service.ErrTooManyRequests):
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		case errors.Is(err, auth_service.ErrUserNotFound):
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return nil, status.Error(codes.Unauthenticated, "bad token")
+	}
+	return &ssov1.RequestEmailChangeResponse{Success: success}, nil
+}
+
+func (s *serverAPI) ConfirmEmailChange(
+	ctx context.Context,
+	req *ssov1.ConfirmEmailChangeRequest,
+) (*ssov1.ConfirmEmailChangeResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: confirm email change",
+		trace.WithAttributes(attribute.String("handler", "confirmEmailChange")))
+	defer span.End()
+
+	if req.GetToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+	success, err := s.auth.ConfirmEmailChange(ctx, req.GetToken())
+	if err != nil {
+		if errors.Is(err, auth_service.ErrInvalidActionToken) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if errors.Is(err, auth_service.ErrEmailTaken) {
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.ConfirmEmailChangeResponse{Success: success}, nil
+}
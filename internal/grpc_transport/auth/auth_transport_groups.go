@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sso/internal/lib/gateway"
+	"sso/internal/services/auth_service"
+	ssov1 "sso/protos/proto/sso/gen"
+)
+
+func (s *serverAPI) CreateGroup(
+	ctx context.Context,
+	req *ssov1.CreateGroupRequest,
+) (*ssov1.CreateGroupResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: create group",
+		trace.WithAttributes(attribute.String("handler", "createGroup")))
+	defer span.End()
+
+	token := gateway.BearerToken(ctx, req.GetToken())
+	if token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+	if req.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+	groupID, err := s.auth.CreateGroup(ctx, token, req.GetName())
+	if err != nil {
+		return nil, groupError(err)
+	}
+	return &ssov1.CreateGroupResponse{GroupId: groupID}, nil
+}
+
+func (s *serverAPI) AddGroupMember(
+	ctx context.Context,
+	req *ssov1.AddGroupMemberRequest,
+) (*ssov1.AddGroupMemberResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: add group member",
+		trace.WithAttributes(attribute.String("handler", "addGroupMember")))
+	defer span.End()
+
+	token := gateway.BearerToken(ctx, req.GetToken())
+	if err := validateGroupMember(token, req.GetGroupId(), req.GetUserId()); err != nil {
+		return nil, err
+	}
+	success, err := s.auth.AddGroupMember(ctx, token, req.GetGroupId(), int(req.GetUserId()))
+	if err != nil {
+		return nil, groupError(err)
+	}
+	return &ssov1.AddGroupMemberResponse{Success: success}, nil
+}
+
+func (s *serverAPI) RemoveGroupMember(
+	ctx context.Context,
+	req *ssov1.RemoveGroupMemberRequest,
+) (*ssov1.RemoveGroupMemberResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: remove group member",
+		trace.WithAttributes(attribute.String("handler", "removeGroupMember")))
+	defer span.End()
+
+	token := gateway.BearerToken(ctx, req.GetToken())
+	if err := validateGroupMember(token, req.GetGroupId(), req.GetUserId()); err != nil {
+		return nil, err
+	}
+	success, err := s.auth.RemoveGroupMember(ctx, token, req.GetGroupId(), int(req.GetUserId()))
+	if err != nil {
+		return nil, groupError(err)
+	}
+	return &ssov1.RemoveGroupMemberResponse{Success: success}, nil
+}
+
+func (s *serverAPI) AddSubgroup(
+	ctx context.Context,
+	req *ssov1.AddSubgroupRequest,
+) (*ssov1.AddSubgroupResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: add subgroup",
+		trace.WithAttributes(attribute.String("handler", "addSubgroup")))
+	defer span.End()
+
+	token := gateway.BearerToken(ctx, req.GetToken())
+	if err := validateSubgroup(token, req.GetParentId(), req.GetChildId()); err != nil {
+		return nil, err
+	}
+	success, err := s.auth.AddSubgroup(ctx, token, req.GetParentId(), req.GetChildId())
+	if err != nil {
+		return nil, groupError(err)
+	}
+	return &ssov1.AddSubgroupResponse{Success: success}, nil
+}
+
+func (s *serverAPI) RemoveSubgroup(
+	ctx context.Context,
+	req *ssov1.RemoveSubgroupRequest,
+) (*ssov1.RemoveSubgroupResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: remove subgroup",
+		trace.WithAttributes(attribute.String("handler", "removeSubgroup")))
+	defer span.End()
+
+	token := gateway.BearerToken(ctx, req.GetToken())
+	if err := validateSubgroup(token, req.GetParentId(), req.GetChildId()); err != nil {
+		return nil, err
+	}
+	success, err := s.auth.RemoveSubgroup(ctx, token, req.GetParentId(), req.GetChildId())
+	if err != nil {
+		return nil, groupError(err)
+	}
+	return &ssov1.RemoveSubgroupResponse{Success: success}, nil
+}
+
+func (s *serverAPI) GetUserGroups(
+	ctx context.Context,
+	req *ssov1.GetUserGroupsRequest,
+) (*ssov1.GetUserGroupsResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: get user groups",
+		trace.WithAttributes(attribute.String("handler", "getUserGroups")))
+	defer span.End()
+
+	token := gateway.BearerToken(ctx, req.GetToken())
+	if token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+	if req.GetUserId() == emptyId {
+		return nil, status.Error(codes.InvalidArgument, "userid is required")
+	}
+	groups, err := s.auth.UserGroups(ctx, token, int(req.GetUserId()))
+	if err != nil {
+		return nil, groupError(err)
+	}
+	respGroups := make([]*ssov1.Group, 0, len(groups))
+	for _, group := range groups {
+		respGroups = append(respGroups, &ssov1.Group{Id: group.ID, Name: group.Name})
+	}
+	return &ssov1.GetUserGroupsResponse{Groups: respGroups}, nil
+}
+
+func groupError(err error) error {
+	switch {
+	case errors.Is(err, auth_service.ErrPermissionDenied):
+		return status.Error(codes.PermissionDenied, "permission denied")
+	case errors.Is(err, auth_service.ErrGroupNotFound):
+		return status.Error(codes.NotFound, "group not found")
+	case errors.Is(err, auth_service.ErrUserNotFound):
+		return status.Error(codes.NotFound, "user not found")
+	case errors.Is(err, auth_service.ErrGroupExists):
+		return status.Error(codes.AlreadyExists, "group already exists")
+	case errors.Is(err, auth_service.ErrGroupCycle):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return status.Error(codes.Unauthenticated, "bad token")
+}
+
+func validateGroupMember(token string, groupID int64, userID int64) error {
+	if token == "" {
+		return status.Error(codes.InvalidArgument, "token is required")
+	}
+	if groupID == emptyId {
+		return status.Error(codes.InvalidArgument, "group_id is required")
+	}
+	if userID == emptyId {
+		return status.Error(codes.InvalidArgument, "userid is required")
+	}
+	return nil
+}
+
+func validateSubgroup(token string, parentID int64, childID int64) error {
+	if token == "" {
+		return status.Error(codes.InvalidArgument, "token is required")
+	}
+	if parentID == emptyId || childID == emptyId {
+		return status.Error(codes.InvalidArgument, "parent_id and child_id are required")
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"math"
+	"sso/internal/services/auth_service"
+	"strconv"
+)
+
+// lockoutStatus converts lock of the login to ResourceExhausted, time to
+// wait is sent both in retry-after header (seconds) and RetryInfo details.
+func lockoutStatus(ctx context.Context, err error) error {
+	var lockout *auth_service.LockoutError
+	if !errors.As(err, &lockout) {
+		return status.Error(codes.ResourceExhausted, "too many failed login attempts")
+	}
+	seconds := int64(math.Ceil(lockout.RetryAfter.Seconds()))
+	_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.FormatInt(seconds, 10)))
+
+	st := status.New(codes.ResourceExhausted, "too many failed login attempts, try again later")
+	detailed, detailsErr := st.WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(lockout.RetryAfter),
+	})
+	if detailsErr != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
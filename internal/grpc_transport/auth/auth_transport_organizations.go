@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sso/internal/domain/models"
+	"sso/internal/lib/gateway"
+	"sso/internal/services/auth_service"
+	ssov1 "sso/protos/proto/sso/gen"
+)
+
+func (s *serverAPI) CreateOrganization(
+	ctx context.Context,
+	req *ssov1.CreateOrganizationRequest,
+) (*ssov1.CreateOrganizationResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: create organization",
+		trace.WithAttributes(attribute.String("handler", "createOrganization")))
+	defer span.End()
+
+	token := gateway.BearerToken(ctx, req.GetToken())
+	if token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+	if req.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+	if req.GetSlug() == "" {
+		return nil, status.Error(codes.InvalidArgument, "slug is required")
+	}
+	orgID, err := s.auth.CreateOrganization(ctx, token, req.GetName(), req.GetSlug())
+	if err != nil {
+		if errors.Is(err, auth_service.ErrOrgExists) {
+			return nil, status.Error(codes.AlreadyExists, "organization already exists")
+		}
+		return nil, organizationError(err)
+	}
+	return &ssov1.CreateOrganizationResponse{OrgId: orgID}, nil
+}
+
+func (s *serverAPI) AddOrganizationMember(
+	ctx context.Context,
+	req *ssov1.AddOrganizationMemberRequest,
+) (*ssov1.AddOrganizationMemberResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: add organization member",
+		trace.WithAttributes(attribute.String("handler", "addOrganizationMember")))
+	defer span.End()
+
+	token := gateway.BearerToken(ctx, req.GetToken())
+	if err := validateOrganizationMember(token, req.GetOrgId(), req.GetUserId()); err != nil {
+		return nil, err
+	}
+	role := req.GetRole()
+	if role == "" {
+		role = models.OrgRoleMember
+	}
+	success, err := s.auth.AddOrganizationMember(ctx, token, req.GetOrgId(), req.GetUserId(), role)
+	if err != nil {
+		return nil, organizationError(err)
+	}
+	return &ssov1.AddOrganizationMemberResponse{Success: success}, nil
+}
+
+func (s *serverAPI) RemoveOrganizationMember(
+	ctx context.Context,
+	req *ssov1.RemoveOrganizationMemberRequest,
+) (*ssov1.RemoveOrganizationMemberResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: remove organization member",
+		trace.WithAttributes(attribute.String("handler", "removeOrganizationMember")))
+	defer span.End()
+
+	token := gateway.BearerToken(ctx, req.GetToken())
+	if err := validateOrganizationMember(token, req.GetOrgId(), req.GetUserId()); err != nil {
+		return nil, err
+	}
+	success, err := s.auth.RemoveOrganizationMember(ctx, token, req.GetOrgId(), req.GetUserId())
+	if err != nil {
+		return nil, organizationError(err)
+	}
+	return &ssov1.RemoveOrganizationMemberResponse{Success: success}, nil
+}
+
+func (s *serverAPI) ListOrganizations(
+	ctx context.Context,
+	req *ssov1.ListOrganizationsRequest,
+) (*ssov1.ListOrganizationsResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: list organizations",
+		trace.WithAttributes(attribute.String("handler", "listOrganizations")))
+	defer span.End()
+
+	token := gateway.BearerToken(ctx, req.GetToken())
+	if token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+	memberships, err := s.auth.ListOrganizations(ctx, token)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "bad token")
+	}
+	organizations := make([]*ssov1.Organization, 0, len(memberships))
+	for _, membership := range memberships {
+		organizations = append(organizations, &ssov1.Organization{
+			Id:   membership.Organization.ID,
+			Name: membership.Organization.Name,
+			Slug: membership.Organization.Slug,
+			Role: membership.Role,
+		})
+	}
+	return &ssov1.ListOrganizationsResponse{Organizations: organizations}, nil
+}
+
+func organizationError(err error) error {
+	switch {
+	case errors.Is(err, auth_service.ErrPermissionDenied):
+		return status.Error(codes.PermissionDenied, "permission denied")
+	case errors.Is(err, auth_service.ErrOrgNotFound):
+		return status.Error(codes.NotFound, "organization not found")
+	case errors.Is(err, auth_service.ErrMemberNotFound):
+		return status.Error(codes.NotFound, "member not found")
+	case errors.Is(err, auth_service.ErrInvalidOrgRole):
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return status.Error(codes.Unauthenticated, "bad token")
+}
+
+func validateOrganizationMember(token string, orgID int64, userID int64) error {
+	if token == "" {
+		return status.Error(codes.InvalidArgument, "token is required")
+	}
+	if orgID == emptyId {
+		return status.Error(codes.InvalidArgument, "org_id is required")
+	}
+	if userID == emptyId {
+		return status.Error(codes.InvalidArgument, "userid is required")
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sso/internal/lib/gateway"
+	"sso/internal/services/auth_service"
+	ssov1 "sso/protos/proto/sso/gen"
+)
+
+func (s *serverAPI) RequestPasswordReset(
+	ctx context.Context,
+	req *ssov1.RequestPasswordResetRequest,
+) (*ssov1.RequestPasswordResetResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: request password reset",
+		trace.WithAttributes(attribute.String("handler", "requestPasswordReset")))
+	defer span.End()
+
+	if req.GetEmail() == "" {
+		return nil, status.Error(codes.InvalidArgument, "email is required")
+	}
+	success, err := s.auth.RequestPasswordReset(ctx, req.GetEmail(), req.GetTenantId())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.RequestPasswordResetResponse{Success: success}, nil
+}
+
+func (s *serverAPI) ResetPassword(
+	ctx context.Context,
+	req *ssov1.ResetPasswordRequest,
+) (*ssov1.ResetPasswordResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: reset password",
+		trace.WithAttributes(attribute.String("handler", "resetPassword")))
+	defer span.End()
+
+	if req.GetToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+	if req.GetNewPassword() == "" {
+		return nil, status.Error(codes.InvalidArgument, "new_password is required")
+	}
+	success, err := s.auth.ResetPassword(ctx, req.GetToken(), req.GetNewPassword())
+	if err != nil {
+		if errors.Is(err, auth_service.ErrWeakPassword) {
+			return nil, weakPasswordStatus(err, "new_password")
+		}
+		if errors.Is(err, auth_service.ErrInvalidActionToken) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.ResetPasswordResponse{Success: success}, nil
+}
+
+func (s *serverAPI) ChangePassword(
+	ctx context.Context,
+	req *ssov1.ChangePasswordRequest,
+) (*ssov1.ChangePasswordResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: change password",
+		trace.WithAttributes(attribute.String("handler", "changePassword")))
+	defer span.End()
+
+	token := gateway.BearerToken(ctx, req.GetToken())
+	if token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+	if req.GetOldPassword() == "" {
+		return nil, status.Error(codes.InvalidArgument, "old_password is required")
+	}
+	if req.GetNewPassword() == "" {
+		return nil, status.Error(codes.InvalidArgument, "new_password is required")
+	}
+	success, err := s.auth.ChangePassword(ctx, token, req.GetOldPassword(), req.GetNewPassword(), req.GetKeepCurrentSession())
+	if err != nil {
+		return nil, passwordError(err)
+	}
+	return &ssov1.ChangePasswordResponse{Success: success}, nil
+}
+
+func passwordError(err error) error {
+	switch {
+	case errors.Is(err, auth_service.ErrInvalidCredentials):
+		return status.Error(codes.InvalidArgument, "invalid credentials")
+	case errors.Is(err, auth_service.ErrWeakPassword):
+		return weakPasswordStatus(err, "new_password")
+	case errors.Is(err, auth_service.ErrSamePassword):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, auth_service.ErrUserNotFound):
+		return status.Error(codes.NotFound, "user not found")
+	case errors.Is(err, auth_service.ErrInvalidActionToken):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case isBadToken(err):
+		return status.Error(codes.Unauthenticated, "bad token")
+	default:
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+// isBadToken reports whether err is a rejection of the caller's token,
+// errors of the jwt library are returned by validation as they are.
+func isBadToken(err error) bool {
+	for _, target := range []error{
+		auth_service.ErrTokenRevoked,
+		auth_service.ErrTokenParsing,
+		auth_service.ErrTokenWrongType,
+		auth_service.ErrTokenTtlExpired,
+		auth_service.ErrTokenUnknownKey,
+		auth_service.ErrTokenWrongIssuer,
+		jwt.ErrTokenMalformed,
+		jwt.ErrTokenUnverifiable,
+		jwt.ErrTokenSignatureInvalid,
+		jwt.ErrTokenExpired,
+		jwt.ErrTokenNotValidYet,
+		jwt.ErrTokenInvalidClaims,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// weakPasswordStatus converts violations of password policy to
+// InvalidArgument. Details carry BadRequest with a violation of field per
+// broken rule and ErrorInfo with rules as keys of metadata, so clients can
+// show hints without parsing the message.
+func weakPasswordStatus(err error, field string) error {
+	var policyErr *auth_service.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	badRequest := &errdetails.BadRequest{}
+	info := &errdetails.ErrorInfo{
+		Reason:   "WEAK_PASSWORD",
+		Domain:   "sso",
+		Metadata: make(map[string]string, len(policyErr.Violations)),
+	}
+	for _, violation := range policyErr.Violations {
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       field,
+			Description: violation.Description,
+		})
+		info.Metadata[violation.Rule] = violation.Description
+	}
+	st := status.New(codes.InvalidArgument, err.Error())
+	detailed, detailsErr := st.WithDetails(badRequest, info)
+	if detailsErr != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
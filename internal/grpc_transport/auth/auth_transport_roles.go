@@ -0,0 +1,238 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sso/internal/domain/models"
+	"sso/internal/lib/gateway"
+	"sso/internal/services/auth_service"
+	ssov1 "sso/protos/proto/sso/gen"
+	"strconv"
+)
+
+func (s *serverAPI) CreateRole(
+	ctx context.Context,
+	req *ssov1.CreateRoleRequest,
+) (*ssov1.CreateRoleResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: create role",
+		trace.WithAttributes(attribute.String("handler", "createRole")))
+	defer span.End()
+
+	if err := validateCreateRole(req); err != nil {
+		return nil, err
+	}
+	roleID, err := s.auth.CreateRole(ctx, req.GetToken(), req.GetName(), req.GetDescription(), req.GetPermissions())
+	if err != nil {
+		switch {
+		case errors.Is(err, auth_service.ErrPermissionDenied):
+			return nil, status.Error(codes.PermissionDenied, "permission denied")
+		case errors.Is(err, auth_service.ErrRoleExists):
+			return nil, status.Error(codes.AlreadyExists, "role already exists")
+		case errors.Is(err, auth_service.ErrInvalidPermission):
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, status.Error(codes.Unauthenticated, "bad token")
+	}
+	return &ssov1.CreateRoleResponse{RoleId: roleID}, nil
+}
+
+func (s *serverAPI) GrantRole(
+	ctx context.Context,
+	req *ssov1.GrantRoleRequest,
+) (*ssov1.GrantRoleResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: grant role",
+		trace.WithAttributes(attribute.String("handler", "grantRole")))
+	defer span.End()
+
+	if err := validateRoleBinding(req.GetToken(), req.GetUserId(), req.GetRole()); err != nil {
+		return nil, err
+	}
+	success, err := s.auth.GrantRole(ctx, req.GetToken(), int(req.GetUserId()), req.GetRole())
+	if err != nil {
+		return nil, roleBindingError(err)
+	}
+	return &ssov1.GrantRoleResponse{Success: success}, nil
+}
+
+func (s *serverAPI) RevokeRole(
+	ctx context.Context,
+	req *ssov1.RevokeRoleRequest,
+) (*ssov1.RevokeRoleResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: revoke role",
+		trace.WithAttributes(attribute.String("handler", "revokeRole")))
+	defer span.End()
+
+	if err := validateRoleBinding(req.GetToken(), req.GetUserId(), req.GetRole()); err != nil {
+		return nil, err
+	}
+	success, err := s.auth.RevokeRole(ctx, req.GetToken(), int(req.GetUserId()), req.GetRole())
+	if err != nil {
+		return nil, roleBindingError(err)
+	}
+	return &ssov1.RevokeRoleResponse{Success: success}, nil
+}
+
+func (s *serverAPI) GetUserPermissions(
+	ctx context.Context,
+	req *ssov1.GetUserPermissionsRequest,
+) (*ssov1.GetUserPermissionsResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: get user permissions",
+		trace.WithAttributes(attribute.String("handler", "getUserPermissions")))
+	defer span.End()
+
+	token := gateway.BearerToken(ctx, req.GetToken())
+	if token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+	if req.GetUserId() == emptyId {
+		return nil, status.Error(codes.InvalidArgument, "userid is required")
+	}
+	roles, permissions, err := s.auth.UserPermissions(ctx, token, int(req.GetUserId()))
+	if err != nil {
+		if errors.Is(err, auth_service.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "permission denied")
+		}
+		return nil, status.Error(codes.Unauthenticated, "bad token")
+	}
+	return &ssov1.GetUserPermissionsResponse{
+		Roles:       roles,
+		Permissions: permissions,
+	}, nil
+}
+
+func roleBindingError(err error) error {
+	switch {
+	case errors.Is(err, auth_service.ErrPermissionDenied):
+		return status.Error(codes.PermissionDenied, "permission denied")
+	case errors.Is(err, auth_service.ErrRoleNotFound):
+		return status.Error(codes.NotFound, "role not found")
+	case errors.Is(err, auth_service.ErrUserNotFound):
+		return status.Error(codes.NotFound, "user not found")
+	}
+	return status.Error(codes.Unauthenticated, "bad token")
+}
+
+func validateCreateRole(req *ssov1.CreateRoleRequest) error {
+	if req.GetToken() == "" {
+		return status.Error(codes.InvalidArgument, "token is required")
+	}
+	if req.GetName() == "" {
+		return status.Error(codes.InvalidArgument, "name is required")
+	}
+	return nil
+}
+
+func validateRoleBinding(token string, userID int64, role string) error {
+	if token == "" {
+		return status.Error(codes.InvalidArgument, "token is required")
+	}
+	if userID == emptyId {
+		return status.Error(codes.InvalidArgument, "userid is required")
+	}
+	if role == "" {
+		return status.Error(codes.InvalidArgument, "role is required")
+	}
+	return nil
+}
+
+// maxPermissionChecks limits batch size, so one call can't load roles of
+// thousands of users.
+const maxPermissionChecks = 100
+
+func (s *serverAPI) CheckPermission(
+	ctx context.Context,
+	req *ssov1.CheckPermissionRequest,
+) (*ssov1.CheckPermissionResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: check permission",
+		trace.WithAttributes(attribute.String("handler", "checkPermission")))
+	defer span.End()
+
+	token := gateway.BearerToken(ctx, req.GetToken())
+	if token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+	check, err := permissionCheck(req)
+	if err != nil {
+		return nil, err
+	}
+	allowed, err := s.auth.CheckPermission(ctx, token, int(check.UserID), check.Action, check.Resource)
+	if err != nil {
+		return nil, permissionCheckError(err)
+	}
+	return &ssov1.CheckPermissionResponse{Allowed: allowed}, nil
+}
+
+func (s *serverAPI) CheckPermissions(
+	ctx context.Context,
+	req *ssov1.CheckPermissionsRequest,
+) (*ssov1.CheckPermissionsResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: check permissions",
+		trace.WithAttributes(attribute.String("handler", "checkPermissions")))
+	defer span.End()
+
+	token := gateway.BearerToken(ctx, req.GetToken())
+	if token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+	if err := validateCheckPermissions(req); err != nil {
+		return nil, err
+	}
+	checks := make([]models.PermissionCheck, 0, len(req.GetChecks()))
+	for _, checkReq := range req.GetChecks() {
+		check, err := permissionCheck(checkReq)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, check)
+	}
+	allowed, err := s.auth.CheckPermissions(ctx, token, checks)
+	if err != nil {
+		return nil, permissionCheckError(err)
+	}
+	return &ssov1.CheckPermissionsResponse{Allowed: allowed}, nil
+}
+
+func permissionCheckError(err error) error {
+	switch {
+	case errors.Is(err, auth_service.ErrPermissionDenied):
+		return status.Error(codes.PermissionDenied, "permission denied")
+	case isBadToken(err):
+		return status.Error(codes.Unauthenticated, "bad token")
+	}
+	return status.Error(codes.Internal, "internal error")
+}
+
+func validateCheckPermissions(req *ssov1.CheckPermissionsRequest) error {
+	if len(req.GetChecks()) == 0 {
+		return status.Error(codes.InvalidArgument, "checks are required")
+	}
+	if len(req.GetChecks()) > maxPermissionChecks {
+		return status.Errorf(codes.InvalidArgument, "at most %d checks are allowed", maxPermissionChecks)
+	}
+	return nil
+}
+
+func permissionCheck(req *ssov1.CheckPermissionRequest) (models.PermissionCheck, error) {
+	if req.GetSubject() == "" {
+		return models.PermissionCheck{}, status.Error(codes.InvalidArgument, "subject is required")
+	}
+	userID, err := strconv.ParseInt(req.GetSubject(), 10, 64)
+	if err != nil {
+		return models.PermissionCheck{}, status.Error(codes.InvalidArgument, "subject must be user id")
+	}
+	if req.GetAction() == "" {
+		return models.PermissionCheck{}, status.Error(codes.InvalidArgument, "action is required")
+	}
+	if req.GetResource() == "" {
+		return models.PermissionCheck{}, status.Error(codes.InvalidArgument, "resource is required")
+	}
+	return models.PermissionCheck{
+		UserID:   userID,
+		Action:   req.GetAction(),
+		Resource: req.GetResource(),
+	}, nil
+}
@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sso/internal/domain/models"
+	"sso/internal/lib/gateway"
+	"sso/internal/services/auth_service"
+	ssov1 "sso/protos/proto/sso/gen"
+)
+
+// maxTuples limits number of tuples written or deleted in one call.
+const maxTuples = 100
+
+func (s *serverAPI) WriteTuples(
+	ctx context.Context,
+	req *ssov1.WriteTuplesRequest,
+) (*ssov1.WriteTuplesResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: write tuples",
+		trace.WithAttributes(attribute.String("handler", "writeTuples")))
+	defer span.End()
+
+	token := gateway.BearerToken(ctx, req.GetToken())
+	if err := validateTuplesRequest(token, req.GetTuples()); err != nil {
+		return nil, err
+	}
+	success, err := s.auth.WriteTuples(ctx, token, tuplesFromRequest(req.GetTuples()))
+	if err != nil {
+		return nil, tuplesError(err)
+	}
+	return &ssov1.WriteTuplesResponse{Success: success}, nil
+}
+
+func (s *serverAPI) DeleteTuples(
+	ctx context.Context,
+	req *ssov1.DeleteTuplesRequest,
+) (*ssov1.DeleteTuplesResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: delete tuples",
+		trace.WithAttributes(attribute.String("handler", "deleteTuples")))
+	defer span.End()
+
+	token := gateway.BearerToken(ctx, req.GetToken())
+	if err := validateTuplesRequest(token, req.GetTuples()); err != nil {
+		return nil, err
+	}
+	success, err := s.auth.DeleteTuples(ctx, token, tuplesFromRequest(req.GetTuples()))
+	if err != nil {
+		return nil, tuplesError(err)
+	}
+	return &ssov1.DeleteTuplesResponse{Success: success}, nil
+}
+
+func (s *serverAPI) Check(
+	ctx context.Context,
+	req *ssov1.CheckRequest,
+) (*ssov1.CheckResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: check",
+		trace.WithAttributes(attribute.String("handler", "check")))
+	defer span.End()
+
+	token := gateway.BearerToken(ctx, req.GetToken())
+	if token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+	allowed, err := s.auth.Check(ctx, token, req.GetObject(), req.GetRelation(), req.GetSubject())
+	if err != nil {
+		return nil, tupleReadError(err)
+	}
+	return &ssov1.CheckResponse{Allowed: allowed}, nil
+}
+
+func (s *serverAPI) ListObjects(
+	ctx context.Context,
+	req *ssov1.ListObjectsRequest,
+) (*ssov1.ListObjectsResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: list objects",
+		trace.WithAttributes(attribute.String("handler", "listObjects")))
+	defer span.End()
+
+	token := gateway.BearerToken(ctx, req.GetToken())
+	if token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+	objects, err := s.auth.ListObjects(ctx, token, req.GetObjectType(), req.GetRelation(), req.GetSubject())
+	if err != nil {
+		return nil, tupleReadError(err)
+	}
+	return &ssov1.ListObjectsResponse{Objects: objects}, nil
+}
+
+func tuplesFromRequest(reqTuples []*ssov1.RelationTuple) []models.Tuple {
+	tuples := make([]models.Tuple, 0, len(reqTuples))
+	for _, tuple := range reqTuples {
+		tuples = append(tuples, models.Tuple{
+			Object:   tuple.GetObject(),
+			Relation: tuple.GetRelation(),
+			Subject:  tuple.GetSubject(),
+		})
+	}
+	return tuples
+}
+
+func tuplesError(err error) error {
+	switch {
+	case errors.Is(err, auth_service.ErrPermissionDenied):
+		return status.Error(codes.PermissionDenied, "permission denied")
+	case errors.Is(err, auth_service.ErrInvalidTuple):
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return status.Error(codes.Unauthenticated, "bad token")
+}
+
+// tupleReadError converts errors of Check and ListObjects, only failures
+// of the storage are internal.
+func tupleReadError(err error) error {
+	switch {
+	case errors.Is(err, auth_service.ErrInvalidTuple):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case isBadToken(err):
+		return status.Error(codes.Unauthenticated, "bad token")
+	}
+	return status.Error(codes.Internal, "internal error")
+}
+
+func validateTuplesRequest(token string, tuples []*ssov1.RelationTuple) error {
+	if token == "" {
+		return status.Error(codes.InvalidArgument, "token is required")
+	}
+	if len(tuples) == 0 {
+		return status.Error(codes.InvalidArgument, "tuples are required")
+	}
+	if len(tuples) > maxTuples {
+		return status.Errorf(codes.InvalidArgument, "at most %d tuples are allowed", maxTuples)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sso/internal/services/auth_service"
+	ssov1 "sso/protos/proto/sso/gen"
+)
+
+func (s *serverAPI) VerifyEmail(
+	ctx context.Context,
+	req *ssov1.VerifyEmailRequest,
+) (*ssov1.VerifyEmailResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: verify email",
+		trace.WithAttributes(attribute.String("handler", "verifyEmail")))
+	defer span.End()
+
+	if req.GetToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+	success, err := s.auth.VerifyEmail(ctx, req.GetToken())
+	if err != nil {
+		if errors.Is(err, auth_service.ErrInvalidActionToken) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.VerifyEmailResponse{Success: success}, nil
+}
+
+func (s *serverAPI) ResendVerification(
+	ctx context.Context,
+	req *ssov1.ResendVerificationRequest,
+) (*ssov1.ResendVerificationResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "transport layer: resend verification",
+		trace.WithAttributes(attribute.String("handler", "resendVerification")))
+	defer span.End()
+
+	if req.GetEmail() == "" {
+		return nil, status.Error(codes.InvalidArgument, "email is required")
+	}
+	success, err := s.auth.ResendVerification(ctx, req.GetEmail(), req.GetTenantId())
+	if err != nil {
+		if errors.Is(err, auth_service.ErrTooManyRequests) {
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		}
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &ssov1.ResendVerificationResponse{Success: success}, nil
+}
@@ -0,0 +1,45 @@
+package oauth2
+
+import "html/template"
+
+// loginPage is a minimal login form of authorization endpoint, parameters
+// of authorization request are passed through hidden fields.
+var loginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>Sign in</title>
+  <style>
+    body { font-family: sans-serif; display: flex; justify-content: center; margin-top: 10vh; }
+    form { display: flex; flex-direction: column; gap: 0.75em; width: 18em; }
+    .error { color: #b00020; }
+  </style>
+</head>
+<body>
+  <form method="post" action="/oauth2/authorize">
+    <h2>Sign in to {{.AppName}}</h2>
+    {{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+    <input type="email" name="email" placeholder="Email" value="{{.Email}}" required autofocus>
+    <input type="password" name="password" placeholder="Password" required>
+    {{range $name, $value := .Params}}<input type="hidden" name="{{$name}}" value="{{$value}}">
+    {{end}}<button type="submit">Sign in</button>
+  </form>
+</body>
+</html>
+`))
+
+// errorPage is shown when the user can't be redirected back to the app.
+var errorPage = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><title>Authorization error</title></head>
+<body><h2>Authorization error</h2><p>{{.}}</p></body>
+</html>
+`))
+
+type loginPageData struct {
+	AppName string
+	Email   string
+	Error   string
+	Params  map[string]string
+}
@@ -0,0 +1,374 @@
+package oauth2
+
+// HTTP TRANSPORT LAYER
+// OAuth 2.0 authorization code flow needs redirects and html pages, so it is
+// served by plain http handlers next to grpc-gateway rather than by grpc.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"sso/internal/config"
+	"sso/internal/domain/models"
+	"sso/internal/lib/gateway"
+	"sso/internal/services/auth_service"
+	"strconv"
+	"strings"
+)
+
+const (
+	grantTypeAuthorizationCode = "authorization_code"
+	grantTypeRefreshToken      = "refresh_token"
+	grantTypeClientCredentials = "client_credentials"
+)
+
+// authorization request parameters passed through the login form
+var authorizationParams = []string{
+	"response_type", "client_id", "redirect_uri", "scope", "state",
+	"code_challenge", "code_challenge_method", "nonce", "tenant_id",
+}
+
+type handler struct {
+	log  *slog.Logger
+	auth auth_service.AuthorizationInterface
+	cfg  *config.Config
+	// proxies allowed to pass address of the browser
+	proxies gateway.TrustedProxies
+	tracer  trace.Tracer
+}
+
+// Register adds OAuth 2.0 authorization and token endpoints to the mux.
+func Register(mux *http.ServeMux, log *slog.Logger, auth auth_service.AuthorizationInterface, cfg *config.Config) {
+	h := &handler{
+		log:     log,
+		auth:    auth,
+		cfg:     cfg,
+		proxies: gateway.MustTrustedProxies(cfg.TrustedProxies),
+		tracer:  otel.Tracer("sso service"),
+	}
+	mux.HandleFunc("/oauth2/authorize", h.authorize)
+	mux.HandleFunc("/oauth2/token", h.token)
+}
+
+// tokenResponse is successful token endpoint response (RFC 6749 5.1).
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+}
+
+// errorResponse is token endpoint error response (RFC 6749 5.2).
+type errorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+func (h *handler) authorize(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(h.withClientMetadata(r), "http transport layer: authorize",
+		trace.WithAttributes(attribute.String("handler", "authorize")))
+	defer span.End()
+
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		renderError(w, http.StatusBadRequest, "malformed request")
+		return
+	}
+	req, err := parseAuthorizationRequest(r.Form)
+	if err != nil {
+		renderError(w, http.StatusBadRequest, "unknown client")
+		return
+	}
+
+	app, err := h.auth.ValidateAuthorizationRequest(ctx, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth_service.ErrInvalidClient):
+			renderError(w, http.StatusBadRequest, "unknown client")
+		case errors.Is(err, auth_service.ErrInvalidRedirectURI):
+			renderError(w, http.StatusBadRequest, "redirect uri is not registered for the client")
+		case errors.Is(err, auth_service.ErrInvalidRequest):
+			redirectWithParams(w, r, req.RedirectURI, url.Values{
+				"error":             {"invalid_request"},
+				"error_description": {err.Error()},
+				"state":             {req.State},
+			})
+		case errors.Is(err, auth_service.ErrInvalidScope):
+			redirectWithParams(w, r, req.RedirectURI, url.Values{
+				"error":             {"invalid_scope"},
+				"error_description": {err.Error()},
+				"state":             {req.State},
+			})
+		default:
+			h.log.Error("failed to validate authorization request", slog.String("error", err.Error()))
+			renderError(w, http.StatusInternalServerError, "internal error")
+		}
+		return
+	}
+
+	page := loginPageData{AppName: app.Name, Params: make(map[string]string, len(authorizationParams))}
+	for _, name := range authorizationParams {
+		page.Params[name] = r.Form.Get(name)
+	}
+	if r.Method == http.MethodGet {
+		renderLogin(w, http.StatusOK, page)
+		return
+	}
+
+	email, password := r.PostForm.Get("email"), r.PostForm.Get("password")
+	code, err := h.auth.Authorize(ctx, req, email, password)
+	if err != nil {
+		page.Email = email
+		if errors.Is(err, auth_service.ErrInvalidCredentials) {
+			page.Error = "Invalid email or password"
+			renderLogin(w, http.StatusUnauthorized, page)
+			return
+		}
+		if errors.Is(err, auth_service.ErrEmailNotVerified) {
+			page.Error = "Please verify your email first, check your inbox for the link"
+			renderLogin(w, http.StatusForbidden, page)
+			return
+		}
+		if errors.Is(err, auth_service.ErrUserDisabled) {
+			page.Error = "Your account is disabled"
+			renderLogin(w, http.StatusForbidden, page)
+			return
+		}
+		var lockout *auth_service.LockoutError
+		if errors.As(err, &lockout) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(lockout.RetryAfter.Seconds()))))
+			page.Error = "Too many failed attempts, please try again later"
+			renderLogin(w, http.StatusTooManyRequests, page)
+			return
+		}
+		h.log.Error("failed to authorize", slog.String("error", err.Error()))
+		page.Error = "Something went wrong, please try again"
+		renderLogin(w, http.StatusInternalServerError, page)
+		return
+	}
+	redirectWithParams(w, r, req.RedirectURI, url.Values{
+		"code":  {code},
+		"state": {req.State},
+	})
+}
+
+func (h *handler) token(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(h.withClientMetadata(r), "http transport layer: token",
+		trace.WithAttributes(attribute.String("handler", "token")))
+	defer span.End()
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		writeTokenError(w, http.StatusMethodNotAllowed, "invalid_request", "POST is required")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeTokenError(w, http.StatusBadRequest, "invalid_request", "malformed request")
+		return
+	}
+
+	switch grantType := r.PostForm.Get("grant_type"); grantType {
+	case grantTypeAuthorizationCode:
+		clientID, clientSecret, err := clientCredentials(r)
+		if err != nil {
+			writeTokenError(w, http.StatusUnauthorized, "invalid_client", "")
+			return
+		}
+		accessToken, refreshToken, idToken, err := h.auth.ExchangeAuthorizationCode(ctx,
+			clientID, clientSecret,
+			r.PostForm.Get("code"), r.PostForm.Get("redirect_uri"), r.PostForm.Get("code_verifier"),
+		)
+		if err != nil {
+			h.writeGrantError(w, err)
+			return
+		}
+		h.writeTokens(w, tokenResponse{AccessToken: accessToken, RefreshToken: refreshToken, IDToken: idToken})
+	case grantTypeRefreshToken:
+		// public clients send only client_id, it must match the refresh token
+		clientID, clientSecret, err := clientCredentials(r)
+		if err != nil {
+			writeTokenError(w, http.StatusUnauthorized, "invalid_client", "")
+			return
+		}
+		accessToken, refreshToken, err := h.auth.RefreshAppTokens(ctx,
+			clientID, clientSecret, r.PostForm.Get("refresh_token"), strings.Fields(r.PostForm.Get("scope")),
+		)
+		if err != nil {
+			if errors.Is(err, auth_service.ErrInvalidClient) {
+				w.Header().Set("WWW-Authenticate", `Basic realm="sso"`)
+				writeTokenError(w, http.StatusUnauthorized, "invalid_client", "")
+				return
+			}
+			if errors.Is(err, auth_service.ErrInvalidScope) {
+				writeTokenError(w, http.StatusBadRequest, "invalid_scope", "")
+				return
+			}
+			writeTokenError(w, http.StatusBadRequest, "invalid_grant", "")
+			return
+		}
+		h.writeTokens(w, tokenResponse{AccessToken: accessToken, RefreshToken: refreshToken})
+	case grantTypeClientCredentials:
+		clientID, clientSecret, err := clientCredentials(r)
+		if err != nil || clientSecret == "" {
+			writeTokenError(w, http.StatusUnauthorized, "invalid_client", "")
+			return
+		}
+		accessToken, err := h.auth.ClientCredentials(ctx,
+			clientID, clientSecret, strings.Fields(r.PostForm.Get("scope")),
+		)
+		if err != nil {
+			h.writeGrantError(w, err)
+			return
+		}
+		// no refresh token, the app can always request a new access token
+		h.writeTokens(w, tokenResponse{AccessToken: accessToken})
+	default:
+		writeTokenError(w, http.StatusBadRequest, "unsupported_grant_type", grantType)
+	}
+}
+
+func (h *handler) writeGrantError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth_service.ErrInvalidClient):
+		w.Header().Set("WWW-Authenticate", `Basic realm="sso"`)
+		writeTokenError(w, http.StatusUnauthorized, "invalid_client", "")
+	case errors.Is(err, auth_service.ErrInvalidGrant):
+		writeTokenError(w, http.StatusBadRequest, "invalid_grant", "")
+	case errors.Is(err, auth_service.ErrInvalidScope):
+		writeTokenError(w, http.StatusBadRequest, "invalid_scope", err.Error())
+	default:
+		h.log.Error("failed to issue tokens", slog.String("error", err.Error()))
+		writeTokenError(w, http.StatusInternalServerError, "server_error", "")
+	}
+}
+
+func (h *handler) writeTokens(w http.ResponseWriter, resp tokenResponse) {
+	resp.TokenType = "Bearer"
+	resp.ExpiresIn = int64(h.cfg.AccessTokenTtl.Seconds())
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func writeTokenError(w http.ResponseWriter, code int, errCode string, description string) {
+	writeJSON(w, code, errorResponse{Error: errCode, ErrorDescription: description})
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	// RFC 6749 5.1: responses with tokens must not be cached
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// clientCredentials returns credentials passed with HTTP Basic authentication
+// or in the form (RFC 6749 2.3.1). Public clients send only client_id.
+func clientCredentials(r *http.Request) (int, string, error) {
+	rawClientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		// credentials are form-urlencoded before basic encoding
+		var err error
+		if rawClientID, err = url.QueryUnescape(rawClientID); err != nil {
+			return 0, "", err
+		}
+		if clientSecret, err = url.QueryUnescape(clientSecret); err != nil {
+			return 0, "", err
+		}
+	} else {
+		rawClientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	clientID, err := strconv.Atoi(rawClientID)
+	if err != nil {
+		return 0, "", err
+	}
+	return clientID, clientSecret, nil
+}
+
+func parseAuthorizationRequest(form url.Values) (models.AuthorizationRequest, error) {
+	clientID, err := strconv.Atoi(form.Get("client_id"))
+	if err != nil {
+		return models.AuthorizationRequest{}, err
+	}
+	// tenant is optional, accounts of the default organization omit it
+	var tenantID int64
+	if rawTenantID := form.Get("tenant_id"); rawTenantID != "" {
+		tenantID, err = strconv.ParseInt(rawTenantID, 10, 64)
+		if err != nil {
+			return models.AuthorizationRequest{}, err
+		}
+	}
+	return models.AuthorizationRequest{
+		ResponseType:        form.Get("response_type"),
+		ClientID:            clientID,
+		RedirectURI:         form.Get("redirect_uri"),
+		Scope:               form.Get("scope"),
+		State:               form.Get("state"),
+		CodeChallenge:       form.Get("code_challenge"),
+		CodeChallengeMethod: form.Get("code_challenge_method"),
+		Nonce:               form.Get("nonce"),
+		TenantID:            tenantID,
+	}, nil
+}
+
+// redirectWithParams redirects to the registered redirect uri, keeping its own query.
+func redirectWithParams(w http.ResponseWriter, r *http.Request, redirectURI string, params url.Values) {
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		renderError(w, http.StatusBadRequest, "malformed redirect uri")
+		return
+	}
+	query := target.Query()
+	for name, values := range params {
+		if len(values) > 0 && values[0] != "" {
+			query.Set(name, values[0])
+		}
+	}
+	target.RawQuery = query.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+func renderLogin(w http.ResponseWriter, code int, page loginPageData) {
+	setPageHeaders(w)
+	w.WriteHeader(code)
+	_ = loginPage.Execute(w, page)
+}
+
+func renderError(w http.ResponseWriter, code int, message string) {
+	setPageHeaders(w)
+	w.WriteHeader(code)
+	_ = errorPage.Execute(w, message)
+}
+
+func setPageHeaders(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	// login form must not be framed by other sites (clickjacking)
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "frame-ancestors 'none'")
+}
+
+// withClientMetadata passes user agent and ip of the browser to the service
+// layer. The ip is resolved here, so the service sees the browser as the
+// peer and X-Forwarded-For of untrusted clients never reaches it.
+func (h *handler) withClientMetadata(r *http.Request) context.Context {
+	ctx := metadata.NewIncomingContext(r.Context(), metadata.Pairs("user-agent", r.UserAgent()))
+	if ip := net.ParseIP(h.proxies.RequestIP(r)); ip != nil {
+		ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: ip}})
+	}
+	return ctx
+}
@@ -0,0 +1,95 @@
+package authz
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DecisionCache keeps results of permission checks in memory. Decisions of
+// a user are dropped as soon as their role bindings change on this instance,
+// ttl bounds staleness of changes made on other instances.
+type DecisionCache struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	maxSize int
+	users   map[int64]*userDecisions
+}
+
+type userDecisions struct {
+	expiresAt time.Time
+	decisions map[string]bool
+}
+
+// NewDecisionCache returns cache, zero ttl disables caching.
+func NewDecisionCache(ttl time.Duration, maxSize int) *DecisionCache {
+	return &DecisionCache{
+		ttl:     ttl,
+		maxSize: maxSize,
+		users:   make(map[int64]*userDecisions),
+	}
+}
+
+// Get returns cached decision, ok is false on cache miss. Decisions are
+// cached per tenant of the caller, the user may be unknown in another one.
+func (c *DecisionCache) Get(
+	tenantID int64,
+	userID int64,
+	action string,
+	resource string,
+	now time.Time,
+) (allowed bool, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, found := c.users[userID]
+	if !found || !now.Before(entry.expiresAt) {
+		return false, false
+	}
+	allowed, ok = entry.decisions[decisionKey(tenantID, action, resource)]
+	return allowed, ok
+}
+
+// Set caches decision, all decisions of the user expire together, so a
+// cached "allowed" never outlives a revocation by more than ttl.
+func (c *DecisionCache) Set(
+	tenantID int64,
+	userID int64,
+	action string,
+	resource string,
+	allowed bool,
+	now time.Time,
+) {
+	if c.ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, found := c.users[userID]
+	if !found || !now.Before(entry.expiresAt) {
+		if !found && c.maxSize > 0 && len(c.users) >= c.maxSize {
+			c.users = make(map[int64]*userDecisions)
+		}
+		entry = &userDecisions{
+			expiresAt: now.Add(c.ttl),
+			decisions: make(map[string]bool),
+		}
+		c.users[userID] = entry
+	}
+	entry.decisions[decisionKey(tenantID, action, resource)] = allowed
+}
+
+// InvalidateUser drops decisions of the user, it must be called whenever
+// roles of the user change.
+func (c *DecisionCache) InvalidateUser(userID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.users, userID)
+}
+
+func decisionKey(tenantID int64, action string, resource string) string {
+	// actions never contain ':', so the key is unambiguous
+	return strconv.FormatInt(tenantID, 10) + ":" + action + ":" + resource
+}
@@ -0,0 +1,45 @@
+package authz
+
+// Rewrites maps object type to relations and relations implying them:
+// rewrites["calc_task"]["viewer"] = ["editor"] means editors are viewers.
+type Rewrites map[string]map[string][]string
+
+// Implying returns relation and every relation implying it, directly or
+// through other relations, so checking relation means looking for any of them.
+func (r Rewrites) Implying(objectType string, relation string) []string {
+	return closure(relation, func(rel string) []string {
+		return r[objectType][rel]
+	})
+}
+
+// Implied returns relation and every relation it implies, so subject
+// having relation has all of them.
+func (r Rewrites) Implied(objectType string, relation string) []string {
+	return closure(relation, func(rel string) []string {
+		implied := make([]string, 0)
+		for candidate, implying := range r[objectType] {
+			for _, imp := range implying {
+				if imp == rel {
+					implied = append(implied, candidate)
+				}
+			}
+		}
+		return implied
+	})
+}
+
+// closure walks relations breadth first, cycles in config are harmless.
+func closure(relation string, next func(string) []string) []string {
+	result := []string{relation}
+	seen := map[string]struct{}{relation: {}}
+	for i := 0; i < len(result); i++ {
+		for _, rel := range next(result[i]) {
+			if _, ok := seen[rel]; ok {
+				continue
+			}
+			seen[rel] = struct{}{}
+			result = append(result, rel)
+		}
+	}
+	return result
+}
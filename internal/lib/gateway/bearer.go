@@ -0,0 +1,22 @@
+package gateway
+
+import (
+	"context"
+	"google.golang.org/grpc/metadata"
+	"strings"
+)
+
+// BearerToken returns access token passed in "Authorization: Bearer" header
+// (RFC 6750), the gateway forwards it as metadata. Falls back to the token
+// passed in the request.
+func BearerToken(ctx context.Context, fallback string) string {
+	const prefix = "Bearer "
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, value := range md.Get("authorization") {
+			if len(value) > len(prefix) && strings.EqualFold(value[:len(prefix)], prefix) {
+				return value[len(prefix):]
+			}
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,113 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// TrustedProxies are networks of proxies allowed to pass address of the
+// client in "X-Forwarded-For", e.g. grpc-gateway calling the service over
+// loopback. The header of any other peer is ignored, clients set it freely.
+type TrustedProxies []*net.IPNet
+
+// NewTrustedProxies parses CIDRs, plain addresses trust a single host.
+func NewTrustedProxies(cidrs []string) (TrustedProxies, error) {
+	proxies := make(TrustedProxies, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("trusted proxy %q is not an ip", cidr)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q: %w", cidr, err)
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies, nil
+}
+
+// MustTrustedProxies is like NewTrustedProxies but panics on a malformed CIDR
+func MustTrustedProxies(cidrs []string) TrustedProxies {
+	proxies, err := NewTrustedProxies(cidrs)
+	if err != nil {
+		panic(err)
+	}
+	return proxies
+}
+
+// ClientIP returns address of the grpc peer. Calls through the gateway
+// come from the gateway itself, it appends the original address to
+// "X-Forwarded-For", so the header is read if the peer is trusted.
+func (p TrustedProxies) ClientIP(ctx context.Context) string {
+	remote, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	return p.resolve(remote.Addr.String(), md.Get("x-forwarded-for"))
+}
+
+// RequestIP is ClientIP of a plain http request.
+func (p TrustedProxies) RequestIP(r *http.Request) string {
+	return p.resolve(r.RemoteAddr, r.Header.Values("X-Forwarded-For"))
+}
+
+// resolve walks forwarded addresses from the right, every proxy appends
+// address of its peer, and returns the first one which isn't a trusted
+// proxy. Addresses left of it are set by the client and can't be trusted.
+func (p TrustedProxies) resolve(remoteAddr string, forwardedFor []string) string {
+	clientIP := remoteAddr
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		clientIP = host
+	}
+	if !p.contains(clientIP) {
+		return clientIP
+	}
+	var hops []string
+	for _, value := range forwardedFor {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			// garbage in the header, the last proxy is the best we know
+			break
+		}
+		clientIP = hop
+		if !p.contains(hop) {
+			break
+		}
+	}
+	return clientIP
+}
+
+func (p TrustedProxies) contains(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, network := range p {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
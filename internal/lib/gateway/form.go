@@ -0,0 +1,53 @@
+package gateway
+
+import (
+	"fmt"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/utilities"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"net/url"
+)
+
+// MIMEForm is content type of html forms, OAuth 2.0 endpoints (RFC 6749, RFC 7662)
+// accept request parameters only in this format.
+const MIMEForm = "application/x-www-form-urlencoded"
+
+// FormMarshaler decodes application/x-www-form-urlencoded request bodies
+// into proto messages, responses are encoded as JSON.
+type FormMarshaler struct {
+	runtime.Marshaler
+}
+
+// NewFormMarshaler returns marshaler to be registered for MIMEForm in grpc-gateway mux.
+func NewFormMarshaler() *FormMarshaler {
+	return &FormMarshaler{
+		Marshaler: &runtime.JSONPb{
+			MarshalOptions:   protojson.MarshalOptions{EmitUnpopulated: true},
+			UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+		},
+	}
+}
+
+func (m *FormMarshaler) Unmarshal(data []byte, v interface{}) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("gateway.FormMarshaler: %T is not a proto message", v)
+	}
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return fmt.Errorf("gateway.FormMarshaler: %w", err)
+	}
+	return runtime.PopulateQueryParameters(msg, values, utilities.NewDoubleArray(nil))
+}
+
+func (m *FormMarshaler) NewDecoder(r io.Reader) runtime.Decoder {
+	return runtime.DecoderFunc(func(v interface{}) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		return m.Unmarshal(data, v)
+	})
+}
@@ -1,33 +1,286 @@
 package jwt
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"sso/internal/config"
+	"strconv"
 	"time"
 
 	"sso/internal/domain/models"
 )
 
+var (
+	ErrNoSubject    = errors.New("token has no subject")
+	ErrNotUserToken = errors.New("token was issued to an app, not to a user")
+)
+
+// GrantClientCredentials is "gty" claim of tokens issued to apps by
+// client credentials grant, their subject is client_id rather than user id.
+const GrantClientCredentials = "client_credentials"
+
+// TokenParams holds claims which depend on the session rather than on the user.
+type TokenParams struct {
+	// SessionID identifies login session, it is shared by all refresh tokens
+	// issued by rotation from one login (token family)
+	SessionID string
+	// Nonce is passed by OpenID Connect client to bind ID token to its request
+	Nonce string
+	// Audience overrides "aud" claim, cfg.OIDC.Audience is used if empty
+	Audience string
+	// ClientID is the app the token was issued to
+	ClientID string
+	// Scope is space separated list of granted scopes (RFC 8693 4.2)
+	Scope string
+	// TenantID is the tenant user is looked up in, claim is taken from the user
+	TenantID int64
+	// Roles are names of roles bound to the user, only access tokens carry them
+	Roles []string
+	// Permissions are effective permissions of the user as "action:resource"
+	Permissions []string
+	// Groups are names of groups the user is a member of, including
+	// groups reached through nested subgroups
+	Groups []string
+}
+
 // NewToken creates new JWT token for given user and app.
 func NewToken(
 	user models.User,
 	cfg *config.Config,
+	key *SigningKey,
 	tokenType string,
+	params TokenParams,
 ) (string, error) {
-	token := jwt.New(jwt.SigningMethodHS256)
+	token := jwt.New(key.Method)
+	token.Header["kid"] = key.Kid
 
 	claims := token.Claims.(jwt.MapClaims)
 	claims["token_type"] = tokenType
-	claims["uid"] = user.ID
+	claims["iss"] = cfg.OIDC.Issuer
+	claims["sub"] = strconv.FormatInt(user.ID, 10)
+	claims["aud"] = params.audience(cfg)
 	claims["email"] = user.Email
+	claims["tenant_id"] = user.TenantID
+	claims["email_verified"] = user.EmailVerified
+	claims["iat"] = time.Now().Unix()
+	claims["jti"] = uuid.NewString()
+	claims["sid"] = params.SessionID
+	params.setAppClaims(claims)
 	if tokenType == "access" {
+		params.setAuthorizationClaims(claims)
 		claims["exp"] = time.Now().Add(cfg.AccessTokenTtl).Unix()
 	} else {
 		claims["exp"] = time.Now().Add(cfg.RefreshTokenTtl).Unix()
 	}
-	tokenString, err := token.SignedString([]byte(cfg.ServiceSecret))
+	tokenString, err := token.SignedString(key.signKey)
 	if err != nil {
 		return "", err
 	}
 	return tokenString, nil
 }
+
+// NewIDToken creates OpenID Connect ID token for given user. ID token only
+// tells the client who has logged in, it has no token_type and is never
+// accepted as access or refresh token.
+func NewIDToken(
+	user models.User,
+	cfg *config.Config,
+	key *SigningKey,
+	params TokenParams,
+) (string, error) {
+	token := jwt.New(key.Method)
+	token.Header["kid"] = key.Kid
+
+	now := time.Now()
+	claims := token.Claims.(jwt.MapClaims)
+	claims["iss"] = cfg.OIDC.Issuer
+	claims["sub"] = strconv.FormatInt(user.ID, 10)
+	claims["aud"] = params.audience(cfg)
+	claims["email"] = user.Email
+	claims["tenant_id"] = user.TenantID
+	claims["email_verified"] = user.EmailVerified
+	claims["iat"] = now.Unix()
+	claims["auth_time"] = now.Unix()
+	claims["exp"] = now.Add(cfg.AccessTokenTtl).Unix()
+	claims["sid"] = params.SessionID
+	if params.Nonce != "" {
+		claims["nonce"] = params.Nonce
+	}
+	return token.SignedString(key.signKey)
+}
+
+// NewClientToken creates access token for the app itself (client
+// credentials grant). There is no user, so no email and session.
+func NewClientToken(
+	app models.App,
+	cfg *config.Config,
+	key *SigningKey,
+	params TokenParams,
+) (string, error) {
+	token := jwt.New(key.Method)
+	token.Header["kid"] = key.Kid
+
+	now := time.Now()
+	claims := token.Claims.(jwt.MapClaims)
+	claims["token_type"] = "access"
+	claims["gty"] = GrantClientCredentials
+	claims["iss"] = cfg.OIDC.Issuer
+	claims["sub"] = strconv.Itoa(app.ID)
+	claims["aud"] = params.audience(cfg)
+	claims["client_id"] = strconv.Itoa(app.ID)
+	claims["tenant_id"] = app.TenantID
+	if params.Scope != "" {
+		claims["scope"] = params.Scope
+	}
+	claims["iat"] = now.Unix()
+	claims["jti"] = uuid.NewString()
+	claims["exp"] = now.Add(cfg.AccessTokenTtl).Unix()
+	return token.SignedString(key.signKey)
+}
+
+// token_type of tokens sent to users by email.
+const (
+	ActionEmailVerification = "email_verification"
+	ActionPasswordReset     = "password_reset"
+	ActionEmailChange       = "email_change"
+)
+
+// NewActionToken creates short-lived token authorizing a single action of
+// the user sent to them by email, e.g. verification of the email. Email is
+// embedded, so the token stops working once the user changes it.
+func NewActionToken(
+	user models.User,
+	cfg *config.Config,
+	key *SigningKey,
+	action string,
+	ttl time.Duration,
+) (string, error) {
+	return newActionToken(user, cfg, key, action, ttl, nil)
+}
+
+// NewPasswordResetToken creates action token of the reset link. It carries
+// fingerprint of the current password hash, so the link stops working once
+// the password changes, by this very link or otherwise.
+func NewPasswordResetToken(
+	user models.User,
+	cfg *config.Config,
+	key *SigningKey,
+	ttl time.Duration,
+) (string, error) {
+	return newActionToken(user, cfg, key, ActionPasswordReset, ttl, jwt.MapClaims{
+		"pwd": PasswordFingerprint(user.PassHash),
+	})
+}
+
+// PasswordFingerprint identifies password hash without disclosing it, the
+// bcrypt hash is salted, so the fingerprint can't be used to guess passwords.
+func PasswordFingerprint(passHash []byte) string {
+	sum := sha256.Sum256(passHash)
+	return base64.RawURLEncoding.EncodeToString(sum[:16])
+}
+
+// NewEmailChangeToken creates action token confirming change of email of
+// the user to newEmail, it is sent to the new address.
+func NewEmailChangeToken(
+	user models.User,
+	newEmail string,
+	cfg *config.Config,
+	key *SigningKey,
+	ttl time.Duration,
+) (string, error) {
+	return newActionToken(user, cfg, key, ActionEmailChange, ttl, jwt.MapClaims{"new_email": newEmail})
+}
+
+func newActionToken(
+	user models.User,
+	cfg *config.Config,
+	key *SigningKey,
+	action string,
+	ttl time.Duration,
+	extra jwt.MapClaims,
+) (string, error) {
+	token := jwt.New(key.Method)
+	token.Header["kid"] = key.Kid
+
+	now := time.Now()
+	claims := token.Claims.(jwt.MapClaims)
+	claims["token_type"] = action
+	claims["iss"] = cfg.OIDC.Issuer
+	claims["sub"] = strconv.FormatInt(user.ID, 10)
+	claims["email"] = user.Email
+	claims["tenant_id"] = user.TenantID
+	claims["iat"] = now.Unix()
+	claims["jti"] = uuid.NewString()
+	claims["exp"] = now.Add(ttl).Unix()
+	for name, value := range extra {
+		claims[name] = value
+	}
+	return token.SignedString(key.signKey)
+}
+
+// IsClientToken reports whether token was issued to an app by client credentials grant.
+func IsClientToken(claims jwt.MapClaims) bool {
+	return claims["gty"] == GrantClientCredentials
+}
+
+// UserID returns id of the user the token was issued to. Tokens issued
+// before "sub" claim was introduced carry it in "uid".
+func UserID(claims jwt.MapClaims) (int64, error) {
+	// subject of app tokens is client_id, it must never be taken for user id
+	if IsClientToken(claims) {
+		return 0, ErrNotUserToken
+	}
+	if sub, ok := claims["sub"].(string); ok {
+		id, err := strconv.ParseInt(sub, 10, 64)
+		if err != nil {
+			return 0, ErrNoSubject
+		}
+		return id, nil
+	}
+	if uid, ok := claims["uid"].(float64); ok {
+		return int64(uid), nil
+	}
+	return 0, ErrNoSubject
+}
+
+// TenantID returns tenant of the user the token was issued to. Tokens
+// issued before tenants were introduced belong to the default tenant.
+func TenantID(claims jwt.MapClaims) int64 {
+	if tenantID, ok := claims["tenant_id"].(float64); ok && tenantID > 0 {
+		return int64(tenantID)
+	}
+	return models.DefaultTenantID
+}
+
+func (p TokenParams) setAppClaims(claims jwt.MapClaims) {
+	if p.ClientID != "" {
+		claims["client_id"] = p.ClientID
+	}
+	if p.Scope != "" {
+		claims["scope"] = p.Scope
+	}
+}
+
+// setAuthorizationClaims embeds roles, permissions and groups, so resource servers
+// can authorize requests without calling the service.
+func (p TokenParams) setAuthorizationClaims(claims jwt.MapClaims) {
+	if len(p.Roles) > 0 {
+		claims["roles"] = p.Roles
+	}
+	if len(p.Permissions) > 0 {
+		claims["permissions"] = p.Permissions
+	}
+	if len(p.Groups) > 0 {
+		claims["groups"] = p.Groups
+	}
+}
+
+func (p TokenParams) audience(cfg *config.Config) string {
+	if p.Audience != "" {
+		return p.Audience
+	}
+	return cfg.OIDC.Audience
+}
@@ -0,0 +1,273 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"os"
+	"sso/internal/config"
+	"time"
+)
+
+var (
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+	ErrKeyAlgorithmMismatch = errors.New("private key does not match signing algorithm")
+	ErrDuplicateKid         = errors.New("duplicate key id")
+	ErrNoActiveKey          = errors.New("no active signing key")
+	ErrUnknownKey           = errors.New("unknown key id")
+)
+
+const defaultKid = "default"
+
+// SigningKey is a key used to sign tokens and to verify their signature.
+type SigningKey struct {
+	Kid       string
+	Method    jwt.SigningMethod
+	NotBefore time.Time
+	NotAfter  time.Time
+	// signKey is []byte for HMAC and crypto.Signer for asymmetric algorithms
+	signKey any
+	// verifyKey is []byte for HMAC and crypto.PublicKey for asymmetric algorithms
+	verifyKey any
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	Kty string
+	Use string
+	Alg string
+	Kid string
+	// RSA
+	N string
+	E string
+	// EC and OKP
+	Crv string
+	X   string
+	Y   string
+}
+
+// KeyRing holds all signing keys of the service. Only one key signs new
+// tokens at a time, retired keys keep verifying tokens they signed until
+// they expire.
+type KeyRing struct {
+	keys []*SigningKey
+	// legacy verifies tokens issued before key rotation was introduced,
+	// they have no kid and are signed with service_secret by HS256, until
+	// legacy_not_after
+	legacy *SigningKey
+}
+
+// NewKeyRing loads all keys described in config. If no keys configured
+// HS256 key based on service_secret is used.
+func NewKeyRing(cfg *config.Config) (*KeyRing, error) {
+	const op = "jwt.NewKeyRing"
+
+	keyConfigs := cfg.SigningKeys
+	if len(keyConfigs) == 0 {
+		keyConfigs = []config.SigningKeyConfig{{Kid: defaultKid, Algorithm: "HS256"}}
+	}
+
+	legacy, err := loadSigningKey(config.SigningKeyConfig{
+		Kid:       defaultKid,
+		Algorithm: "HS256",
+		NotAfter:  cfg.LegacyNotAfter,
+	}, cfg.ServiceSecret)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	ring := &KeyRing{keys: make([]*SigningKey, 0, len(keyConfigs)), legacy: legacy}
+	kids := make(map[string]struct{}, len(keyConfigs))
+	for _, keyCfg := range keyConfigs {
+		if _, ok := kids[keyCfg.Kid]; ok {
+			return nil, fmt.Errorf("%s: %s: %w", op, keyCfg.Kid, ErrDuplicateKid)
+		}
+		kids[keyCfg.Kid] = struct{}{}
+
+		key, err := loadSigningKey(keyCfg, cfg.ServiceSecret)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		ring.keys = append(ring.keys, key)
+	}
+	return ring, nil
+}
+
+// SigningKey returns key which must be used to sign tokens at the moment:
+// the one with the latest not_before among keys in their validity window.
+func (r *KeyRing) SigningKey(now time.Time) (*SigningKey, error) {
+	var active *SigningKey
+	for _, key := range r.keys {
+		if !key.isValid(now) {
+			continue
+		}
+		if active == nil || key.NotBefore.After(active.NotBefore) {
+			active = key
+		}
+	}
+	if active == nil {
+		return nil, ErrNoActiveKey
+	}
+	return active, nil
+}
+
+// VerificationKey returns key with given kid if it is not expired yet.
+// Keys with not_before in the future are returned as well, because they
+// are already published and can't have signed anything yet. Tokens without
+// kid are verified by HS256 key based on service_secret until
+// legacy_not_after, so rotation doesn't log out users holding tokens
+// issued before it.
+func (r *KeyRing) VerificationKey(kid string, now time.Time) (*SigningKey, error) {
+	if kid == "" {
+		if r.legacy.isExpired(now) {
+			return nil, ErrUnknownKey
+		}
+		return r.legacy, nil
+	}
+	for _, key := range r.keys {
+		if key.Kid == kid && !key.isExpired(now) {
+			return key, nil
+		}
+	}
+	return nil, ErrUnknownKey
+}
+
+// PublicKeys returns public parts of all not expired keys, upcoming keys
+// included, so verifiers can cache them before rotation happens.
+func (r *KeyRing) PublicKeys(now time.Time) []JWK {
+	keys := make([]JWK, 0, len(r.keys))
+	for _, key := range r.keys {
+		if key.isExpired(now) {
+			continue
+		}
+		if jwk, ok := key.JWK(); ok {
+			keys = append(keys, jwk)
+		}
+	}
+	return keys
+}
+
+// Algorithms returns distinct signing algorithms of not expired keys.
+func (r *KeyRing) Algorithms(now time.Time) []string {
+	algs := make([]string, 0, len(r.keys))
+	seen := make(map[string]struct{}, len(r.keys))
+	for _, key := range r.keys {
+		if key.isExpired(now) {
+			continue
+		}
+		if _, ok := seen[key.Method.Alg()]; ok {
+			continue
+		}
+		seen[key.Method.Alg()] = struct{}{}
+		algs = append(algs, key.Method.Alg())
+	}
+	return algs
+}
+
+func loadSigningKey(keyCfg config.SigningKeyConfig, serviceSecret string) (*SigningKey, error) {
+	method := jwt.GetSigningMethod(keyCfg.Algorithm)
+	if method == nil {
+		return nil, fmt.Errorf("%s: %w", keyCfg.Algorithm, ErrUnsupportedAlgorithm)
+	}
+
+	if _, ok := method.(*jwt.SigningMethodHMAC); ok {
+		secret := keyCfg.Secret
+		if secret == "" {
+			secret = serviceSecret
+		}
+		return &SigningKey{
+			Kid:       keyCfg.Kid,
+			Method:    method,
+			NotBefore: keyCfg.NotBefore,
+			NotAfter:  keyCfg.NotAfter,
+			signKey:   []byte(secret),
+			verifyKey: []byte(secret),
+		}, nil
+	}
+
+	pemBytes, err := os.ReadFile(keyCfg.PrivateKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var signer crypto.Signer
+	switch method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		signer, err = jwt.ParseRSAPrivateKeyFromPEM(pemBytes)
+	case *jwt.SigningMethodECDSA:
+		signer, err = jwt.ParseECPrivateKeyFromPEM(pemBytes)
+	case *jwt.SigningMethodEd25519:
+		var key crypto.PrivateKey
+		key, err = jwt.ParseEdPrivateKeyFromPEM(pemBytes)
+		if err == nil {
+			signer = key.(ed25519.PrivateKey)
+		}
+	default:
+		return nil, fmt.Errorf("%s: %w", keyCfg.Algorithm, ErrUnsupportedAlgorithm)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", keyCfg.Kid, err)
+	}
+
+	if ecMethod, ok := method.(*jwt.SigningMethodECDSA); ok {
+		if signer.(*ecdsa.PrivateKey).Curve.Params().BitSize != ecMethod.CurveBits {
+			return nil, fmt.Errorf("%s: %w", keyCfg.Kid, ErrKeyAlgorithmMismatch)
+		}
+	}
+
+	return &SigningKey{
+		Kid:       keyCfg.Kid,
+		Method:    method,
+		NotBefore: keyCfg.NotBefore,
+		NotAfter:  keyCfg.NotAfter,
+		signKey:   signer,
+		verifyKey: signer.Public(),
+	}, nil
+}
+
+func (k *SigningKey) isExpired(now time.Time) bool {
+	return !k.NotAfter.IsZero() && !now.Before(k.NotAfter)
+}
+
+func (k *SigningKey) isValid(now time.Time) bool {
+	return !now.Before(k.NotBefore) && !k.isExpired(now)
+}
+
+// VerifyKey returns key which must be used to verify token signature.
+func (k *SigningKey) VerifyKey() any {
+	return k.verifyKey
+}
+
+// JWK returns public part of the key. Symmetric keys can't be published,
+// in that case false is returned.
+func (k *SigningKey) JWK() (JWK, bool) {
+	jwk := JWK{
+		Use: "sig",
+		Alg: k.Method.Alg(),
+		Kid: k.Kid,
+	}
+	switch key := k.verifyKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = key.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(key)
+	default:
+		return JWK{}, false
+	}
+	return jwk, true
+}
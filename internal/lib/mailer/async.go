@@ -0,0 +1,111 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sso/internal/config"
+	"sync"
+	"time"
+)
+
+var (
+	ErrQueueFull = errors.New("mail queue is full")
+	ErrClosed    = errors.New("mailer is closed")
+)
+
+// sendTimeout bounds a single delivery attempt.
+const sendTimeout = 30 * time.Second
+
+// Async queues messages and delivers them in background workers, failed
+// deliveries are retried with exponential backoff.
+type Async struct {
+	mailer      Mailer
+	log         *slog.Logger
+	queue       chan Message
+	maxAttempts int
+	backoff     time.Duration
+	wg          sync.WaitGroup
+	// mu guards closed, the queue is closed under the write lock, so Send
+	// never writes to a closed channel
+	mu     sync.RWMutex
+	closed bool
+}
+
+func NewAsync(mailer Mailer, log *slog.Logger, cfg config.MailerConfig) *Async {
+	a := &Async{
+		mailer:      mailer,
+		log:         log,
+		queue:       make(chan Message, max(cfg.QueueSize, 1)),
+		maxAttempts: max(cfg.MaxAttempts, 1),
+		backoff:     cfg.RetryBackoff,
+	}
+	for i := 0; i < max(cfg.Workers, 1); i++ {
+		a.wg.Add(1)
+		go a.work()
+	}
+	return a
+}
+
+// Send enqueues message and returns immediately. Delivery errors are only
+// logged, ErrQueueFull is returned when workers can't keep up and ErrClosed
+// after Close.
+func (a *Async) Send(_ context.Context, msg Message) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.closed {
+		return ErrClosed
+	}
+	select {
+	case a.queue <- msg:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close stops accepting messages and waits until queued ones are handled.
+func (a *Async) Close() {
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.queue)
+	}
+	a.mu.Unlock()
+	a.wg.Wait()
+}
+
+func (a *Async) work() {
+	defer a.wg.Done()
+	for msg := range a.queue {
+		a.deliver(msg)
+	}
+}
+
+func (a *Async) deliver(msg Message) {
+	backoff := a.backoff
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		err := a.mailer.Send(ctx, msg)
+		cancel()
+		if err == nil {
+			return
+		}
+		if attempt >= a.maxAttempts {
+			a.log.Error("failed to send email, giving up",
+				slog.String("subject", msg.Subject),
+				slog.Int("attempts", attempt),
+				slog.String("error", err.Error()),
+			)
+			return
+		}
+		a.log.Warn("failed to send email, retrying",
+			slog.String("subject", msg.Subject),
+			slog.Int("attempt", attempt),
+			slog.String("error", err.Error()),
+		)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
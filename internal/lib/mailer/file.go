@@ -0,0 +1,61 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// File writes every message to its own .eml file in dir, development and
+// tests read emails from there instead of a mailbox.
+type File struct {
+	dir  string
+	from string
+}
+
+func NewFile(dir string, from string) *File {
+	return &File{dir: dir, from: from}
+}
+
+func (f *File) Send(_ context.Context, msg Message) error {
+	const op = "mailer.File.Send"
+
+	body, err := compose(f.from, msg)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := os.MkdirAll(f.dir, 0o750); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), messageID(f.from)[1:9])
+	// write to temp file first, so readers never see half written messages
+	tmp := filepath.Join(f.dir, "."+name)
+	if err := os.WriteFile(tmp, body, 0o640); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := os.Rename(tmp, filepath.Join(f.dir, name)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Log only logs recipients and subjects, bodies carry secret links and
+// never get to logs.
+type Log struct {
+	log *slog.Logger
+}
+
+func NewLog(log *slog.Logger) *Log {
+	return &Log{log: log}
+}
+
+func (l *Log) Send(_ context.Context, msg Message) error {
+	l.log.Info("email is not delivered, log mailer is configured",
+		slog.String("to", msg.To),
+		slog.String("subject", msg.Subject),
+	)
+	return nil
+}
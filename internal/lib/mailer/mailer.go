@@ -0,0 +1,122 @@
+// Package mailer delivers emails of the service: verification links,
+// password reset links and security notifications.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"sso/internal/config"
+	"strings"
+	"time"
+)
+
+var ErrUnknownDriver = errors.New("unknown mailer driver")
+
+// Message is an email ready to be sent.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	// HTML is optional alternative of Text
+	HTML string
+}
+
+// Mailer sends messages. Implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New returns driver chosen by cfg.Mailer.Driver wrapped into Async, so
+// callers never wait for delivery.
+func New(cfg *config.Config, log *slog.Logger) (*Async, error) {
+	var driver Mailer
+	switch cfg.Mailer.Driver {
+	case "smtp":
+		driver = NewSMTP(cfg.Mailer.SMTP, cfg.Mailer.From)
+	case "file":
+		driver = NewFile(cfg.Mailer.Dir, cfg.Mailer.From)
+	case "log", "":
+		driver = NewLog(log)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownDriver, cfg.Mailer.Driver)
+	}
+	return NewAsync(driver, log, cfg.Mailer), nil
+}
+
+// compose renders message as RFC 5322 email, multipart/alternative if it
+// has HTML part.
+func compose(from string, msg Message) ([]byte, error) {
+	var buf bytes.Buffer
+	header := textproto.MIMEHeader{}
+	header.Set("From", from)
+	header.Set("To", msg.To)
+	header.Set("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header.Set("Date", time.Now().Format(time.RFC1123Z))
+	header.Set("Message-ID", messageID(from))
+	header.Set("MIME-Version", "1.0")
+
+	if msg.HTML == "" {
+		header.Set("Content-Type", "text/plain; charset=utf-8")
+		header.Set("Content-Transfer-Encoding", "8bit")
+		writeHeader(&buf, header)
+		buf.WriteString(msg.Text)
+		return buf.Bytes(), nil
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"8bit"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	header.Set("Content-Type", "multipart/alternative; boundary="+parts.Boundary())
+	writeHeader(&buf, header)
+	buf.Write(body.Bytes())
+	return buf.Bytes(), nil
+}
+
+func writeHeader(buf *bytes.Buffer, header textproto.MIMEHeader) {
+	for _, name := range []string{
+		"From", "To", "Subject", "Date", "Message-ID", "MIME-Version", "Content-Type", "Content-Transfer-Encoding",
+	} {
+		if value := header.Get(name); value != "" {
+			fmt.Fprintf(buf, "%s: %s\r\n", name, value)
+		}
+	}
+	buf.WriteString("\r\n")
+}
+
+func messageID(from string) string {
+	random := make([]byte, 16)
+	_, _ = rand.Read(random)
+	domain := "localhost"
+	if address, err := mail.ParseAddress(from); err == nil {
+		if _, host, ok := strings.Cut(address.Address, "@"); ok {
+			domain = host
+		}
+	}
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(random), domain)
+}
@@ -0,0 +1,52 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"sso/internal/config"
+	"strconv"
+)
+
+// SMTP sends messages through SMTP server. STARTTLS is used when the server
+// offers it, credentials are only sent if username is set, so local
+// stand-ins like MailHog work without any.
+type SMTP struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTP(cfg config.SMTPConfig, from string) *SMTP {
+	s := &SMTP{
+		addr: net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		from: from,
+	}
+	if cfg.Username != "" {
+		s.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	return s
+}
+
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	const op = "mailer.SMTP.Send"
+
+	body, err := compose(s.from, msg)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	sender, err := mail.ParseAddress(s.from)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	// net/smtp has no context support, at least don't start a canceled send
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := smtp.SendMail(s.addr, s.auth, sender.Address, []string{msg.To}, body); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
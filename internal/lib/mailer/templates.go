@@ -0,0 +1,132 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"path"
+	"strings"
+	"text/template"
+)
+
+// Names of templates, every locale has <name>.txt defining "subject" and
+// "text" and <name>.html with HTML body.
+const (
+	TemplateVerification  = "verification"
+	TemplatePasswordReset = "password_reset"
+	TemplateNewLogin      = "new_login"
+	TemplateEmailChange   = "email_change"
+	TemplateEmailChanged  = "email_changed"
+)
+
+var ErrUnknownTemplate = errors.New("unknown email template")
+
+// LinkData is data of emails carrying a single-use link.
+type LinkData struct {
+	Email    string
+	Link     string
+	ValidFor string
+}
+
+// NewLoginData is data of new sign-in notifications.
+type NewLoginData struct {
+	Email     string
+	Time      string
+	IP        string
+	UserAgent string
+}
+
+// EmailChangedData is data of the notice sent to the old email.
+type EmailChangedData struct {
+	Email    string
+	NewEmail string
+}
+
+//go:embed templates
+var templateFS embed.FS
+
+type emailTemplate struct {
+	text *template.Template
+	html *htmltemplate.Template
+}
+
+// Templates renders localized emails.
+type Templates struct {
+	defaultLocale string
+	// locale -> template name -> template
+	locales map[string]map[string]emailTemplate
+}
+
+// MustLoadTemplates parses embedded templates, defaultLocale is used for
+// clients whose languages aren't supported.
+func MustLoadTemplates(defaultLocale string) *Templates {
+	t := &Templates{defaultLocale: defaultLocale, locales: make(map[string]map[string]emailTemplate)}
+	locales, err := templateFS.ReadDir("templates")
+	if err != nil {
+		panic("failed to read email templates: " + err.Error())
+	}
+	for _, locale := range locales {
+		dir := path.Join("templates", locale.Name())
+		files, err := templateFS.ReadDir(dir)
+		if err != nil {
+			panic("failed to read email templates: " + err.Error())
+		}
+		t.locales[locale.Name()] = make(map[string]emailTemplate)
+		for _, file := range files {
+			name, ok := strings.CutSuffix(file.Name(), ".txt")
+			if !ok {
+				continue
+			}
+			// every .txt file defines its own "subject" and "text", so
+			// each one is parsed into a separate set
+			t.locales[locale.Name()][name] = emailTemplate{
+				text: template.Must(template.ParseFS(templateFS, path.Join(dir, name+".txt"))),
+				html: htmltemplate.Must(htmltemplate.ParseFS(templateFS, path.Join(dir, name+".html"))),
+			}
+		}
+	}
+	if _, ok := t.locales[defaultLocale]; !ok {
+		panic("no email templates for default locale " + defaultLocale)
+	}
+	return t
+}
+
+// Render renders template in the locale best matching Accept-Language
+// value, recipient of the returned message is left empty.
+func (t *Templates) Render(name string, acceptLanguage string, data any) (Message, error) {
+	tmpl, ok := t.locales[t.Locale(acceptLanguage)][name]
+	if !ok {
+		return Message{}, fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, fmt.Errorf("render %s: %w", name, err)
+	}
+	if err := tmpl.text.ExecuteTemplate(&text, "text", data); err != nil {
+		return Message{}, fmt.Errorf("render %s: %w", name, err)
+	}
+	if err := tmpl.html.Execute(&html, data); err != nil {
+		return Message{}, fmt.Errorf("render %s: %w", name, err)
+	}
+	return Message{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimLeft(text.String(), "\n"),
+		HTML:    html.String(),
+	}, nil
+}
+
+// Locale picks the first supported language of Accept-Language value,
+// quality values are ignored as clients list languages by preference.
+func (t *Templates) Locale(acceptLanguage string) string {
+	for _, tag := range strings.Split(acceptLanguage, ",") {
+		tag, _, _ = strings.Cut(strings.TrimSpace(tag), ";")
+		language, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if _, ok := t.locales[language]; ok {
+			return language
+		}
+	}
+	return t.defaultLocale
+}
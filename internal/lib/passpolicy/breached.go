@@ -0,0 +1,71 @@
+package passpolicy
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// prefixLen is length of hash prefix ranges are keyed by, the same as in
+// haveibeenpwned range API
+const prefixLen = 5
+
+// BreachedList keeps SHA-1 hashes of breached passwords split into ranges
+// by hash prefix (k-anonymity). Lookup takes the whole range of a prefix
+// and compares suffixes locally, so the list could be served by another
+// service without it ever seeing full hashes.
+type BreachedList struct {
+	// sorted suffixes of hashes keyed by prefix
+	ranges map[string][]string
+}
+
+// LoadBreachedList reads file of upper case hex SHA-1 hashes, one per
+// line, optionally followed by ":count" as in haveibeenpwned downloads.
+// Empty lines and lines starting with # are skipped.
+func LoadBreachedList(path string) (*BreachedList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("LoadBreachedList: %w", err)
+	}
+	defer f.Close()
+
+	ranges := make(map[string][]string)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		hash, _, _ := strings.Cut(line, ":")
+		hash = strings.ToUpper(hash)
+		if len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("LoadBreachedList: %s:%d: not a SHA-1 hash", path, n)
+		}
+		ranges[hash[:prefixLen]] = append(ranges[hash[:prefixLen]], hash[prefixLen:])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("LoadBreachedList: %w", err)
+	}
+	for _, suffixes := range ranges {
+		sort.Strings(suffixes)
+	}
+	return &BreachedList{ranges: ranges}, nil
+}
+
+// Range returns sorted suffixes of breached hashes starting with prefix
+func (l *BreachedList) Range(prefix string) []string {
+	return l.ranges[strings.ToUpper(prefix)]
+}
+
+// Contains reports whether password is in the list
+func (l *BreachedList) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	suffixes := l.Range(hash[:prefixLen])
+	i := sort.SearchStrings(suffixes, hash[prefixLen:])
+	return i < len(suffixes) && suffixes[i] == hash[prefixLen:]
+}
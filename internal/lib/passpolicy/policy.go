@@ -0,0 +1,125 @@
+package passpolicy
+
+import (
+	"fmt"
+	"sso/internal/config"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// bcryptMaxBytes is length of passwords bcrypt takes into account, the
+// rest is silently ignored
+const bcryptMaxBytes = 72
+
+// rules of the policy, clients may rely on them to show hints
+const (
+	RuleMinLength     = "min_length"
+	RuleMaxLength     = "max_length"
+	RuleLower         = "lower"
+	RuleUpper         = "upper"
+	RuleDigit         = "digit"
+	RuleSpecial       = "special"
+	RuleContainsEmail = "contains_email"
+	RuleBreached      = "breached"
+)
+
+// minEmailLocalLen is the shortest local part of email checked by
+// contains_email rule, shorter ones would reject too many passwords
+const minEmailLocalLen = 3
+
+// Violation is a rule the password breaks
+type Violation struct {
+	Rule        string
+	Description string
+}
+
+// Policy checks new passwords of users
+type Policy struct {
+	cfg      config.PasswordPolicyConfig
+	breached *BreachedList
+}
+
+// New returns policy described in cfg, it loads breached passwords if
+// the file is configured.
+func New(cfg config.PasswordPolicyConfig) (*Policy, error) {
+	if cfg.MaxBytes <= 0 || cfg.MaxBytes > bcryptMaxBytes {
+		cfg.MaxBytes = bcryptMaxBytes
+	}
+	policy := &Policy{cfg: cfg}
+	if cfg.BreachedFile != "" {
+		breached, err := LoadBreachedList(cfg.BreachedFile)
+		if err != nil {
+			return nil, err
+		}
+		policy.breached = breached
+	}
+	return policy, nil
+}
+
+// MustNew is like New but panics if breached passwords can't be loaded
+func MustNew(cfg config.PasswordPolicyConfig) *Policy {
+	policy, err := New(cfg)
+	if err != nil {
+		panic(err)
+	}
+	return policy
+}
+
+// Check returns all rules the password of the account with email breaks,
+// empty email skips contains_email rule.
+func (p *Policy) Check(password string, email string) []Violation {
+	var violations []Violation
+	if utf8.RuneCountInString(password) < p.cfg.MinLength {
+		violations = append(violations, Violation{
+			Rule:        RuleMinLength,
+			Description: fmt.Sprintf("must be at least %d characters long", p.cfg.MinLength),
+		})
+	}
+	if len(password) > p.cfg.MaxBytes {
+		violations = append(violations, Violation{
+			Rule:        RuleMaxLength,
+			Description: fmt.Sprintf("must be at most %d bytes long", p.cfg.MaxBytes),
+		})
+	}
+
+	var lower, upper, digit, special bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			special = true
+		}
+	}
+	if p.cfg.RequireLower && !lower {
+		violations = append(violations, Violation{Rule: RuleLower, Description: "must contain a lower case letter"})
+	}
+	if p.cfg.RequireUpper && !upper {
+		violations = append(violations, Violation{Rule: RuleUpper, Description: "must contain an upper case letter"})
+	}
+	if p.cfg.RequireDigit && !digit {
+		violations = append(violations, Violation{Rule: RuleDigit, Description: "must contain a digit"})
+	}
+	if p.cfg.RequireSpecial && !special {
+		violations = append(violations, Violation{Rule: RuleSpecial, Description: "must contain a special character"})
+	}
+
+	if p.cfg.RejectEmail {
+		local, _, _ := strings.Cut(email, "@")
+		if len(local) >= minEmailLocalLen && strings.Contains(strings.ToLower(password), strings.ToLower(local)) {
+			violations = append(violations, Violation{Rule: RuleContainsEmail, Description: "must not contain the email"})
+		}
+	}
+	if p.breached != nil && p.breached.Contains(password) {
+		violations = append(violations, Violation{
+			Rule:        RuleBreached,
+			Description: "is known from data breaches, choose another one",
+		})
+	}
+	return violations
+}
@@ -0,0 +1,173 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"log/slog"
+	"math"
+	"sso/internal/config"
+	"sso/internal/lib/gateway"
+	jwtlib "sso/internal/lib/jwt"
+	"sso/storage"
+	"strconv"
+	"time"
+)
+
+const (
+	KeyIP       = "ip"
+	KeyClientID = "client_id"
+	KeyUserID   = "user_id"
+
+	// metadata clients identify themselves with, through the gateway it is
+	// passed as "Grpc-Metadata-Client-Id" header
+	clientIDMetadata = "client-id"
+
+	memoryBucketsSize = 100000
+)
+
+// Limiter limits rpc calls per method. Buckets are kept in redis, so all
+// instances share limits, memory buckets are used while redis fails.
+type Limiter struct {
+	log     *slog.Logger
+	rules   map[string]config.RateLimitRule
+	store   storage.RateLimitStorage
+	memory  *MemoryBuckets
+	keyfunc jwt.Keyfunc
+	proxies gateway.TrustedProxies
+}
+
+// New returns limiter of methods configured in cfg, nil store limits every
+// instance on its own.
+func New(
+	log *slog.Logger,
+	cfg config.RateLimitConfig,
+	store storage.RateLimitStorage,
+	keyfunc jwt.Keyfunc,
+	proxies gateway.TrustedProxies,
+) *Limiter {
+	rules := make(map[string]config.RateLimitRule, len(cfg.Methods))
+	for method, rule := range cfg.Methods {
+		if rule.Requests <= 0 || rule.Per <= 0 {
+			log.Warn("rate limit rule ignored, requests and per must be positive",
+				slog.String("method", method))
+			continue
+		}
+		if rule.Burst <= 0 {
+			rule.Burst = rule.Requests
+		}
+		rules[method] = rule
+	}
+	if !cfg.Enabled {
+		rules = nil
+	}
+	if cfg.Backend == "memory" {
+		store = nil
+	}
+	return &Limiter{
+		log:     log,
+		rules:   rules,
+		store:   store,
+		memory:  NewMemoryBuckets(memoryBucketsSize),
+		keyfunc: keyfunc,
+		proxies: proxies,
+	}
+}
+
+// UnaryServerInterceptor rejects calls above limits of their method with
+// ResourceExhausted, time to wait is sent both in retry-after header
+// (seconds) and RetryInfo details.
+func (l *Limiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		rule, ok := l.rules[info.FullMethod]
+		if !ok {
+			return handler(ctx, req)
+		}
+		allowed, retryAfter := l.take(ctx, info.FullMethod+":"+l.key(ctx, req, rule), rule)
+		if !allowed {
+			return nil, exhausted(ctx, retryAfter)
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (l *Limiter) take(ctx context.Context, key string, rule config.RateLimitRule) (bool, time.Duration) {
+	rate := float64(rule.Requests) / rule.Per.Seconds()
+	if l.store != nil {
+		_, allowed, retryAfter, err := l.store.TakeToken(ctx, key, rate, rule.Burst)
+		if err == nil {
+			return allowed, retryAfter
+		}
+		l.log.Warn("rate limit storage failed, falling back to memory", slog.String("error", err.Error()))
+	}
+	allowed, retryAfter := l.memory.Take(key, rate, rule.Burst, time.Now())
+	return allowed, retryAfter
+}
+
+// key identifies the caller, calls without client id or user are limited
+// by ip.
+func (l *Limiter) key(ctx context.Context, req any, rule config.RateLimitRule) string {
+	switch rule.Key {
+	case KeyClientID:
+		md, _ := metadata.FromIncomingContext(ctx)
+		if values := md.Get(clientIDMetadata); len(values) > 0 && values[0] != "" {
+			return KeyClientID + ":" + values[0]
+		}
+	case KeyUserID:
+		if userID, ok := l.userID(ctx, req); ok {
+			return KeyUserID + ":" + strconv.FormatInt(userID, 10)
+		}
+	}
+	return KeyIP + ":" + l.proxies.ClientIP(ctx)
+}
+
+// userID returns user of the token passed in the request or in
+// "Authorization" header. Only the signature is verified, revoked tokens
+// are rejected later by the service anyway.
+func (l *Limiter) userID(ctx context.Context, req any) (int64, bool) {
+	var token string
+	if withToken, ok := req.(interface{ GetToken() string }); ok {
+		token = withToken.GetToken()
+	}
+	token = gateway.BearerToken(ctx, token)
+	if token == "" || l.keyfunc == nil {
+		return 0, false
+	}
+	parsed, err := jwt.Parse(token, l.keyfunc)
+	if err != nil {
+		return 0, false
+	}
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0, false
+	}
+	userID, err := jwtlib.UserID(claims)
+	if err != nil {
+		return 0, false
+	}
+	return userID, true
+}
+
+func exhausted(ctx context.Context, retryAfter time.Duration) error {
+	seconds := int64(math.Ceil(retryAfter.Seconds()))
+	_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.FormatInt(seconds, 10)))
+
+	st := status.New(codes.ResourceExhausted, "too many requests, try again later")
+	detailed, err := st.WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(retryAfter),
+	})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
@@ -0,0 +1,56 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// MemoryBuckets keeps token buckets of this instance only. It limits calls
+// when no redis is configured and while redis is unavailable.
+type MemoryBuckets struct {
+	mu      sync.Mutex
+	maxSize int
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// NewMemoryBuckets returns buckets, when maxSize keys are reached all
+// buckets are dropped, so memory stays bounded under spoofed keys.
+func NewMemoryBuckets(maxSize int) *MemoryBuckets {
+	return &MemoryBuckets{
+		maxSize: maxSize,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Take takes a token from the bucket of key refilled with rate tokens per
+// second up to burst, if the bucket is empty returns false and time until
+// the next token.
+func (m *MemoryBuckets) Take(key string, rate float64, burst int, now time.Time) (bool, time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, found := m.buckets[key]
+	if !found {
+		if m.maxSize > 0 && len(m.buckets) >= m.maxSize {
+			m.buckets = make(map[string]*bucket)
+		}
+		b = &bucket{tokens: float64(burst), updatedAt: now}
+		m.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.updatedAt); elapsed > 0 {
+		b.tokens = math.Min(float64(burst), b.tokens+elapsed.Seconds()*rate)
+	}
+	b.updatedAt = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration(math.Ceil((1 - b.tokens) / rate * float64(time.Second)))
+	return false, wait
+}
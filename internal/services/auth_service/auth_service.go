@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
@@ -16,7 +17,11 @@ import (
 	"log/slog"
 	"sso/internal/config"
 	"sso/internal/domain/models"
+	"sso/internal/lib/authz"
+	"sso/internal/lib/gateway"
 	jwtlib "sso/internal/lib/jwt"
+	"sso/internal/lib/mailer"
+	"sso/internal/lib/passpolicy"
 	"sso/storage"
 	"time"
 )
@@ -26,25 +31,82 @@ type Auth struct {
 	// data layer
 	userStorage storage.UserStorage
 	// data layer
+	appStorage storage.AppStorage
+	// data layer
 	tokenStorage storage.TokenStorage
-	cfg          *config.Config
+	// data layer
+	sessionStorage storage.SessionStorage
+	// data layer
+	authCodeStorage storage.AuthCodeStorage
+	// data layer
+	roleStorage storage.RoleStorage
+	// data layer
+	tupleStorage storage.TupleStorage
+	// data layer
+	orgStorage storage.OrganizationStorage
+	// data layer
+	groupStorage storage.GroupStorage
+	// data layer
+	attemptStorage storage.LoginAttemptStorage
+	// outbound email, sends in background
+	mailer    mailer.Mailer
+	templates *mailer.Templates
+	// rules new passwords must satisfy
+	passwordPolicy *passpolicy.Policy
+	// results of permission checks, invalidated on role changes
+	decisions *authz.DecisionCache
+	// proxies allowed to pass address of the client
+	proxies gateway.TrustedProxies
+	keyRing *jwtlib.KeyRing
+	cfg     *config.Config
+}
+
+// Storages are data layer dependencies of Auth, one backend usually
+// implements several of them
+type Storages struct {
+	User     storage.UserStorage
+	App      storage.AppStorage
+	Token    storage.TokenStorage
+	Session  storage.SessionStorage
+	AuthCode storage.AuthCodeStorage
+	Role     storage.RoleStorage
+	Tuple    storage.TupleStorage
+	Org      storage.OrganizationStorage
+	Group    storage.GroupStorage
+	Attempt  storage.LoginAttemptStorage
 }
 
 // New returns a new instance of Auth service
 func New(
 	log *slog.Logger,
 	// data layer
-	userStorage storage.UserStorage,
-	// data layer
-	tokenStorage storage.TokenStorage,
+	storages Storages,
+	// outbound email
+	mail mailer.Mailer,
+	// keys to sign and verify tokens
+	keyRing *jwtlib.KeyRing,
 
 	cfg *config.Config,
 ) *Auth {
 	return &Auth{
-		log:          log,
-		userStorage:  userStorage,
-		tokenStorage: tokenStorage,
-		cfg:          cfg,
+		log:             log,
+		userStorage:     storages.User,
+		appStorage:      storages.App,
+		tokenStorage:    storages.Token,
+		sessionStorage:  storages.Session,
+		authCodeStorage: storages.AuthCode,
+		roleStorage:     storages.Role,
+		tupleStorage:    storages.Tuple,
+		orgStorage:      storages.Org,
+		groupStorage:    storages.Group,
+		attemptStorage:  storages.Attempt,
+		mailer:          mail,
+		templates:       mailer.MustLoadTemplates(cfg.Mailer.DefaultLocale),
+		passwordPolicy:  passpolicy.MustNew(cfg.PasswordPolicy),
+		decisions:       authz.NewDecisionCache(cfg.Authz.DecisionCacheTtl, cfg.Authz.DecisionCacheSize),
+		proxies:         gateway.MustTrustedProxies(cfg.TrustedProxies),
+		keyRing:         keyRing,
+		cfg:             cfg,
 	}
 }
 
@@ -54,11 +116,16 @@ const (
 
 var tracer = otel.Tracer("sso service")
 
+// Login checks credentials and issues tokens. If appID is set tokens are
+// issued for that app with requested scopes, otherwise for the service itself.
 func (a *Auth) Login(
 	ctx context.Context,
 	email string,
 	password string,
-) (string, string, error) {
+	appID int,
+	scopes []string,
+	tenantID int64,
+) (accessToken string, refreshToken string, idToken string, err error) {
 	ctx, span := tracer.Start(ctx, "service layer: login",
 		trace.WithAttributes(attribute.String("handler", "login")))
 	defer span.End()
@@ -66,10 +133,52 @@ func (a *Auth) Login(
 	md, _ := metadata.FromIncomingContext(ctx)
 	a.log.Info("time: %v, userId: %v", md.Get("timestamp"), md.Get("user-id"))
 
-	ctx, usrWithTokens, err := a.generateRefreshAccessToken(ctx, email)
+	ctx, params, err := a.appTokenParams(ctx, appID, scopes)
+	if err != nil {
+		a.log.Info("app token params rejected", slog.String("error", err.Error()))
+		return "", "", "", err
+	}
+	params.TenantID = tenantID
+	ctx, usrWithTokens, params, err := a.login(ctx, email, password, params)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	idToken, err = a.newIDToken(*usrWithTokens.user, params)
 	if err != nil {
+		a.log.Error("failed to generate id token", slog.String("error", err.Error()))
+		return "", "", "", fmt.Errorf("generation token failed: %w", err)
+	}
+
+	return usrWithTokens.accessToken, usrWithTokens.refreshToken, idToken, nil
+}
+
+// login checks credentials of the user and starts a new session, returned
+// params hold id of the session.
+func (a *Auth) login(
+	ctx context.Context,
+	email string,
+	password string,
+	params jwtlib.TokenParams,
+) (context.Context, userWithTokens, jwtlib.TokenParams, error) {
+	// every login starts a new session, its id is shared by all refresh
+	// tokens issued by rotation (token family)
+	params.SessionID = uuid.NewString()
+	// locked logins are rejected before the password is checked, so
+	// guessing gets no answers until the lock expires
+	accountKey, ipKey := a.attemptKeys(ctx, params.TenantID, email)
+	ctx, err := a.checkLockout(ctx, accountKey, ipKey)
+	if err != nil {
+		a.securityEvent(ctx, "locked_login", slog.String("email", email))
+		return ctx, userWithTokens{}, params, err
+	}
+	ctx, usrWithTokens, err := a.generateRefreshAccessToken(ctx, email, params)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCredentials) {
+			ctx = a.registerFailure(ctx, accountKey, ipKey)
+		}
 		a.log.Error("Generation token failed:", err)
-		return "", "", fmt.Errorf(
+		return ctx, userWithTokens{}, params, fmt.Errorf(
 			"generation token failed: %w", err,
 		)
 	}
@@ -78,23 +187,47 @@ func (a *Auth) Login(
 		usrWithTokens.user.PassHash, []byte(password),
 	); err != nil {
 		a.log.Info("invalid credentials")
-		return "", "", fmt.Errorf(
+		ctx = a.registerFailure(ctx, accountKey, ipKey)
+		return ctx, userWithTokens{}, params, fmt.Errorf(
 			"invalid credentials: %w", ErrInvalidCredentials,
 		)
 	}
+	ctx = a.resetFailures(ctx, accountKey)
+	// checked after the password, so status of accounts isn't disclosed
+	// to whoever doesn't know it
+	if usrWithTokens.user.Disabled {
+		a.log.Info("user is disabled")
+		a.securityEvent(ctx, "disabled_user_login", slog.Int64("user_id", usrWithTokens.user.ID))
+		return ctx, userWithTokens{}, params, ErrUserDisabled
+	}
+	if a.cfg.EmailVerification.RequireVerified && !usrWithTokens.user.EmailVerified {
+		a.log.Info("email is not verified")
+		return ctx, userWithTokens{}, params, ErrEmailNotVerified
+	}
 
-	return usrWithTokens.accessToken, usrWithTokens.refreshToken, nil
+	ctx, err = a.startSession(ctx, params.SessionID, usrWithTokens.user.ID)
+	if err != nil {
+		a.log.Error("failed to save session", slog.String("error", err.Error()))
+		return ctx, userWithTokens{}, params, fmt.Errorf("session creation failed: %w", err)
+	}
+	ctx = a.recordLogin(ctx, usrWithTokens.user.ID)
+	if a.cfg.Mailer.NotifyNewLogin {
+		a.notifyNewLogin(ctx, *usrWithTokens.user)
+	}
+	return ctx, usrWithTokens, params, nil
 }
 
+// Refresh rotates tokens of the session. Tokens stay issued for the same
+// app, requested scopes may only narrow the scopes granted at login.
 func (a *Auth) Refresh(
 	ctx context.Context,
 	token string,
+	appID int,
+	scopes []string,
 ) (string, string, error) {
 	ctx, span := tracer.Start(ctx, "service layer: refresh",
 		trace.WithAttributes(attribute.String("handler", "refresh")))
 	defer span.End()
-	md, _ := metadata.FromIncomingContext(ctx)
-	a.log.Info("time: %v, userId: %v", md.Get("timestamp"), md.Get("user-id"))
 	log := a.log.With(
 		slog.String("info", "SERVICE LAYER: auth_service.Refresh"),
 		slog.String("trace-id", "trace-id from opentelemetry"),
@@ -103,31 +236,76 @@ func (a *Auth) Refresh(
 	log.Info("starting validate token")
 	ctx, claims, err := a.validateToken(ctx, token)
 	if err != nil {
+		log.Info("failed validate token", slog.String("error", err.Error()))
 		return "", "", ErrTokenRevoked
 	}
-	ttl := time.Duration(claims["exp"].(float64)-float64(time.Now().Unix())) * time.Second
-	if err != nil {
-		log.Info("failed validate token: ", err.Error())
-		return "", "", err
-	}
+	ttl := time.Until(claimsExpiresAt(claims))
 	log.Info("validate token successfully")
 	if claims["token_type"].(string) == "access" {
 		return "", "", ErrTokenWrongType
 	}
-	userID := int(claims["uid"].(float64))
-	ctx, usrWithTokens, err := a.generateRefreshAccessToken(ctx, userID)
+	userID := int(claimsUserID(claims))
+	params, err := refreshTokenParams(claims, appID, scopes)
 	if err != nil {
-		a.log.Error("failed to generate tokens", err.Error())
+		log.Info("refresh params rejected", slog.String("error", err.Error()))
 		return "", "", err
 	}
-	a.log.Info("saving refresh token to redis")
-	ctx, err = a.tokenStorage.SaveToken(ctx, token, ttl)
+
+	// every refresh token can be used only once, second use means
+	// the token has been stolen, so the whole family (session) is revoked
+	sessionID, _ := claims["sid"].(string)
+	tokenID, _ := claims["jti"].(string)
+	if sessionID == "" || tokenID == "" {
+		// token issued before rotation was introduced, start a new session
+		sessionID = uuid.NewString()
+		ctx, err = a.tokenStorage.SaveToken(ctx, token, ttl)
+		if err != nil {
+			log.Error("failed to save token", slog.String("error", err.Error()))
+			return "", "", err
+		}
+		ctx, err = a.startSession(ctx, sessionID, int64(userID))
+		if err != nil {
+			log.Error("failed to save session", slog.String("error", err.Error()))
+			return "", "", err
+		}
+	} else {
+		var firstUse bool
+		ctx, firstUse, err = a.tokenStorage.MarkTokenUsed(ctx, tokenID, ttl)
+		if err != nil {
+			log.Error("failed to mark token used", slog.String("error", err.Error()))
+			return "", "", err
+		}
+		if !firstUse {
+			ctx, err = a.revokeSession(ctx, sessionID, int64(userID))
+			if err != nil {
+				log.Error("failed to revoke token family", slog.String("error", err.Error()))
+				return "", "", err
+			}
+			a.securityEvent(ctx, "refresh_token_reuse",
+				slog.Int("user_id", userID),
+				slog.String("family_id", sessionID),
+				slog.String("token_id", tokenID),
+			)
+			return "", "", ErrTokenReused
+		}
+	}
+
+	params.SessionID = sessionID
+	ctx, usrWithTokens, err := a.generateRefreshAccessToken(ctx, userID, params)
+	if err != nil {
+		log.Error("failed to generate tokens", slog.String("error", err.Error()))
+		return "", "", err
+	}
+	if usrWithTokens.user.Disabled {
+		log.Info("user is disabled")
+		return "", "", ErrUserDisabled
+	}
+	ctx, err = a.touchSession(ctx, sessionID, int64(userID))
 	if err != nil {
-		a.log.Error("failed to save token", err.Error())
+		log.Error("failed to update session", slog.String("error", err.Error()))
 		return "", "", err
 	}
-	fmt.Println("888888888")
-	a.log.Info(" token saved to redis successfully")
+	log.Info("tokens rotated successfully")
 	return usrWithTokens.accessToken, usrWithTokens.refreshToken, nil
 }
 
@@ -135,6 +313,7 @@ func (a *Auth) Register(
 	ctx context.Context,
 	email string,
 	password string,
+	tenantID int64,
 ) (int64, error) {
 
 	const op = "SERVICE LAYER: auth_service.RegisterNewUser"
@@ -149,6 +328,10 @@ func (a *Auth) Register(
 	)
 
 	log.Info("registering user")
+	if err := a.checkPassword(password, email); err != nil {
+		log.Info("password rejected by policy")
+		return 0, err
+	}
 	passHash, err := bcrypt.GenerateFromPassword(
 		[]byte(password), bcrypt.DefaultCost,
 	)
@@ -156,18 +339,26 @@ func (a *Auth) Register(
 		log.Error("failed to generate password hash", err.Error())
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
-	ctx, id, err := a.userStorage.SaveUser(ctx, email, passHash)
+	if tenantID == 0 {
+		tenantID = models.DefaultTenantID
+	}
+	ctx, id, err := a.userStorage.SaveUser(ctx, tenantID, email, passHash)
 	if err != nil {
 		log.Error("failed to save user", err.Error())
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 	log.Info("user registrated")
+	// account exists already, user may ask to resend the link if this fails
+	if err := a.sendVerification(ctx, models.User{ID: id, TenantID: tenantID, Email: email}); err != nil {
+		log.Error("failed to send verification", slog.String("error", err.Error()))
+	}
 	return id, nil
 }
 
 func (a *Auth) IsAdmin(
 	ctx context.Context,
 	userID int,
+	tenantID int64,
 ) (success bool, err error) {
 
 	const op = "SERVICE LAYER: auth_service.IsAdmin"
@@ -178,7 +369,10 @@ func (a *Auth) IsAdmin(
 	)
 
 	log.Info("getting user from database")
-	ctx, user, err := a.userStorage.GetUser(ctx, userID)
+	if tenantID == 0 {
+		tenantID = models.DefaultTenantID
+	}
+	ctx, user, err := a.userStorage.GetUser(ctx, tenantID, userID)
 	if err != nil {
 		log.Error("failed to extract user", err.Error())
 		return false, fmt.Errorf("%s: %w", op, err)
@@ -204,7 +398,7 @@ func (a *Auth) Logout(
 		log.Info("failed validate token: ", err.Error())
 		return false, err
 	}
-	ttl := time.Duration(claims["exp"].(float64)-float64(time.Now().Unix())) * time.Second
+	ttl := time.Until(claimsExpiresAt(claims))
 
 	log.Info("validate token successfully")
 	log.Info("saving token to redis")
@@ -215,12 +409,23 @@ func (a *Auth) Logout(
 		return false, err
 	}
 	log.Info("token saved to redis successfully")
+
+	// tokens of the same session issued earlier or later must stop working too
+	if sessionID, ok := claims["sid"].(string); ok {
+		ctx, err = a.revokeSession(ctx, sessionID, claimsUserID(claims))
+		if err != nil {
+			log.Error("failed to revoke session", slog.String("error", err.Error()))
+			return false, err
+		}
+	}
 	return true, nil
 }
 
+// Validate checks the token, if audience is set the token must be issued for it.
 func (a *Auth) Validate(
 	ctx context.Context,
 	token string,
+	audience string,
 ) (success bool, err error) {
 
 	log := a.log.With(
@@ -229,20 +434,47 @@ func (a *Auth) Validate(
 		slog.String("user-id", "user-id from opentelemetry extracted from jwt"),
 	)
 	log.Info("starting validate token")
-	ctx, _, err = a.validateToken(ctx, token)
+	ctx, claims, err := a.validateToken(ctx, token)
 	if err != nil {
 		log.Info("failed validate token: ", err.Error())
 		return false, err
 	}
+	if err := checkAudience(claims, audience); err != nil {
+		log.Info("token audience mismatch", slog.String("audience", audience))
+		return false, err
+	}
 	log.Info("validate token successfully")
 	return true, nil
 }
 
+// Jwks returns public keys which can be used to verify tokens offline.
+// Symmetric keys are never published, so HS256 keys are skipped.
+func (a *Auth) Jwks(ctx context.Context) ([]jwtlib.JWK, error) {
+	_, span := tracer.Start(ctx, "service layer: jwks",
+		trace.WithAttributes(attribute.String("handler", "jwks")))
+	defer span.End()
+
+	return a.keyRing.PublicKeys(time.Now()), nil
+}
+
+// Keyfunc picks verification key by kid, so tokens signed before rotation
+// stay valid. It is passed to jwt.Parse.
+func (a *Auth) Keyfunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, err := a.keyRing.VerificationKey(kid, time.Now())
+	if err != nil {
+		return nil, ErrTokenUnknownKey
+	}
+	// never let token choose algorithm for the key
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, ErrTokenUnknownKey
+	}
+	return key.VerifyKey(), nil
+}
+
 func (a *Auth) validateToken(ctx context.Context, token string) (context.Context, jwt.MapClaims, error) {
 
-	tokenParsed, err := jwt.Parse(token, func(token *jwt.Token) (any, error) {
-		return []byte(a.cfg.ServiceSecret), nil
-	})
+	tokenParsed, err := jwt.Parse(token, a.Keyfunc)
 	if err != nil {
 		return ctx, jwt.MapClaims{}, err
 	}
@@ -250,17 +482,79 @@ func (a *Auth) validateToken(ctx context.Context, token string) (context.Context
 	if !ok {
 		return ctx, jwt.MapClaims{}, ErrTokenParsing
 	}
-	// check ttl
-	ttl := time.Duration(claims["exp"].(float64)-float64(time.Now().Unix())) * time.Second
-	if ttl < 0 {
+	// check ttl, tokens without expiration are never accepted
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return ctx, jwt.MapClaims{}, ErrTokenParsing
+	}
+	if int64(exp) < time.Now().Unix() {
 		return ctx, jwt.MapClaims{}, ErrTokenTtlExpired
 	}
-	// check type of token
+	// check type of token, ID tokens have no type and are never accepted
 	if (claims["token_type"] != "refresh") && claims["token_type"] != "access" {
 		return ctx, jwt.MapClaims{}, ErrTokenWrongType
 	}
-	// check if token exists in redis
+	// tokens issued before "iss" claim was introduced have no issuer
+	if issuer, ok := claims["iss"]; ok && issuer != a.cfg.OIDC.Issuer {
+		return ctx, jwt.MapClaims{}, ErrTokenWrongIssuer
+	}
+	if jwtlib.IsClientToken(claims) {
+		// app tokens have no user and session to be revoked
+		return a.checkTokenBlacklisted(ctx, token, claims)
+	}
+	if _, err := jwtlib.UserID(claims); err != nil {
+		return ctx, jwt.MapClaims{}, ErrTokenParsing
+	}
+	// check if session (token family) has been revoked: by user or because of refresh token reuse
+	if sessionID, ok := claims["sid"].(string); ok {
+		var revoked bool
+		ctx, revoked, err = a.tokenStorage.CheckFamilyRevoked(ctx, sessionID)
+		if err != nil {
+			return ctx, jwt.MapClaims{}, fmt.Errorf("validateToken: %w", err)
+		}
+		if revoked {
+			return ctx, jwt.MapClaims{}, ErrTokenRevoked
+		}
+	}
+	// check if all sessions of the user have been revoked after token was issued
+	ctx, revokedBefore, err := a.tokenStorage.GetRevokedBefore(ctx, claimsUserID(claims))
+	if err != nil {
+		return ctx, jwt.MapClaims{}, fmt.Errorf("validateToken: %w", err)
+	}
+	if revokedBeforeWatermark(claims, revokedBefore) {
+		return ctx, jwt.MapClaims{}, ErrTokenRevoked
+	}
+	// tokens without session can't be revoked one by one, they have their
+	// own watermark set when other sessions are revoked
+	if _, ok := claims["sid"].(string); !ok {
+		ctx, revokedBefore, err = a.tokenStorage.GetSessionlessRevokedBefore(ctx, claimsUserID(claims))
+		if err != nil {
+			return ctx, jwt.MapClaims{}, fmt.Errorf("validateToken: %w", err)
+		}
+		if revokedBeforeWatermark(claims, revokedBefore) {
+			return ctx, jwt.MapClaims{}, ErrTokenRevoked
+		}
+	}
+	return a.checkTokenBlacklisted(ctx, token, claims)
+}
+
+// revokedBeforeWatermark reports whether token was issued before the
+// watermark, zero watermark revokes nothing.
+func revokedBeforeWatermark(claims jwt.MapClaims, revokedBefore time.Time) bool {
+	if revokedBefore.IsZero() {
+		return false
+	}
+	issuedAt, ok := claims["iat"].(float64)
+	return !ok || int64(issuedAt) < revokedBefore.Unix()
+}
 
+// checkTokenBlacklisted checks if token was revoked by Logout.
+func (a *Auth) checkTokenBlacklisted(
+	ctx context.Context,
+	token string,
+	claims jwt.MapClaims,
+) (context.Context, jwt.MapClaims, error) {
+	// check if token exists in redis
 	ctx, value, err := a.tokenStorage.CheckTokenExists(ctx, token)
 	if err != nil {
 		return ctx, jwt.MapClaims{}, fmt.Errorf("validateToken: %w", err)
@@ -271,6 +565,33 @@ func (a *Auth) validateToken(ctx context.Context, token string) (context.Context
 	return ctx, claims, nil
 }
 
+// validateUserAccessToken validates access token issued to a user,
+// refresh tokens and tokens of apps are rejected.
+func (a *Auth) validateUserAccessToken(ctx context.Context, token string) (context.Context, jwt.MapClaims, error) {
+	ctx, claims, err := a.validateToken(ctx, token)
+	if err != nil {
+		return ctx, jwt.MapClaims{}, err
+	}
+	if claims["token_type"] != "access" || jwtlib.IsClientToken(claims) {
+		return ctx, jwt.MapClaims{}, ErrTokenWrongType
+	}
+	return ctx, claims, nil
+}
+
+// claimsUserID returns id of the user from claims checked by validateToken,
+// which guarantees the subject is present. It is 0 for tokens of apps.
+func claimsUserID(claims jwt.MapClaims) int64 {
+	userID, _ := jwtlib.UserID(claims)
+	return userID
+}
+
+// claimsExpiresAt returns expiration of the token from claims checked by
+// validateToken, which guarantees "exp" is present.
+func claimsExpiresAt(claims jwt.MapClaims) time.Time {
+	exp, _ := claims["exp"].(float64)
+	return time.Unix(int64(exp), 0)
+}
+
 type userWithTokens struct {
 	user         *models.User
 	accessToken  string
@@ -281,9 +602,13 @@ type userWithTokens struct {
 func (a *Auth) generateRefreshAccessToken(
 	ctx context.Context,
 	value any,
+	params jwtlib.TokenParams,
 ) (context.Context, userWithTokens, error) {
 
-	ctx, user, err := a.userStorage.GetUser(ctx, value)
+	if params.TenantID == 0 {
+		params.TenantID = models.DefaultTenantID
+	}
+	ctx, user, err := a.userStorage.GetUser(ctx, params.TenantID, value)
 	if err != nil {
 		if errors.Is(err, storage.ErrUserNotFound) {
 			return ctx,
@@ -301,7 +626,39 @@ func (a *Auth) generateRefreshAccessToken(
 			}, err
 	}
 
-	accessToken, err := jwtlib.NewToken(user, a.cfg, "access")
+	ctx, roles, err := a.roleStorage.UserRoles(ctx, user.TenantID, user.ID)
+	if err != nil {
+		return ctx,
+			userWithTokens{
+				user:         nil,
+				accessToken:  "",
+				refreshToken: "",
+			}, fmt.Errorf("roles extraction failed: %w", err)
+	}
+	params.Roles, params.Permissions = roleNames(roles), effectivePermissions(roles)
+
+	ctx, groups, err := a.groupStorage.UserGroups(ctx, user.ID)
+	if err != nil {
+		return ctx,
+			userWithTokens{
+				user:         nil,
+				accessToken:  "",
+				refreshToken: "",
+			}, fmt.Errorf("groups extraction failed: %w", err)
+	}
+	params.Groups = groupNames(groups)
+
+	signingKey, err := a.keyRing.SigningKey(time.Now())
+	if err != nil {
+		return ctx,
+			userWithTokens{
+				user:         nil,
+				accessToken:  "",
+				refreshToken: "",
+			}, fmt.Errorf("signing key selection failed: %w", err)
+	}
+
+	accessToken, err := jwtlib.NewToken(user, a.cfg, signingKey, "access", params)
 	if err != nil {
 		return ctx,
 			userWithTokens{
@@ -310,7 +667,7 @@ func (a *Auth) generateRefreshAccessToken(
 				refreshToken: "",
 			}, fmt.Errorf("accessToken generation failed: %w", err)
 	}
-	refreshToken, err := jwtlib.NewToken(user, a.cfg, "refresh")
+	refreshToken, err := jwtlib.NewToken(user, a.cfg, signingKey, "refresh", params)
 	if err != nil {
 		return ctx,
 			userWithTokens{
@@ -0,0 +1,199 @@
+package auth_service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/crypto/bcrypt"
+	"log/slog"
+	"sso/internal/domain/models"
+	jwtlib "sso/internal/lib/jwt"
+	"sso/storage"
+	"time"
+)
+
+// DeleteAccount erases the account and signs it out everywhere. Users
+// delete themselves (userID is zero or their own id) confirming it with
+// password, admins delete users of their tenant without it.
+func (a *Auth) DeleteAccount(
+	ctx context.Context,
+	token string,
+	userID int64,
+	password string,
+) (success bool, err error) {
+	const op = "SERVICE LAYER: auth_service.DeleteAccount"
+
+	ctx, span := tracer.Start(ctx, "service layer: delete account",
+		trace.WithAttributes(attribute.String("handler", "deleteAccount")))
+	defer span.End()
+
+	log := a.log.With(slog.String("info", op))
+
+	ctx, claims, err := a.validateUserAccessToken(ctx, token)
+	if err != nil {
+		log.Info("failed validate token", slog.String("error", err.Error()))
+		return false, err
+	}
+	callerID := claimsUserID(claims)
+	if userID == 0 {
+		userID = callerID
+	}
+	log = log.With(slog.Int64("user-id", userID), slog.Int64("caller-id", callerID))
+
+	if userID == callerID {
+		var user models.User
+		ctx, user, err = a.userStorage.GetUser(ctx, jwtlib.TenantID(claims), int(userID))
+		if err != nil {
+			if errors.Is(err, storage.ErrUserNotFound) {
+				return false, ErrUserNotFound
+			}
+			log.Error("failed to get user", slog.String("error", err.Error()))
+			return false, fmt.Errorf("%s: %w", op, err)
+		}
+		if err = bcrypt.CompareHashAndPassword(user.PassHash, []byte(password)); err != nil {
+			log.Info("password mismatch")
+			return false, ErrInvalidCredentials
+		}
+	} else if ctx, err = a.authorizeUserAccess(ctx, claims, int(userID)); err != nil {
+		log.Info("caller is not allowed to delete the account", slog.String("error", err.Error()))
+		return false, err
+	}
+
+	// tokens are revoked first: if deletion fails the user is only signed out
+	ctx, err = a.revokeAllSessions(ctx, int(userID))
+	if err != nil {
+		log.Error("failed to revoke sessions", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	ctx, err = a.sessionStorage.DeleteUserSessions(ctx, userID)
+	if err != nil {
+		log.Error("failed to delete sessions", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	ctx, err = a.userStorage.DeleteUser(ctx, jwtlib.TenantID(claims), userID, callerID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return false, ErrUserNotFound
+		}
+		log.Error("failed to delete user", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	a.decisions.InvalidateUser(userID)
+	a.securityEvent(ctx, "account_deleted",
+		slog.Int64("user_id", userID),
+		slog.Int64("deleted_by", callerID))
+	return true, nil
+}
+
+// ExportMyData returns JSON archive of personal data of the caller:
+// profile, sessions, login history, roles, organizations and groups.
+func (a *Auth) ExportMyData(
+	ctx context.Context,
+	token string,
+) (archive []byte, err error) {
+	const op = "SERVICE LAYER: auth_service.ExportMyData"
+
+	ctx, span := tracer.Start(ctx, "service layer: export my data",
+		trace.WithAttributes(attribute.String("handler", "exportMyData")))
+	defer span.End()
+
+	log := a.log.With(slog.String("info", op))
+
+	ctx, claims, err := a.validateUserAccessToken(ctx, token)
+	if err != nil {
+		log.Info("failed validate token", slog.String("error", err.Error()))
+		return nil, err
+	}
+	userID := claimsUserID(claims)
+	log = log.With(slog.Int64("user-id", userID))
+
+	ctx, user, err := a.userStorage.GetUser(ctx, jwtlib.TenantID(claims), int(userID))
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	export := models.AccountExport{
+		ExportedAt: time.Now().UTC(),
+		Profile: models.AccountProfile{
+			ID:            user.ID,
+			TenantID:      user.TenantID,
+			Email:         user.Email,
+			EmailVerified: user.EmailVerified,
+			IsAdmin:       user.IsAdmin,
+		},
+	}
+	if ctx, export.Sessions, err = a.sessionStorage.ListSessions(ctx, userID); err != nil {
+		log.Error("failed to list sessions", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if ctx, export.LoginHistory, err = a.userStorage.LoginHistory(ctx, userID); err != nil {
+		log.Error("failed to get login history", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ctx, roles, err := a.roleStorage.UserRoles(ctx, user.TenantID, userID)
+	if err != nil {
+		log.Error("failed to get roles", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	export.Roles = make([]models.ExportedRole, 0, len(roles))
+	for _, role := range roles {
+		export.Roles = append(export.Roles, models.ExportedRole{
+			Name:        role.Name,
+			Permissions: effectivePermissions([]models.Role{role}),
+		})
+	}
+
+	ctx, memberships, err := a.orgStorage.UserOrganizations(ctx, userID)
+	if err != nil {
+		log.Error("failed to get organizations", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	export.Organizations = make([]models.ExportedOrg, 0, len(memberships))
+	for _, membership := range memberships {
+		export.Organizations = append(export.Organizations, models.ExportedOrg{
+			ID:   membership.Organization.ID,
+			Name: membership.Organization.Name,
+			Slug: membership.Organization.Slug,
+			Role: membership.Role,
+		})
+	}
+
+	ctx, groups, err := a.groupStorage.UserGroups(ctx, userID)
+	if err != nil {
+		log.Error("failed to get groups", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	export.Groups = groupNames(groups)
+
+	archive, err = json.Marshal(export)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	a.securityEvent(ctx, "data_exported", slog.Int64("user_id", userID))
+	return archive, nil
+}
+
+// recordLogin appends sign-in to login history, failure to record never
+// fails the login.
+func (a *Auth) recordLogin(ctx context.Context, userID int64) context.Context {
+	userAgent, ip := a.clientInfo(ctx)
+	ctx, err := a.userStorage.SaveLogin(ctx, models.Login{
+		UserID:    userID,
+		IP:        ip,
+		UserAgent: userAgent,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		a.log.Error("failed to record login",
+			slog.Int64("user-id", userID),
+			slog.String("error", err.Error()),
+		)
+	}
+	return ctx
+}
@@ -0,0 +1,255 @@
+package auth_service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"sso/internal/domain/models"
+	jwtlib "sso/internal/lib/jwt"
+	"sso/storage"
+	"strconv"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// ListUsers returns page of users of the admin's tenant matching the
+// filter. Cursor is opaque for clients, it is id of the last user of the
+// previous page.
+func (a *Auth) ListUsers(
+	ctx context.Context,
+	token string,
+	filter models.UserFilter,
+	cursor string,
+) (users []models.User, nextCursor string, err error) {
+	const op = "SERVICE LAYER: auth_service.ListUsers"
+
+	ctx, span := tracer.Start(ctx, "service layer: list users",
+		trace.WithAttributes(attribute.String("handler", "listUsers")))
+	defer span.End()
+
+	log := a.log.With(slog.String("info", op))
+
+	ctx, claims, err := a.requireAdmin(ctx, token)
+	if err != nil {
+		log.Info("caller is not allowed to list users", slog.String("error", err.Error()))
+		return nil, "", err
+	}
+	filter.TenantID = jwtlib.TenantID(claims)
+	if filter.AfterID, err = decodeCursor(cursor); err != nil {
+		return nil, "", err
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultPageSize
+	}
+	filter.Limit = min(filter.Limit, maxPageSize)
+
+	// one extra row tells whether there is a next page
+	pageSize := filter.Limit
+	filter.Limit++
+	ctx, users, err = a.userStorage.ListUsers(ctx, filter)
+	if err != nil {
+		log.Error("failed to list users", slog.String("error", err.Error()))
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+	if len(users) > pageSize {
+		users = users[:pageSize]
+		nextCursor = encodeCursor(users[pageSize-1].ID)
+	}
+	return users, nextCursor, nil
+}
+
+// GetUser returns user of the admin's tenant.
+func (a *Auth) GetUser(
+	ctx context.Context,
+	token string,
+	userID int64,
+) (user models.User, err error) {
+	const op = "SERVICE LAYER: auth_service.GetUser"
+
+	ctx, span := tracer.Start(ctx, "service layer: get user",
+		trace.WithAttributes(attribute.String("handler", "getUser")))
+	defer span.End()
+
+	ctx, claims, err := a.requireAdmin(ctx, token)
+	if err != nil {
+		return models.User{}, err
+	}
+	ctx, user, err = a.userStorage.GetUser(ctx, jwtlib.TenantID(claims), int(userID))
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.User{}, ErrUserNotFound
+		}
+		a.log.Error("failed to get user", slog.String("info", op), slog.String("error", err.Error()))
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return user, nil
+}
+
+// DisableUser forbids the user to sign in and revokes all their sessions.
+// Admins can't disable themselves, so a tenant never loses its last admin
+// by mistake.
+func (a *Auth) DisableUser(
+	ctx context.Context,
+	token string,
+	userID int64,
+) (success bool, err error) {
+	const op = "SERVICE LAYER: auth_service.DisableUser"
+
+	ctx, span := tracer.Start(ctx, "service layer: disable user",
+		trace.WithAttributes(attribute.String("handler", "disableUser")))
+	defer span.End()
+
+	log := a.log.With(
+		slog.String("info", op),
+		slog.Int64("user-id", userID),
+	)
+
+	ctx, claims, err := a.requireAdminOf(ctx, token, userID)
+	if err != nil {
+		log.Info("caller is not allowed to disable the user", slog.String("error", err.Error()))
+		return false, err
+	}
+	if claimsUserID(claims) == userID {
+		return false, ErrPermissionDenied
+	}
+	ctx, err = a.userStorage.SetDisabled(ctx, jwtlib.TenantID(claims), userID, true)
+	if err != nil {
+		log.Error("failed to disable user", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	ctx, err = a.revokeAllSessions(ctx, int(userID))
+	if err != nil {
+		log.Error("failed to revoke sessions", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	ctx, err = a.sessionStorage.DeleteUserSessions(ctx, userID)
+	if err != nil {
+		log.Error("failed to delete sessions", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	a.securityEvent(ctx, "user_disabled",
+		slog.Int64("user_id", userID),
+		slog.Int64("admin_id", claimsUserID(claims)))
+	return true, nil
+}
+
+// EnableUser allows disabled user to sign in again.
+func (a *Auth) EnableUser(
+	ctx context.Context,
+	token string,
+	userID int64,
+) (success bool, err error) {
+	const op = "SERVICE LAYER: auth_service.EnableUser"
+
+	ctx, span := tracer.Start(ctx, "service layer: enable user",
+		trace.WithAttributes(attribute.String("handler", "enableUser")))
+	defer span.End()
+
+	log := a.log.With(
+		slog.String("info", op),
+		slog.Int64("user-id", userID),
+	)
+
+	ctx, claims, err := a.requireAdminOf(ctx, token, userID)
+	if err != nil {
+		log.Info("caller is not allowed to enable the user", slog.String("error", err.Error()))
+		return false, err
+	}
+	ctx, err = a.userStorage.SetDisabled(ctx, jwtlib.TenantID(claims), userID, false)
+	if err != nil {
+		log.Error("failed to enable user", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	a.securityEvent(ctx, "user_enabled",
+		slog.Int64("user_id", userID),
+		slog.Int64("admin_id", claimsUserID(claims)))
+	return true, nil
+}
+
+// SetAdmin grants or revokes admin role. Admins can't demote themselves.
+func (a *Auth) SetAdmin(
+	ctx context.Context,
+	token string,
+	userID int64,
+	isAdmin bool,
+) (success bool, err error) {
+	const op = "SERVICE LAYER: auth_service.SetAdmin"
+
+	ctx, span := tracer.Start(ctx, "service layer: set admin",
+		trace.WithAttributes(attribute.String("handler", "setAdmin")))
+	defer span.End()
+
+	log := a.log.With(
+		slog.String("info", op),
+		slog.Int64("user-id", userID),
+		slog.Bool("is-admin", isAdmin),
+	)
+
+	ctx, claims, err := a.requireAdminOf(ctx, token, userID)
+	if err != nil {
+		log.Info("caller is not allowed to change admins", slog.String("error", err.Error()))
+		return false, err
+	}
+	if claimsUserID(claims) == userID && !isAdmin {
+		return false, ErrPermissionDenied
+	}
+	if isAdmin {
+		ctx, err = a.roleStorage.GrantRole(ctx, jwtlib.TenantID(claims), userID, models.AdminRole)
+	} else {
+		ctx, err = a.roleStorage.RevokeRole(ctx, jwtlib.TenantID(claims), userID, models.AdminRole)
+	}
+	if err != nil {
+		log.Error("failed to change admin role", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	a.decisions.InvalidateUser(userID)
+	a.securityEvent(ctx, "admin_changed",
+		slog.Int64("user_id", userID),
+		slog.Bool("is_admin", isAdmin),
+		slog.Int64("admin_id", claimsUserID(claims)))
+	return true, nil
+}
+
+// requireAdminOf checks that token is an access token of an admin of the
+// tenant the user belongs to.
+func (a *Auth) requireAdminOf(ctx context.Context, token string, userID int64) (context.Context, jwt.MapClaims, error) {
+	ctx, claims, err := a.requireAdmin(ctx, token)
+	if err != nil {
+		return ctx, nil, err
+	}
+	ctx, _, err = a.userStorage.GetUser(ctx, jwtlib.TenantID(claims), int(userID))
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return ctx, nil, ErrUserNotFound
+		}
+		return ctx, nil, fmt.Errorf("requireAdminOf: %w", err)
+	}
+	return ctx, claims, nil
+}
+
+func encodeCursor(lastID int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(lastID, 10)))
+}
+
+func decodeCursor(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	lastID, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil || lastID < 0 {
+		return 0, ErrInvalidCursor
+	}
+	return lastID, nil
+}
@@ -0,0 +1,116 @@
+package auth_service
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"net/url"
+	"sso/internal/domain/models"
+	jwtlib "sso/internal/lib/jwt"
+	"sso/storage"
+	"time"
+)
+
+// RegisterApp adds the app to the registry and returns its client id and
+// secret. Only admins may register apps, the secret is never shown again.
+func (a *Auth) RegisterApp(
+	ctx context.Context,
+	token string,
+	name string,
+	redirectURIs []string,
+	allowedScopes []string,
+) (clientID int, clientSecret string, err error) {
+	const op = "SERVICE LAYER: auth_service.RegisterApp"
+
+	ctx, span := tracer.Start(ctx, "service layer: register app",
+		trace.WithAttributes(attribute.String("handler", "registerApp")))
+	defer span.End()
+
+	log := a.log.With(
+		slog.String("info", op),
+		slog.String("app-name", name),
+	)
+
+	ctx, claims, err := a.requireAdmin(ctx, token)
+	if err != nil {
+		log.Info("caller is not allowed to register apps", slog.String("error", err.Error()))
+		return 0, "", err
+	}
+	for _, redirectURI := range redirectURIs {
+		if !validRedirectURI(redirectURI) {
+			return 0, "", fmt.Errorf("%w: %s", ErrInvalidRedirectURI, redirectURI)
+		}
+	}
+
+	clientSecret, err = randomCode()
+	if err != nil {
+		return 0, "", fmt.Errorf("%s: %w", op, err)
+	}
+	secretHash := sha256.Sum256([]byte(clientSecret))
+	ctx, clientID, err = a.appStorage.SaveApp(ctx, models.App{
+		Name:          name,
+		SecretHash:    secretHash[:],
+		RedirectURIs:  redirectURIs,
+		AllowedScopes: allowedScopes,
+		TenantID:      jwtlib.TenantID(claims),
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrAppExists) {
+			return 0, "", ErrAppExists
+		}
+		log.Error("failed to save app", slog.String("error", err.Error()))
+		return 0, "", fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("app registered", slog.Int("client-id", clientID))
+	return clientID, clientSecret, nil
+}
+
+// ClientCredentials issues access token to the app itself for service to
+// service calls (RFC 6749 4.4). The token has sub=client_id and no user.
+func (a *Auth) ClientCredentials(
+	ctx context.Context,
+	clientID int,
+	clientSecret string,
+	scopes []string,
+) (accessToken string, err error) {
+	const op = "SERVICE LAYER: auth_service.ClientCredentials"
+
+	ctx, span := tracer.Start(ctx, "service layer: client credentials",
+		trace.WithAttributes(attribute.String("handler", "clientCredentials")))
+	defer span.End()
+
+	log := a.log.With(
+		slog.String("info", op),
+		slog.Int("client-id", clientID),
+	)
+
+	ctx, app, err := a.authenticateApp(ctx, clientID, clientSecret)
+	if err != nil {
+		log.Info("app authentication failed", slog.String("error", err.Error()))
+		return "", err
+	}
+	scope, err := grantScopes(app.AllowedScopes, scopes)
+	if err != nil {
+		return "", err
+	}
+	signingKey, err := a.keyRing.SigningKey(time.Now())
+	if err != nil {
+		return "", fmt.Errorf("%s: signing key selection failed: %w", op, err)
+	}
+	accessToken, err = jwtlib.NewClientToken(app, a.cfg, signingKey, jwtlib.TokenParams{Scope: scope})
+	if err != nil {
+		log.Error("failed to generate token", slog.String("error", err.Error()))
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	return accessToken, nil
+}
+
+// validRedirectURI allows absolute urls without fragment (RFC 6749 3.1.2).
+func validRedirectURI(redirectURI string) bool {
+	parsed, err := url.Parse(redirectURI)
+	return err == nil && parsed.IsAbs() && parsed.Host != "" && parsed.Fragment == ""
+}
@@ -0,0 +1,155 @@
+package auth_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"net/url"
+	jwtlib "sso/internal/lib/jwt"
+	"sso/internal/lib/mailer"
+	"sso/storage"
+	"time"
+)
+
+// emailChangeThrottlePrefix keys throttling of email change confirmations.
+const emailChangeThrottlePrefix = "email_change_request:"
+
+// RequestEmailChange sends confirmation link to the new email of the
+// caller. Email stays the same until the link is opened.
+func (a *Auth) RequestEmailChange(
+	ctx context.Context,
+	token string,
+	newEmail string,
+) (success bool, err error) {
+	const op = "SERVICE LAYER: auth_service.RequestEmailChange"
+
+	ctx, span := tracer.Start(ctx, "service layer: request email change",
+		trace.WithAttributes(attribute.String("handler", "requestEmailChange")))
+	defer span.End()
+
+	log := a.log.With(slog.String("info", op))
+
+	ctx, claims, err := a.validateUserAccessToken(ctx, token)
+	if err != nil {
+		log.Info("failed validate token", slog.String("error", err.Error()))
+		return false, err
+	}
+	tenantID := jwtlib.TenantID(claims)
+	ctx, user, err := a.userStorage.GetUser(ctx, tenantID, int(claimsUserID(claims)))
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return false, ErrUserNotFound
+		}
+		log.Error("failed to get user", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	log = log.With(slog.Int64("user-id", user.ID))
+	if newEmail == user.Email {
+		return false, ErrSameEmail
+	}
+	ctx, _, err = a.userStorage.GetUser(ctx, tenantID, newEmail)
+	if err == nil {
+		return false, ErrEmailTaken
+	}
+	if !errors.Is(err, storage.ErrUserNotFound) {
+		log.Error("failed to check new email", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ctx, first, err := a.throttleEmail(ctx, emailChangeThrottlePrefix, user.ID, a.cfg.EmailChange.RequestInterval)
+	if err != nil {
+		log.Error("failed to throttle email change", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	if !first {
+		return false, ErrTooManyRequests
+	}
+
+	signingKey, err := a.keyRing.SigningKey(time.Now())
+	if err != nil {
+		log.Error("failed to get signing key", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	changeToken, err := jwtlib.NewEmailChangeToken(user, newEmail, a.cfg, signingKey, a.cfg.EmailChange.TokenTtl)
+	if err != nil {
+		log.Error("failed to generate email change token", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	err = a.sendEmail(ctx, newEmail, mailer.TemplateEmailChange, mailer.LinkData{
+		Email:    newEmail,
+		Link:     a.cfg.OIDC.Issuer + "/sso/email/change/confirm?token=" + url.QueryEscape(changeToken),
+		ValidFor: a.cfg.EmailChange.TokenTtl.String(),
+	})
+	if err != nil {
+		log.Error("failed to send confirmation email", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	a.securityEvent(ctx, "email_change_requested", slog.Int64("user_id", user.ID))
+	return true, nil
+}
+
+// ConfirmEmailChange applies email change by token of the confirmation
+// link. Every token carrying the old email is revoked and the old address
+// is notified about the change.
+func (a *Auth) ConfirmEmailChange(
+	ctx context.Context,
+	token string,
+) (success bool, err error) {
+	const op = "SERVICE LAYER: auth_service.ConfirmEmailChange"
+
+	ctx, span := tracer.Start(ctx, "service layer: confirm email change",
+		trace.WithAttributes(attribute.String("handler", "confirmEmailChange")))
+	defer span.End()
+
+	log := a.log.With(slog.String("info", op))
+
+	// the token embeds the old email, it stops working once the email
+	// changes, so at most one of concurrent changes wins
+	ctx, user, claims, err := a.useActionToken(ctx, token, jwtlib.ActionEmailChange)
+	if err != nil {
+		log.Info("email change token rejected", slog.String("error", err.Error()))
+		return false, err
+	}
+	newEmail, _ := claims["new_email"].(string)
+	if newEmail == "" {
+		return false, ErrInvalidActionToken
+	}
+	log = log.With(slog.Int64("user-id", user.ID))
+
+	ctx, err = a.userStorage.UpdateEmail(ctx, user.TenantID, user.ID, user.Email, newEmail)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserExists) {
+			return false, ErrEmailTaken
+		}
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return false, ErrInvalidActionToken
+		}
+		log.Error("failed to update email", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	// access and ID tokens carry the email claim, none of them may outlive it
+	ctx, err = a.revokeAllSessions(ctx, int(user.ID))
+	if err != nil {
+		log.Error("failed to revoke sessions", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	ctx, err = a.sessionStorage.DeleteUserSessions(ctx, user.ID)
+	if err != nil {
+		log.Error("failed to delete sessions", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = a.sendEmail(ctx, user.Email, mailer.TemplateEmailChanged, mailer.EmailChangedData{
+		Email:    user.Email,
+		NewEmail: newEmail,
+	})
+	if err != nil {
+		// the change is done, failed notice must not undo it
+		log.Error("failed to notify old email", slog.String("error", err.Error()))
+	}
+	a.securityEvent(ctx, "email_changed", slog.Int64("user_id", user.ID))
+	return true, nil
+}
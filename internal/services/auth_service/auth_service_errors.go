@@ -9,4 +9,37 @@ var (
 	ErrTokenParsing       = errors.New("fail to parse token")
 	ErrTokenTtlExpired    = errors.New("token ttl expired")
 	ErrTokenWrongType     = errors.New("token wrong type")
+	ErrTokenUnknownKey    = errors.New("token signed with unknown key")
+	ErrTokenReused        = errors.New("refresh token reuse detected")
+	ErrPermissionDenied   = errors.New("permission denied")
+	ErrSessionNotFound    = errors.New("session not found")
+	ErrInvalidClient      = errors.New("invalid client credentials")
+	ErrTokenWrongIssuer   = errors.New("token issued by another issuer")
+	ErrInvalidRedirectURI = errors.New("redirect uri is not registered for the app")
+	ErrInvalidRequest     = errors.New("invalid authorization request")
+	ErrInvalidGrant       = errors.New("invalid authorization grant")
+	ErrAppExists          = errors.New("app already exists")
+	ErrInvalidScope       = errors.New("scope is not allowed for the app")
+	ErrTokenWrongAudience = errors.New("token issued for another audience")
+	ErrRoleNotFound       = errors.New("role not found")
+	ErrRoleExists         = errors.New("role already exists")
+	ErrInvalidPermission  = errors.New("permission must be in action:resource format")
+	ErrInvalidTuple       = errors.New("tuple must be in type:id#relation@subject format")
+	ErrOrgNotFound        = errors.New("organization not found")
+	ErrOrgExists          = errors.New("organization already exists")
+	ErrInvalidOrgRole     = errors.New("organization role must be owner, admin or member")
+	ErrMemberNotFound     = errors.New("user is not a member of the organization")
+	ErrGroupNotFound      = errors.New("group not found")
+	ErrGroupExists        = errors.New("group already exists")
+	ErrGroupCycle         = errors.New("group can't be nested into its own subgroup")
+	ErrEmailNotVerified   = errors.New("email is not verified")
+	ErrInvalidActionToken = errors.New("link is invalid, expired or has already been used")
+	ErrTooManyRequests    = errors.New("too many requests, try again later")
+	ErrWeakPassword       = errors.New("password does not satisfy password policy")
+	ErrSamePassword       = errors.New("new password must differ from the old one")
+	ErrEmailTaken         = errors.New("email is already used by another account")
+	ErrSameEmail          = errors.New("new email must differ from the current one")
+	ErrUserDisabled       = errors.New("user is disabled")
+	ErrInvalidCursor      = errors.New("invalid page cursor")
+	ErrTooManyAttempts    = errors.New("too many failed login attempts")
 )
@@ -0,0 +1,24 @@
+package auth_service
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+)
+
+// securityEvent reports events which need attention of security team,
+// e.g. stolen refresh token replay. Events are written to the log with
+// "security_event" key, so they can be filtered in loki, and attached
+// to the current span.
+func (a *Auth) securityEvent(ctx context.Context, name string, attrs ...slog.Attr) {
+	spanAttrs := make([]attribute.KeyValue, 0, len(attrs))
+	args := make([]any, 0, len(attrs)+1)
+	args = append(args, slog.String("security_event", name))
+	for _, attr := range attrs {
+		args = append(args, attr)
+		spanAttrs = append(spanAttrs, attribute.String(attr.Key, attr.Value.String()))
+	}
+	a.log.Warn("security event", args...)
+	trace.SpanFromContext(ctx).AddEvent(name, trace.WithAttributes(spanAttrs...))
+}
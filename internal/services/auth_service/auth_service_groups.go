@@ -0,0 +1,266 @@
+package auth_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"sso/internal/domain/models"
+	jwtlib "sso/internal/lib/jwt"
+	"sso/storage"
+)
+
+// CreateGroup creates group in the tenant of the admin.
+func (a *Auth) CreateGroup(
+	ctx context.Context,
+	token string,
+	name string,
+) (groupID int64, err error) {
+	const op = "SERVICE LAYER: auth_service.CreateGroup"
+
+	ctx, span := tracer.Start(ctx, "service layer: create group",
+		trace.WithAttributes(attribute.String("handler", "createGroup")))
+	defer span.End()
+
+	log := a.log.With(
+		slog.String("info", op),
+		slog.String("group", name),
+	)
+
+	ctx, claims, err := a.requireAdmin(ctx, token)
+	if err != nil {
+		log.Info("caller is not allowed to create groups", slog.String("error", err.Error()))
+		return 0, err
+	}
+	ctx, groupID, err = a.groupStorage.SaveGroup(ctx, models.Group{
+		TenantID: jwtlib.TenantID(claims),
+		Name:     name,
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrGroupExists) {
+			return 0, ErrGroupExists
+		}
+		log.Error("failed to save group", slog.String("error", err.Error()))
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("group created", slog.Int64("group-id", groupID))
+	return groupID, nil
+}
+
+// AddGroupMember adds user of the admin's tenant to the group.
+func (a *Auth) AddGroupMember(
+	ctx context.Context,
+	token string,
+	groupID int64,
+	userID int,
+) (success bool, err error) {
+	const op = "SERVICE LAYER: auth_service.AddGroupMember"
+
+	ctx, span := tracer.Start(ctx, "service layer: add group member",
+		trace.WithAttributes(attribute.String("handler", "addGroupMember")))
+	defer span.End()
+
+	log := a.log.With(
+		slog.String("info", op),
+		slog.Int64("group-id", groupID),
+		slog.Int("user-id", userID),
+	)
+
+	ctx, tenantID, err := a.requireGroupAdmin(ctx, token, groupID)
+	if err != nil {
+		log.Info("caller is not allowed to manage the group", slog.String("error", err.Error()))
+		return false, err
+	}
+	ctx, _, err = a.userStorage.GetUser(ctx, tenantID, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return false, ErrUserNotFound
+		}
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	ctx, err = a.groupStorage.AddGroupMember(ctx, groupID, int64(userID))
+	if err != nil {
+		if errors.Is(err, storage.ErrGroupNotFound) {
+			return false, ErrGroupNotFound
+		}
+		log.Error("failed to add group member", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	a.securityEvent(ctx, "group_member_added", slog.Int("user_id", userID), slog.Int64("group_id", groupID))
+	return true, nil
+}
+
+// RemoveGroupMember removes user from the group, missing membership is
+// not an error.
+func (a *Auth) RemoveGroupMember(
+	ctx context.Context,
+	token string,
+	groupID int64,
+	userID int,
+) (success bool, err error) {
+	const op = "SERVICE LAYER: auth_service.RemoveGroupMember"
+
+	ctx, span := tracer.Start(ctx, "service layer: remove group member",
+		trace.WithAttributes(attribute.String("handler", "removeGroupMember")))
+	defer span.End()
+
+	log := a.log.With(
+		slog.String("info", op),
+		slog.Int64("group-id", groupID),
+		slog.Int("user-id", userID),
+	)
+
+	ctx, _, err = a.requireGroupAdmin(ctx, token, groupID)
+	if err != nil {
+		log.Info("caller is not allowed to manage the group", slog.String("error", err.Error()))
+		return false, err
+	}
+	ctx, err = a.groupStorage.RemoveGroupMember(ctx, groupID, int64(userID))
+	if err != nil {
+		log.Error("failed to remove group member", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	a.securityEvent(ctx, "group_member_removed", slog.Int("user_id", userID), slog.Int64("group_id", groupID))
+	return true, nil
+}
+
+// AddSubgroup nests child group into parent group, so members of child
+// become members of parent. Nesting that would close a cycle is refused.
+func (a *Auth) AddSubgroup(
+	ctx context.Context,
+	token string,
+	parentID int64,
+	childID int64,
+) (success bool, err error) {
+	const op = "SERVICE LAYER: auth_service.AddSubgroup"
+
+	ctx, span := tracer.Start(ctx, "service layer: add subgroup",
+		trace.WithAttributes(attribute.String("handler", "addSubgroup")))
+	defer span.End()
+
+	log := a.log.With(
+		slog.String("info", op),
+		slog.Int64("parent-id", parentID),
+		slog.Int64("child-id", childID),
+	)
+
+	ctx, err = a.requireGroupsAdmin(ctx, token, parentID, childID)
+	if err != nil {
+		log.Info("caller is not allowed to manage the groups", slog.String("error", err.Error()))
+		return false, err
+	}
+	ctx, err = a.groupStorage.AddSubgroup(ctx, parentID, childID)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrGroupCycle):
+			return false, ErrGroupCycle
+		case errors.Is(err, storage.ErrGroupNotFound):
+			return false, ErrGroupNotFound
+		}
+		log.Error("failed to add subgroup", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return true, nil
+}
+
+// RemoveSubgroup removes nesting of child group into parent group.
+func (a *Auth) RemoveSubgroup(
+	ctx context.Context,
+	token string,
+	parentID int64,
+	childID int64,
+) (success bool, err error) {
+	const op = "SERVICE LAYER: auth_service.RemoveSubgroup"
+
+	ctx, span := tracer.Start(ctx, "service layer: remove subgroup",
+		trace.WithAttributes(attribute.String("handler", "removeSubgroup")))
+	defer span.End()
+
+	log := a.log.With(
+		slog.String("info", op),
+		slog.Int64("parent-id", parentID),
+		slog.Int64("child-id", childID),
+	)
+
+	ctx, err = a.requireGroupsAdmin(ctx, token, parentID, childID)
+	if err != nil {
+		log.Info("caller is not allowed to manage the groups", slog.String("error", err.Error()))
+		return false, err
+	}
+	ctx, err = a.groupStorage.RemoveSubgroup(ctx, parentID, childID)
+	if err != nil {
+		log.Error("failed to remove subgroup", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return true, nil
+}
+
+// UserGroups returns effective groups of the user: groups they are a
+// member of directly and every group those are nested into.
+func (a *Auth) UserGroups(
+	ctx context.Context,
+	token string,
+	userID int,
+) (groups []models.Group, err error) {
+	const op = "SERVICE LAYER: auth_service.UserGroups"
+
+	ctx, span := tracer.Start(ctx, "service layer: user groups",
+		trace.WithAttributes(attribute.String("handler", "userGroups")))
+	defer span.End()
+
+	ctx, claims, err := a.validateUserAccessToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = a.authorizeUserAccess(ctx, claims, userID)
+	if err != nil {
+		return nil, err
+	}
+	ctx, groups, err = a.groupStorage.UserGroups(ctx, int64(userID))
+	if err != nil {
+		a.log.Error("failed to get groups", slog.String("info", op), slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return groups, nil
+}
+
+// requireGroupAdmin allows admins to manage groups of their own tenant,
+// returns the tenant.
+func (a *Auth) requireGroupAdmin(ctx context.Context, token string, groupID int64) (context.Context, int64, error) {
+	ctx, claims, err := a.requireAdmin(ctx, token)
+	if err != nil {
+		return ctx, 0, err
+	}
+	tenantID := jwtlib.TenantID(claims)
+	ctx, group, err := a.groupStorage.Group(ctx, groupID)
+	if err != nil {
+		if errors.Is(err, storage.ErrGroupNotFound) {
+			return ctx, 0, ErrGroupNotFound
+		}
+		return ctx, 0, fmt.Errorf("requireGroupAdmin: %w", err)
+	}
+	// groups of other tenants are invisible
+	if group.TenantID != tenantID {
+		return ctx, 0, ErrGroupNotFound
+	}
+	return ctx, tenantID, nil
+}
+
+func (a *Auth) requireGroupsAdmin(ctx context.Context, token string, parentID int64, childID int64) (context.Context, error) {
+	ctx, _, err := a.requireGroupAdmin(ctx, token, parentID)
+	if err != nil {
+		return ctx, err
+	}
+	ctx, _, err = a.requireGroupAdmin(ctx, token, childID)
+	return ctx, err
+}
+
+func groupNames(groups []models.Group) []string {
+	names := make([]string, 0, len(groups))
+	for _, group := range groups {
+		names = append(names, group.Name)
+	}
+	return names
+}
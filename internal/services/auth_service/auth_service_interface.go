@@ -3,6 +3,8 @@ package auth_service
 
 import (
 	"context"
+	"sso/internal/domain/models"
+	jwtlib "sso/internal/lib/jwt"
 )
 
 type AuthorizationInterface interface {
@@ -10,11 +12,15 @@ type AuthorizationInterface interface {
 		ctx context.Context,
 		email string,
 		password string,
-	) (accessToken string, refreshToken string, err error)
+		appID int,
+		scopes []string,
+		tenantID int64,
+	) (accessToken string, refreshToken string, idToken string, err error)
 	Register(
 		ctx context.Context,
 		email string,
 		password string,
+		tenantID int64,
 	) (userID int64, err error)
 	Logout(
 		ctx context.Context,
@@ -23,13 +29,285 @@ type AuthorizationInterface interface {
 	IsAdmin(
 		ctx context.Context,
 		userID int,
+		tenantID int64,
 	) (success bool, err error)
 	Validate(
 		ctx context.Context,
 		token string,
+		audience string,
 	) (success bool, err error)
 	Refresh(
 		ctx context.Context,
 		token string,
+		appID int,
+		scopes []string,
 	) (accessToken string, refreshToken string, err error)
+	Jwks(
+		ctx context.Context,
+	) (keys []jwtlib.JWK, err error)
+	RevokeAllSessions(
+		ctx context.Context,
+		token string,
+		userID int,
+	) (success bool, err error)
+	ListSessions(
+		ctx context.Context,
+		token string,
+		userID int,
+	) (sessions []models.Session, currentSessionID string, err error)
+	RevokeSession(
+		ctx context.Context,
+		token string,
+		sessionID string,
+	) (success bool, err error)
+	Introspect(
+		ctx context.Context,
+		clientID int,
+		clientSecret string,
+		token string,
+		audience string,
+	) (info models.TokenInfo, err error)
+	OpenIDConfiguration(
+		ctx context.Context,
+	) (metadata models.ProviderMetadata, err error)
+	UserInfo(
+		ctx context.Context,
+		token string,
+	) (user models.User, err error)
+	ValidateAuthorizationRequest(
+		ctx context.Context,
+		req models.AuthorizationRequest,
+	) (app models.App, err error)
+	Authorize(
+		ctx context.Context,
+		req models.AuthorizationRequest,
+		email string,
+		password string,
+	) (code string, err error)
+	ExchangeAuthorizationCode(
+		ctx context.Context,
+		clientID int,
+		clientSecret string,
+		code string,
+		redirectURI string,
+		codeVerifier string,
+	) (accessToken string, refreshToken string, idToken string, err error)
+	RefreshAppTokens(
+		ctx context.Context,
+		clientID int,
+		clientSecret string,
+		refreshToken string,
+		scopes []string,
+	) (accessToken string, newRefreshToken string, err error)
+	RegisterApp(
+		ctx context.Context,
+		token string,
+		name string,
+		redirectURIs []string,
+		allowedScopes []string,
+	) (clientID int, clientSecret string, err error)
+	ClientCredentials(
+		ctx context.Context,
+		clientID int,
+		clientSecret string,
+		scopes []string,
+	) (accessToken string, err error)
+	CreateRole(
+		ctx context.Context,
+		token string,
+		name string,
+		description string,
+		permissions []string,
+	) (roleID int64, err error)
+	GrantRole(
+		ctx context.Context,
+		token string,
+		userID int,
+		roleName string,
+	) (success bool, err error)
+	RevokeRole(
+		ctx context.Context,
+		token string,
+		userID int,
+		roleName string,
+	) (success bool, err error)
+	UserPermissions(
+		ctx context.Context,
+		token string,
+		userID int,
+	) (roles []string, permissions []string, err error)
+	CheckPermission(
+		ctx context.Context,
+		token string,
+		userID int,
+		action string,
+		resource string,
+	) (allowed bool, err error)
+	CheckPermissions(
+		ctx context.Context,
+		token string,
+		checks []models.PermissionCheck,
+	) (decisions []bool, err error)
+	WriteTuples(
+		ctx context.Context,
+		token string,
+		tuples []models.Tuple,
+	) (success bool, err error)
+	DeleteTuples(
+		ctx context.Context,
+		token string,
+		tuples []models.Tuple,
+	) (success bool, err error)
+	Check(
+		ctx context.Context,
+		token string,
+		object string,
+		relation string,
+		subject string,
+	) (allowed bool, err error)
+	ListObjects(
+		ctx context.Context,
+		token string,
+		objectType string,
+		relation string,
+		subject string,
+	) (objects []string, err error)
+	CreateOrganization(
+		ctx context.Context,
+		token string,
+		name string,
+		slug string,
+	) (orgID int64, err error)
+	AddOrganizationMember(
+		ctx context.Context,
+		token string,
+		orgID int64,
+		userID int64,
+		role string,
+	) (success bool, err error)
+	RemoveOrganizationMember(
+		ctx context.Context,
+		token string,
+		orgID int64,
+		userID int64,
+	) (success bool, err error)
+	ListOrganizations(
+		ctx context.Context,
+		token string,
+	) (memberships []models.OrgMembership, err error)
+	CreateGroup(
+		ctx context.Context,
+		token string,
+		name string,
+	) (groupID int64, err error)
+	AddGroupMember(
+		ctx context.Context,
+		token string,
+		groupID int64,
+		userID int,
+	) (success bool, err error)
+	RemoveGroupMember(
+		ctx context.Context,
+		token string,
+		groupID int64,
+		userID int,
+	) (success bool, err error)
+	AddSubgroup(
+		ctx context.Context,
+		token string,
+		parentID int64,
+		childID int64,
+	) (success bool, err error)
+	RemoveSubgroup(
+		ctx context.Context,
+		token string,
+		parentID int64,
+		childID int64,
+	) (success bool, err error)
+	UserGroups(
+		ctx context.Context,
+		token string,
+		userID int,
+	) (groups []models.Group, err error)
+	VerifyEmail(
+		ctx context.Context,
+		token string,
+	) (success bool, err error)
+	ResendVerification(
+		ctx context.Context,
+		email string,
+		tenantID int64,
+	) (success bool, err error)
+	RequestPasswordReset(
+		ctx context.Context,
+		email string,
+		tenantID int64,
+	) (success bool, err error)
+	ResetPassword(
+		ctx context.Context,
+		token string,
+		newPassword string,
+	) (success bool, err error)
+	ChangePassword(
+		ctx context.Context,
+		token string,
+		oldPassword string,
+		newPassword string,
+		keepCurrentSession bool,
+	) (success bool, err error)
+	RequestEmailChange(
+		ctx context.Context,
+		token string,
+		newEmail string,
+	) (success bool, err error)
+	ConfirmEmailChange(
+		ctx context.Context,
+		token string,
+	) (success bool, err error)
+	DeleteAccount(
+		ctx context.Context,
+		token string,
+		userID int64,
+		password string,
+	) (success bool, err error)
+	ExportMyData(
+		ctx context.Context,
+		token string,
+	) (archive []byte, err error)
+}
+
+// AdminInterface is user management available to admins only.
+type AdminInterface interface {
+	ListUsers(
+		ctx context.Context,
+		token string,
+		filter models.UserFilter,
+		cursor string,
+	) (users []models.User, nextCursor string, err error)
+	GetUser(
+		ctx context.Context,
+		token string,
+		userID int64,
+	) (user models.User, err error)
+	DisableUser(
+		ctx context.Context,
+		token string,
+		userID int64,
+	) (success bool, err error)
+	EnableUser(
+		ctx context.Context,
+		token string,
+		userID int64,
+	) (success bool, err error)
+	SetAdmin(
+		ctx context.Context,
+		token string,
+		userID int64,
+		isAdmin bool,
+	) (success bool, err error)
+	UnlockUser(
+		ctx context.Context,
+		token string,
+		userID int64,
+	) (success bool, err error)
 }
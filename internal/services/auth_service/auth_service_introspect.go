@@ -0,0 +1,91 @@
+package auth_service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"sso/internal/domain/models"
+	"sso/storage"
+	"strconv"
+)
+
+// Introspect returns metadata of the token (RFC 7662), so resource servers
+// don't have to parse tokens themselves. Only registered apps may introspect
+// tokens. Invalid, expired and revoked tokens and tokens issued for another
+// audience (if audience is set) are reported as inactive.
+func (a *Auth) Introspect(
+	ctx context.Context,
+	clientID int,
+	clientSecret string,
+	token string,
+	audience string,
+) (models.TokenInfo, error) {
+	const op = "SERVICE LAYER: auth_service.Introspect"
+
+	ctx, span := tracer.Start(ctx, "service layer: introspect",
+		trace.WithAttributes(attribute.String("handler", "introspect")))
+	defer span.End()
+
+	log := a.log.With(
+		slog.String("info", op),
+		slog.Int("client-id", clientID),
+	)
+
+	ctx, _, err := a.authenticateApp(ctx, clientID, clientSecret)
+	if err != nil {
+		log.Info("app authentication failed", slog.String("error", err.Error()))
+		return models.TokenInfo{}, err
+	}
+
+	ctx, claims, err := a.validateToken(ctx, token)
+	if err != nil {
+		log.Info("token is inactive", slog.String("error", err.Error()))
+		return models.TokenInfo{Active: false}, nil
+	}
+	if err := checkAudience(claims, audience); err != nil {
+		log.Info("token is inactive", slog.String("error", err.Error()))
+		return models.TokenInfo{Active: false}, nil
+	}
+
+	info := models.TokenInfo{
+		Active:    true,
+		ExpiresAt: claimsExpiresAt(claims).Unix(),
+	}
+	if info.Subject, _ = claims["sub"].(string); info.Subject == "" {
+		// token issued before "sub" claim was introduced
+		info.Subject = strconv.FormatInt(claimsUserID(claims), 10)
+	}
+	info.Email, _ = claims["email"].(string)
+	info.TokenType, _ = claims["token_type"].(string)
+	info.Scope, _ = claims["scope"].(string)
+	info.ClientID, _ = claims["client_id"].(string)
+	if issuedAt, ok := claims["iat"].(float64); ok {
+		info.IssuedAt = int64(issuedAt)
+	}
+	return info, nil
+}
+
+// authenticateApp checks client credentials of the registered app.
+func (a *Auth) authenticateApp(
+	ctx context.Context,
+	clientID int,
+	clientSecret string,
+) (context.Context, models.App, error) {
+	ctx, app, err := a.appStorage.App(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return ctx, models.App{}, ErrInvalidClient
+		}
+		return ctx, models.App{}, fmt.Errorf("authenticateApp: %w", err)
+	}
+	secretHash := sha256.Sum256([]byte(clientSecret))
+	if subtle.ConstantTimeCompare(app.SecretHash, secretHash[:]) != 1 {
+		return ctx, models.App{}, ErrInvalidClient
+	}
+	return ctx, app, nil
+}
@@ -0,0 +1,172 @@
+package auth_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"sso/internal/domain/models"
+	jwtlib "sso/internal/lib/jwt"
+	"sso/storage"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LockoutError is returned by Login while the account or the client ip is
+// locked after too many failed attempts, it matches ErrTooManyAttempts.
+type LockoutError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockoutError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrTooManyAttempts, e.RetryAfter.Round(time.Second))
+}
+
+func (e *LockoutError) Is(target error) bool {
+	return target == ErrTooManyAttempts
+}
+
+// attemptKeys identify counters of failed logins, the account counter
+// is kept for unknown emails too, so locks don't disclose which accounts
+// exist. The ip counter is skipped if the client address is unknown.
+func (a *Auth) attemptKeys(ctx context.Context, tenantID int64, email string) (accountKey string, ipKey string) {
+	if tenantID == 0 {
+		tenantID = models.DefaultTenantID
+	}
+	accountKey = "account:" + strconv.FormatInt(tenantID, 10) + ":" + strings.ToLower(email)
+	if _, ip := a.clientInfo(ctx); ip != "" {
+		ipKey = "ip:" + ip
+	}
+	return accountKey, ipKey
+}
+
+// checkLockout returns LockoutError if any of keys is locked. Failures of
+// the storage don't block logins.
+func (a *Auth) checkLockout(ctx context.Context, keys ...string) (context.Context, error) {
+	if !a.cfg.Lockout.Enabled {
+		return ctx, nil
+	}
+	var retryAfter time.Duration
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		var lockedFor time.Duration
+		var err error
+		ctx, lockedFor, err = a.attemptStorage.LockedFor(ctx, key)
+		if err != nil {
+			a.log.Error("failed to check login lock", slog.String("error", err.Error()))
+			continue
+		}
+		retryAfter = max(retryAfter, lockedFor)
+	}
+	if retryAfter > 0 {
+		return ctx, &LockoutError{RetryAfter: retryAfter}
+	}
+	return ctx, nil
+}
+
+// registerFailure counts the failed login and locks keys which reached
+// their threshold. Every failure past the threshold doubles the lock.
+func (a *Auth) registerFailure(ctx context.Context, accountKey string, ipKey string) context.Context {
+	if !a.cfg.Lockout.Enabled {
+		return ctx
+	}
+	ctx = a.countFailure(ctx, accountKey, a.cfg.Lockout.AccountThreshold)
+	if ipKey != "" {
+		ctx = a.countFailure(ctx, ipKey, a.cfg.Lockout.IPThreshold)
+	}
+	return ctx
+}
+
+func (a *Auth) countFailure(ctx context.Context, key string, threshold int) context.Context {
+	ctx, failures, err := a.attemptStorage.RegisterFailure(ctx, key, a.cfg.Lockout.Window)
+	if err != nil {
+		a.log.Error("failed to register login failure", slog.String("error", err.Error()))
+		return ctx
+	}
+	if threshold <= 0 || failures < int64(threshold) {
+		return ctx
+	}
+	delay := lockoutDelay(a.cfg.Lockout.BaseDelay, a.cfg.Lockout.MaxDelay, failures-int64(threshold))
+	ctx, err = a.attemptStorage.Lock(ctx, key, delay)
+	if err != nil {
+		a.log.Error("failed to lock login", slog.String("error", err.Error()))
+		return ctx
+	}
+	a.securityEvent(ctx, "login_locked",
+		slog.String("key", key),
+		slog.Int64("failures", failures),
+		slog.Duration("duration", delay))
+	return ctx
+}
+
+// lockoutDelay returns base doubled exponent times, but no more than limit
+func lockoutDelay(base time.Duration, limit time.Duration, exponent int64) time.Duration {
+	delay := base
+	for i := int64(0); i < exponent && delay < limit; i++ {
+		delay *= 2
+	}
+	return min(delay, limit)
+}
+
+// resetFailures forgets failures of the account after a successful login.
+// Counter of the ip is kept, a valid password of one account shouldn't
+// unlock guessing of others.
+func (a *Auth) resetFailures(ctx context.Context, accountKey string) context.Context {
+	if !a.cfg.Lockout.Enabled {
+		return ctx
+	}
+	ctx, err := a.attemptStorage.ResetFailures(ctx, accountKey)
+	if err != nil {
+		a.log.Error("failed to reset login failures", slog.String("error", err.Error()))
+	}
+	return ctx
+}
+
+// UnlockUser lifts the lock of the account and forgets its failed logins.
+// Locks of client ips expire on their own.
+func (a *Auth) UnlockUser(
+	ctx context.Context,
+	token string,
+	userID int64,
+) (success bool, err error) {
+	const op = "SERVICE LAYER: auth_service.UnlockUser"
+
+	ctx, span := tracer.Start(ctx, "service layer: unlock user",
+		trace.WithAttributes(attribute.String("handler", "unlockUser")))
+	defer span.End()
+
+	log := a.log.With(
+		slog.String("info", op),
+		slog.Int64("user-id", userID),
+	)
+
+	ctx, claims, err := a.requireAdmin(ctx, token)
+	if err != nil {
+		log.Info("caller is not allowed to unlock the user", slog.String("error", err.Error()))
+		return false, err
+	}
+	tenantID := jwtlib.TenantID(claims)
+	ctx, user, err := a.userStorage.GetUser(ctx, tenantID, int(userID))
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return false, ErrUserNotFound
+		}
+		log.Error("failed to get user", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	accountKey, _ := a.attemptKeys(ctx, tenantID, user.Email)
+	ctx, err = a.attemptStorage.ResetFailures(ctx, accountKey)
+	if err != nil {
+		log.Error("failed to unlock user", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	a.securityEvent(ctx, "user_unlocked",
+		slog.Int64("user_id", userID),
+		slog.Int64("admin_id", claimsUserID(claims)))
+	return true, nil
+}
@@ -0,0 +1,70 @@
+package auth_service
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/grpc/metadata"
+	"log/slog"
+	"sso/internal/domain/models"
+	"sso/internal/lib/mailer"
+	"strconv"
+	"time"
+)
+
+// sendEmail renders template in the language of the client and queues the
+// email. Delivery happens in background, so only rendering and queueing
+// errors are returned.
+func (a *Auth) sendEmail(ctx context.Context, to string, template string, data any) error {
+	msg, err := a.templates.Render(template, acceptLanguage(ctx), data)
+	if err != nil {
+		return fmt.Errorf("sendEmail: %w", err)
+	}
+	msg.To = to
+	if err := a.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("sendEmail: %w", err)
+	}
+	return nil
+}
+
+// notifyNewLogin tells the user about sign-in, failure to notify never
+// fails the login.
+func (a *Auth) notifyNewLogin(ctx context.Context, user models.User) {
+	userAgent, ip := a.clientInfo(ctx)
+	err := a.sendEmail(ctx, user.Email, mailer.TemplateNewLogin, mailer.NewLoginData{
+		Email:     user.Email,
+		Time:      time.Now().UTC().Format(time.RFC1123),
+		IP:        ip,
+		UserAgent: userAgent,
+	})
+	if err != nil {
+		a.log.Error("failed to notify about new login",
+			slog.Int64("user-id", user.ID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// acceptLanguage returns Accept-Language of the client, grpc-gateway
+// forwards it with its prefix.
+func acceptLanguage(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("grpcgateway-accept-language"); len(values) > 0 {
+		return values[0]
+	}
+	if values := md.Get("accept-language"); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// throttleEmail reports whether email of the kind may be sent to the user
+// now. The first call within the interval sets the key, the rest are
+// throttled until it expires.
+func (a *Auth) throttleEmail(
+	ctx context.Context,
+	prefix string,
+	userID int64,
+	interval time.Duration,
+) (context.Context, bool, error) {
+	return a.tokenStorage.MarkTokenUsed(ctx, prefix+strconv.FormatInt(userID, 10), interval)
+}
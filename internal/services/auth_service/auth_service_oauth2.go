@@ -0,0 +1,241 @@
+package auth_service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"slices"
+	"sso/internal/domain/models"
+	jwtlib "sso/internal/lib/jwt"
+	"sso/storage"
+	"strconv"
+	"strings"
+)
+
+const (
+	responseTypeCode = "code"
+	// only S256 is supported, "plain" gives no protection if the code leaks
+	codeChallengeS256 = "S256"
+)
+
+// ValidateAuthorizationRequest checks authorization request before the
+// login page is shown. ErrInvalidClient and ErrInvalidRedirectURI mean the
+// user must not be redirected back, other errors may be reported to the app.
+func (a *Auth) ValidateAuthorizationRequest(
+	ctx context.Context,
+	req models.AuthorizationRequest,
+) (models.App, error) {
+	ctx, span := tracer.Start(ctx, "service layer: validate authorization request",
+		trace.WithAttributes(attribute.String("handler", "validateAuthorizationRequest")))
+	defer span.End()
+
+	ctx, app, err := a.appStorage.App(ctx, req.ClientID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return models.App{}, ErrInvalidClient
+		}
+		return models.App{}, fmt.Errorf("validateAuthorizationRequest: %w", err)
+	}
+	// exact match only, prefix or pattern matching allows open redirects
+	if !slices.Contains(app.RedirectURIs, req.RedirectURI) {
+		return models.App{}, ErrInvalidRedirectURI
+	}
+	if req.ResponseType != responseTypeCode {
+		return models.App{}, fmt.Errorf("%w: unsupported response type", ErrInvalidRequest)
+	}
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != codeChallengeS256 {
+		return models.App{}, fmt.Errorf("%w: S256 code challenge is required", ErrInvalidRequest)
+	}
+	if _, err := grantScopes(appScopes(app), strings.Fields(req.Scope)); err != nil {
+		return models.App{}, err
+	}
+	return app, nil
+}
+
+// Authorize checks credentials of the user, starts a session and returns
+// authorization code the app exchanges for tokens of that session.
+func (a *Auth) Authorize(
+	ctx context.Context,
+	req models.AuthorizationRequest,
+	email string,
+	password string,
+) (code string, err error) {
+	const op = "SERVICE LAYER: auth_service.Authorize"
+
+	ctx, span := tracer.Start(ctx, "service layer: authorize",
+		trace.WithAttributes(attribute.String("handler", "authorize")))
+	defer span.End()
+
+	log := a.log.With(
+		slog.String("info", op),
+		slog.Int("client-id", req.ClientID),
+	)
+
+	if _, err := a.ValidateAuthorizationRequest(ctx, req); err != nil {
+		return "", err
+	}
+	ctx, params, err := a.appTokenParams(ctx, req.ClientID, strings.Fields(req.Scope))
+	if err != nil {
+		return "", err
+	}
+	params.TenantID = req.TenantID
+	ctx, usrWithTokens, params, err := a.login(ctx, email, password, params)
+	if err != nil {
+		return "", err
+	}
+
+	code, err = randomCode()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	ctx, err = a.authCodeStorage.SaveAuthCode(ctx, code, models.AuthCode{
+		ClientID:            req.ClientID,
+		RedirectURI:         req.RedirectURI,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		Nonce:               req.Nonce,
+		Scope:               params.Scope,
+		UserID:              usrWithTokens.user.ID,
+		TenantID:            usrWithTokens.user.TenantID,
+		SessionID:           params.SessionID,
+	}, a.cfg.OIDC.AuthCodeTtl)
+	if err != nil {
+		log.Error("failed to save authorization code", slog.String("error", err.Error()))
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("authorization code issued")
+	return code, nil
+}
+
+// ExchangeAuthorizationCode issues tokens for the authorization code. The
+// code is single use, the app proves it started the flow with PKCE code
+// verifier, so public clients (browser apps) may omit client secret.
+func (a *Auth) ExchangeAuthorizationCode(
+	ctx context.Context,
+	clientID int,
+	clientSecret string,
+	code string,
+	redirectURI string,
+	codeVerifier string,
+) (accessToken string, refreshToken string, idToken string, err error) {
+	const op = "SERVICE LAYER: auth_service.ExchangeAuthorizationCode"
+
+	ctx, span := tracer.Start(ctx, "service layer: exchange authorization code",
+		trace.WithAttributes(attribute.String("handler", "exchangeAuthorizationCode")))
+	defer span.End()
+
+	log := a.log.With(
+		slog.String("info", op),
+		slog.Int("client-id", clientID),
+	)
+
+	if clientSecret != "" {
+		if ctx, _, err = a.authenticateApp(ctx, clientID, clientSecret); err != nil {
+			return "", "", "", err
+		}
+	}
+
+	ctx, authCode, err := a.authCodeStorage.TakeAuthCode(ctx, code)
+	if err != nil {
+		if errors.Is(err, storage.ErrAuthCodeNotFound) {
+			return "", "", "", ErrInvalidGrant
+		}
+		log.Error("failed to get authorization code", slog.String("error", err.Error()))
+		return "", "", "", fmt.Errorf("%s: %w", op, err)
+	}
+	if authCode.ClientID != clientID || authCode.RedirectURI != redirectURI {
+		return "", "", "", ErrInvalidGrant
+	}
+	if !verifyCodeChallenge(authCode.CodeChallenge, codeVerifier) {
+		a.securityEvent(ctx, "pkce_verification_failed",
+			slog.Int("client_id", clientID),
+			slog.Int64("user_id", authCode.UserID),
+		)
+		return "", "", "", ErrInvalidGrant
+	}
+
+	// tokens are issued to the app, so the app is their audience
+	params := jwtlib.TokenParams{
+		SessionID: authCode.SessionID,
+		Nonce:     authCode.Nonce,
+		Audience:  strconv.Itoa(clientID),
+		ClientID:  strconv.Itoa(clientID),
+		Scope:     authCode.Scope,
+		TenantID:  authCode.TenantID,
+	}
+	ctx, usrWithTokens, err := a.generateRefreshAccessToken(ctx, int(authCode.UserID), params)
+	if err != nil {
+		log.Error("failed to generate tokens", slog.String("error", err.Error()))
+		return "", "", "", fmt.Errorf("%s: %w", op, err)
+	}
+	// the user may have been disabled after the code was issued
+	if usrWithTokens.user.Disabled {
+		return "", "", "", ErrInvalidGrant
+	}
+	idToken, err = a.newIDToken(*usrWithTokens.user, params)
+	if err != nil {
+		log.Error("failed to generate id token", slog.String("error", err.Error()))
+		return "", "", "", fmt.Errorf("%s: %w", op, err)
+	}
+	return usrWithTokens.accessToken, usrWithTokens.refreshToken, idToken, nil
+}
+
+// verifyCodeChallenge checks PKCE code verifier against S256 challenge (RFC 7636 4.6).
+func verifyCodeChallenge(challenge string, verifier string) bool {
+	// RFC 7636 4.1: verifier is 43-128 characters long
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+// randomCode returns unguessable url safe authorization code.
+func randomCode() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// RefreshAppTokens is the refresh_token grant of the token endpoint. The
+// refresh token must have been issued to the client, confidential clients
+// authenticate with client secret as in the other grants.
+func (a *Auth) RefreshAppTokens(
+	ctx context.Context,
+	clientID int,
+	clientSecret string,
+	refreshToken string,
+	scopes []string,
+) (accessToken string, newRefreshToken string, err error) {
+	const op = "SERVICE LAYER: auth_service.RefreshAppTokens"
+
+	ctx, span := tracer.Start(ctx, "service layer: refresh app tokens",
+		trace.WithAttributes(attribute.String("handler", "refreshAppTokens")))
+	defer span.End()
+
+	log := a.log.With(
+		slog.String("info", op),
+		slog.Int("client-id", clientID),
+	)
+	if clientID == 0 {
+		return "", "", ErrInvalidClient
+	}
+	if clientSecret != "" {
+		if ctx, _, err = a.authenticateApp(ctx, clientID, clientSecret); err != nil {
+			log.Info("client authentication failed", slog.String("error", err.Error()))
+			return "", "", err
+		}
+	}
+	// non zero app id binds the refresh token to the client
+	return a.Refresh(ctx, refreshToken, clientID, scopes)
+}
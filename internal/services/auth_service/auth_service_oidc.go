@@ -0,0 +1,82 @@
+package auth_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"sso/internal/domain/models"
+	jwtlib "sso/internal/lib/jwt"
+	"sso/storage"
+	"strings"
+	"time"
+)
+
+// OpenIDConfiguration returns discovery document, so OpenID Connect client
+// libraries can find endpoints and keys of the service by its issuer url.
+func (a *Auth) OpenIDConfiguration(ctx context.Context) (models.ProviderMetadata, error) {
+	_, span := tracer.Start(ctx, "service layer: openid configuration",
+		trace.WithAttributes(attribute.String("handler", "openidConfiguration")))
+	defer span.End()
+
+	issuer := strings.TrimSuffix(a.cfg.OIDC.Issuer, "/")
+	return models.ProviderMetadata{
+		Issuer:                           a.cfg.OIDC.Issuer,
+		AuthorizationEndpoint:            issuer + "/oauth2/authorize",
+		TokenEndpoint:                    issuer + "/oauth2/token",
+		UserinfoEndpoint:                 issuer + "/userinfo",
+		JwksURI:                          issuer + "/.well-known/jwks.json",
+		IntrospectionEndpoint:            issuer + "/oauth2/introspect",
+		ResponseTypesSupported:           []string{responseTypeCode},
+		GrantTypesSupported:              []string{"authorization_code", "refresh_token", "client_credentials"},
+		SubjectTypesSupported:            []string{"public"},
+		IDTokenSigningAlgValuesSupported: a.keyRing.Algorithms(time.Now()),
+		ScopesSupported:                  []string{"openid", "email"},
+		ClaimsSupported:                  []string{"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce", "sid", "email", "email_verified"},
+		CodeChallengeMethodsSupported:    []string{codeChallengeS256},
+	}, nil
+}
+
+// UserInfo returns claims about the user the access token was issued to.
+func (a *Auth) UserInfo(ctx context.Context, token string) (models.User, error) {
+	const op = "SERVICE LAYER: auth_service.UserInfo"
+
+	ctx, span := tracer.Start(ctx, "service layer: userinfo",
+		trace.WithAttributes(attribute.String("handler", "userinfo")))
+	defer span.End()
+
+	log := a.log.With(
+		slog.String("info", op),
+	)
+
+	ctx, claims, err := a.validateUserAccessToken(ctx, token)
+	if err != nil {
+		log.Info("failed validate token", slog.String("error", err.Error()))
+		return models.User{}, err
+	}
+
+	ctx, user, err := a.userStorage.GetUser(ctx, jwtlib.TenantID(claims), int(claimsUserID(claims)))
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.User{}, ErrUserNotFound
+		}
+		log.Error("failed to get user", slog.String("error", err.Error()))
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return user, nil
+}
+
+// newIDToken signs OpenID Connect ID token with the active key.
+func (a *Auth) newIDToken(user models.User, params jwtlib.TokenParams) (string, error) {
+	signingKey, err := a.keyRing.SigningKey(time.Now())
+	if err != nil {
+		return "", fmt.Errorf("signing key selection failed: %w", err)
+	}
+	idToken, err := jwtlib.NewIDToken(user, a.cfg, signingKey, params)
+	if err != nil {
+		return "", fmt.Errorf("idToken generation failed: %w", err)
+	}
+	return idToken, nil
+}
@@ -0,0 +1,205 @@
+package auth_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"sso/internal/domain/models"
+	jwtlib "sso/internal/lib/jwt"
+	"sso/storage"
+)
+
+// CreateOrganization creates organization (tenant), the admin creating it
+// becomes its owner. Only admins may create organizations.
+func (a *Auth) CreateOrganization(
+	ctx context.Context,
+	token string,
+	name string,
+	slug string,
+) (orgID int64, err error) {
+	const op = "SERVICE LAYER: auth_service.CreateOrganization"
+
+	ctx, span := tracer.Start(ctx, "service layer: create organization",
+		trace.WithAttributes(attribute.String("handler", "createOrganization")))
+	defer span.End()
+
+	log := a.log.With(
+		slog.String("info", op),
+		slog.String("slug", slug),
+	)
+
+	ctx, claims, err := a.requireAdmin(ctx, token)
+	if err != nil {
+		log.Info("caller is not allowed to create organizations", slog.String("error", err.Error()))
+		return 0, err
+	}
+	ctx, orgID, err = a.orgStorage.SaveOrganization(ctx, models.Organization{Name: name, Slug: slug})
+	if err != nil {
+		if errors.Is(err, storage.ErrOrgExists) {
+			return 0, ErrOrgExists
+		}
+		log.Error("failed to save organization", slog.String("error", err.Error()))
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	ctx, err = a.orgStorage.SaveMember(ctx, orgID, claimsUserID(claims), models.OrgRoleOwner)
+	if err != nil {
+		log.Error("failed to save owner", slog.String("error", err.Error()))
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("organization created", slog.Int64("org-id", orgID))
+	return orgID, nil
+}
+
+// AddOrganizationMember adds user to organization or changes their role.
+// Owners and admins of the organization and global admins may do it,
+// only owners and global admins may appoint owners.
+func (a *Auth) AddOrganizationMember(
+	ctx context.Context,
+	token string,
+	orgID int64,
+	userID int64,
+	role string,
+) (success bool, err error) {
+	const op = "SERVICE LAYER: auth_service.AddOrganizationMember"
+
+	ctx, span := tracer.Start(ctx, "service layer: add organization member",
+		trace.WithAttributes(attribute.String("handler", "addOrganizationMember")))
+	defer span.End()
+
+	log := a.log.With(
+		slog.String("info", op),
+		slog.Int64("org-id", orgID),
+		slog.Int64("user-id", userID),
+	)
+
+	if !models.ValidOrgRole(role) {
+		return false, fmt.Errorf("%w: %s", ErrInvalidOrgRole, role)
+	}
+	ctx, callerRole, err := a.authorizeOrgManagement(ctx, token, orgID)
+	if err != nil {
+		log.Info("caller is not allowed to manage members", slog.String("error", err.Error()))
+		return false, err
+	}
+	if role == models.OrgRoleOwner && callerRole != models.OrgRoleOwner {
+		return false, ErrPermissionDenied
+	}
+	ctx, err = a.orgStorage.SaveMember(ctx, orgID, userID, role)
+	if err != nil {
+		if errors.Is(err, storage.ErrOrgNotFound) {
+			return false, ErrOrgNotFound
+		}
+		log.Error("failed to save member", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return true, nil
+}
+
+// RemoveOrganizationMember removes user from organization. Members may
+// leave organizations, but nobody can leave their own tenant.
+func (a *Auth) RemoveOrganizationMember(
+	ctx context.Context,
+	token string,
+	orgID int64,
+	userID int64,
+) (success bool, err error) {
+	const op = "SERVICE LAYER: auth_service.RemoveOrganizationMember"
+
+	ctx, span := tracer.Start(ctx, "service layer: remove organization member",
+		trace.WithAttributes(attribute.String("handler", "removeOrganizationMember")))
+	defer span.End()
+
+	log := a.log.With(
+		slog.String("info", op),
+		slog.Int64("org-id", orgID),
+		slog.Int64("user-id", userID),
+	)
+
+	ctx, claims, err := a.validateUserAccessToken(ctx, token)
+	if err != nil {
+		return false, err
+	}
+	if claimsUserID(claims) != userID {
+		var callerRole string
+		ctx, callerRole, err = a.authorizeOrgManagement(ctx, token, orgID)
+		if err != nil {
+			log.Info("caller is not allowed to manage members", slog.String("error", err.Error()))
+			return false, err
+		}
+		var memberRole string
+		ctx, memberRole, err = a.orgStorage.MemberRole(ctx, orgID, userID)
+		if err != nil {
+			if errors.Is(err, storage.ErrMemberNotFound) {
+				return false, ErrMemberNotFound
+			}
+			return false, fmt.Errorf("%s: %w", op, err)
+		}
+		if memberRole == models.OrgRoleOwner && callerRole != models.OrgRoleOwner {
+			return false, ErrPermissionDenied
+		}
+	} else if jwtlib.TenantID(claims) == orgID {
+		return false, ErrPermissionDenied
+	}
+
+	ctx, err = a.orgStorage.DeleteMember(ctx, orgID, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrMemberNotFound) {
+			return false, ErrMemberNotFound
+		}
+		log.Error("failed to delete member", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return true, nil
+}
+
+// ListOrganizations returns organizations the caller is a member of.
+func (a *Auth) ListOrganizations(
+	ctx context.Context,
+	token string,
+) (memberships []models.OrgMembership, err error) {
+	const op = "SERVICE LAYER: auth_service.ListOrganizations"
+
+	ctx, span := tracer.Start(ctx, "service layer: list organizations",
+		trace.WithAttributes(attribute.String("handler", "listOrganizations")))
+	defer span.End()
+
+	ctx, claims, err := a.validateUserAccessToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	ctx, memberships, err = a.orgStorage.UserOrganizations(ctx, claimsUserID(claims))
+	if err != nil {
+		a.log.Error("failed to list organizations", slog.String("info", op), slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return memberships, nil
+}
+
+// authorizeOrgManagement checks that caller may manage members of the
+// organization, global admins act as owners.
+func (a *Auth) authorizeOrgManagement(ctx context.Context, token string, orgID int64) (context.Context, string, error) {
+	ctx, claims, err := a.validateUserAccessToken(ctx, token)
+	if err != nil {
+		return ctx, "", err
+	}
+	ctx, callerRole, err := a.orgStorage.MemberRole(ctx, orgID, claimsUserID(claims))
+	if err == nil && models.CanManageMembers(callerRole) {
+		return ctx, callerRole, nil
+	}
+	if err != nil && !errors.Is(err, storage.ErrMemberNotFound) {
+		return ctx, "", fmt.Errorf("authorizeOrgManagement: %w", err)
+	}
+	if ctx, _, err = a.requireAdmin(ctx, token); err != nil {
+		return ctx, "", err
+	}
+	ctx, _, err = a.orgStorage.Organization(ctx, orgID)
+	if err != nil {
+		if errors.Is(err, storage.ErrOrgNotFound) {
+			return ctx, "", ErrOrgNotFound
+		}
+		return ctx, "", fmt.Errorf("authorizeOrgManagement: %w", err)
+	}
+	return ctx, models.OrgRoleOwner, nil
+}
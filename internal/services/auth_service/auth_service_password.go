@@ -0,0 +1,283 @@
+package auth_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/crypto/bcrypt"
+	"log/slog"
+	"net/url"
+	"sso/internal/domain/models"
+	jwtlib "sso/internal/lib/jwt"
+	"sso/internal/lib/mailer"
+	"sso/internal/lib/passpolicy"
+	"sso/storage"
+	"strings"
+	"time"
+)
+
+const (
+	// resetThrottlePrefix keys throttling of password reset emails.
+	resetThrottlePrefix = "password_reset_request:"
+)
+
+// RequestPasswordReset emails reset link to the account. It succeeds for
+// unknown emails and throttled requests alike, so the call tells nothing
+// about which emails are registered.
+func (a *Auth) RequestPasswordReset(
+	ctx context.Context,
+	email string,
+	tenantID int64,
+) (success bool, err error) {
+	const op = "SERVICE LAYER: auth_service.RequestPasswordReset"
+
+	ctx, span := tracer.Start(ctx, "service layer: request password reset",
+		trace.WithAttributes(attribute.String("handler", "requestPasswordReset")))
+	defer span.End()
+
+	log := a.log.With(slog.String("info", op))
+
+	if tenantID == 0 {
+		tenantID = models.DefaultTenantID
+	}
+	ctx, user, err := a.userStorage.GetUser(ctx, tenantID, email)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return true, nil
+		}
+		log.Error("failed to get user", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	ctx, first, err := a.throttleEmail(ctx, resetThrottlePrefix, user.ID, a.cfg.PasswordReset.RequestInterval)
+	if err != nil {
+		log.Error("failed to throttle password reset", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	if !first {
+		log.Info("password reset throttled", slog.Int64("user-id", user.ID))
+		return true, nil
+	}
+
+	signingKey, err := a.keyRing.SigningKey(time.Now())
+	if err != nil {
+		log.Error("failed to get signing key", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	token, err := jwtlib.NewPasswordResetToken(user, a.cfg, signingKey, a.cfg.PasswordReset.TokenTtl)
+	if err != nil {
+		log.Error("failed to generate reset token", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	err = a.sendEmail(ctx, user.Email, mailer.TemplatePasswordReset, mailer.LinkData{
+		Email:    user.Email,
+		Link:     a.cfg.PasswordReset.PageUrl + "?token=" + url.QueryEscape(token),
+		ValidFor: a.cfg.PasswordReset.TokenTtl.String(),
+	})
+	if err != nil {
+		log.Error("failed to send reset email", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	a.securityEvent(ctx, "password_reset_requested", slog.Int64("user_id", user.ID))
+	return true, nil
+}
+
+// ResetPassword sets new password of the user by token of the reset link
+// and signs them out everywhere, whoever knew the old password loses access.
+func (a *Auth) ResetPassword(
+	ctx context.Context,
+	token string,
+	newPassword string,
+) (success bool, err error) {
+	const op = "SERVICE LAYER: auth_service.ResetPassword"
+
+	ctx, span := tracer.Start(ctx, "service layer: reset password",
+		trace.WithAttributes(attribute.String("handler", "resetPassword")))
+	defer span.End()
+
+	log := a.log.With(slog.String("info", op))
+
+	// checked before the token is burnt, so the user may retry the link
+	if err = a.checkPassword(newPassword, actionTokenEmail(token)); err != nil {
+		return false, err
+	}
+	ctx, user, claims, err := a.useActionToken(ctx, token, jwtlib.ActionPasswordReset)
+	if err != nil {
+		log.Info("reset token rejected", slog.String("error", err.Error()))
+		return false, err
+	}
+	// the password has changed since the link was sent
+	if claims["pwd"] != jwtlib.PasswordFingerprint(user.PassHash) {
+		log.Info("reset token rejected", slog.String("error", "password changed"))
+		return false, ErrInvalidActionToken
+	}
+	ctx, err = a.setPassword(ctx, user.TenantID, user.ID, newPassword)
+	if err != nil {
+		log.Error("failed to set password", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	// the link came by email, so the user owns it
+	if !user.EmailVerified {
+		if ctx, err = a.userStorage.SetEmailVerified(ctx, user.TenantID, user.ID); err != nil {
+			log.Error("failed to verify email", slog.String("error", err.Error()))
+		}
+	}
+	ctx, err = a.revokeAllSessions(ctx, int(user.ID))
+	if err != nil {
+		log.Error("failed to revoke sessions", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	ctx, err = a.sessionStorage.DeleteUserSessions(ctx, user.ID)
+	if err != nil {
+		log.Error("failed to delete sessions", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	a.securityEvent(ctx, "password_reset", slog.Int64("user_id", user.ID))
+	return true, nil
+}
+
+// ChangePassword replaces password of the caller after checking the old
+// one. Other sessions are signed out, the current one survives only when
+// keepCurrentSession is set.
+func (a *Auth) ChangePassword(
+	ctx context.Context,
+	token string,
+	oldPassword string,
+	newPassword string,
+	keepCurrentSession bool,
+) (success bool, err error) {
+	const op = "SERVICE LAYER: auth_service.ChangePassword"
+
+	ctx, span := tracer.Start(ctx, "service layer: change password",
+		trace.WithAttributes(attribute.String("handler", "changePassword")))
+	defer span.End()
+
+	log := a.log.With(slog.String("info", op))
+
+	ctx, claims, err := a.validateUserAccessToken(ctx, token)
+	if err != nil {
+		log.Info("failed validate token", slog.String("error", err.Error()))
+		return false, err
+	}
+	userID := claimsUserID(claims)
+	log = log.With(slog.Int64("user-id", userID))
+
+	ctx, user, err := a.userStorage.GetUser(ctx, jwtlib.TenantID(claims), int(userID))
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return false, ErrUserNotFound
+		}
+		log.Error("failed to get user", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	if err = bcrypt.CompareHashAndPassword(user.PassHash, []byte(oldPassword)); err != nil {
+		log.Info("old password mismatch")
+		a.securityEvent(ctx, "password_change_failed", slog.Int64("user_id", userID))
+		return false, ErrInvalidCredentials
+	}
+	if oldPassword == newPassword {
+		return false, ErrSamePassword
+	}
+	if err = a.checkPassword(newPassword, user.Email); err != nil {
+		return false, err
+	}
+	ctx, err = a.setPassword(ctx, user.TenantID, userID, newPassword)
+	if err != nil {
+		log.Error("failed to set password", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	currentSessionID, _ := claims["sid"].(string)
+	if keepCurrentSession && currentSessionID != "" {
+		ctx, err = a.revokeOtherSessions(ctx, userID, currentSessionID)
+	} else {
+		ctx, err = a.revokeAllSessions(ctx, int(userID))
+		if err == nil {
+			ctx, err = a.sessionStorage.DeleteUserSessions(ctx, userID)
+		}
+	}
+	if err != nil {
+		log.Error("failed to revoke sessions", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	a.securityEvent(ctx, "password_changed",
+		slog.Int64("user_id", userID),
+		slog.Bool("kept_current_session", keepCurrentSession))
+	return true, nil
+}
+
+// revokeOtherSessions revokes every registered session of the user but
+// the given one. The iat watermark can't be used here, it would revoke
+// the kept session as well, tokens without session are revoked by their
+// own watermark.
+func (a *Auth) revokeOtherSessions(ctx context.Context, userID int64, keepSessionID string) (context.Context, error) {
+	revokedBefore := time.Now().Truncate(time.Second).Add(time.Second)
+	ctx, err := a.tokenStorage.SetSessionlessRevokedBefore(ctx, userID, revokedBefore, a.cfg.RefreshTokenTtl)
+	if err != nil {
+		return ctx, err
+	}
+	ctx, sessions, err := a.sessionStorage.ListSessions(ctx, userID)
+	if err != nil {
+		return ctx, err
+	}
+	for _, session := range sessions {
+		if session.ID == keepSessionID {
+			continue
+		}
+		if ctx, err = a.revokeSession(ctx, session.ID, userID); err != nil {
+			return ctx, err
+		}
+	}
+	return ctx, nil
+}
+
+// PasswordPolicyError lists rules the new password breaks, it matches
+// ErrWeakPassword.
+type PasswordPolicyError struct {
+	Violations []passpolicy.Violation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	descriptions := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		descriptions = append(descriptions, violation.Description)
+	}
+	return fmt.Sprintf("%s: %s", ErrWeakPassword, strings.Join(descriptions, ", "))
+}
+
+func (e *PasswordPolicyError) Is(target error) bool {
+	return target == ErrWeakPassword
+}
+
+// checkPassword applies password policy to the new password of the
+// account with email.
+func (a *Auth) checkPassword(password string, email string) error {
+	if violations := a.passwordPolicy.Check(password, email); len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// actionTokenEmail returns email the action token was issued to without
+// verifying the token, it is used only to check the new password before
+// the token is verified and burnt.
+func actionTokenEmail(token string) string {
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		return ""
+	}
+	claims, _ := parsed.Claims.(jwt.MapClaims)
+	email, _ := claims["email"].(string)
+	return email
+}
+
+// setPassword hashes and stores new password of the user.
+func (a *Auth) setPassword(ctx context.Context, tenantID int64, userID int64, password string) (context.Context, error) {
+	passHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return ctx, fmt.Errorf("setPassword: %w", err)
+	}
+	return a.userStorage.UpdatePassword(ctx, tenantID, userID, passHash)
+}
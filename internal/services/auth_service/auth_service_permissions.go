@@ -0,0 +1,151 @@
+package auth_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"sso/internal/domain/models"
+	jwtlib "sso/internal/lib/jwt"
+	"sso/storage"
+	"time"
+)
+
+// CheckPermission reports whether roles bound to the user allow action on
+// resource. Unknown users have no roles, so nothing is allowed to them.
+func (a *Auth) CheckPermission(
+	ctx context.Context,
+	token string,
+	userID int,
+	action string,
+	resource string,
+) (allowed bool, err error) {
+	ctx, span := tracer.Start(ctx, "service layer: check permission",
+		trace.WithAttributes(attribute.String("handler", "checkPermission")))
+	defer span.End()
+
+	decisions, err := a.CheckPermissions(ctx, token, []models.PermissionCheck{{
+		UserID:   int64(userID),
+		Action:   action,
+		Resource: resource,
+	}})
+	if err != nil {
+		return false, err
+	}
+	return decisions[0], nil
+}
+
+// CheckPermissions evaluates many checks at once, decisions are returned in
+// order of checks. Roles of every user are loaded at most once. Apps and
+// admins check users of their tenant, other users check only themselves.
+func (a *Auth) CheckPermissions(
+	ctx context.Context,
+	token string,
+	checks []models.PermissionCheck,
+) (decisions []bool, err error) {
+	const op = "SERVICE LAYER: auth_service.CheckPermissions"
+
+	ctx, span := tracer.Start(ctx, "service layer: check permissions",
+		trace.WithAttributes(attribute.String("handler", "checkPermissions")))
+	defer span.End()
+
+	log := a.log.With(
+		slog.String("info", op),
+		slog.Int("checks", len(checks)),
+	)
+
+	ctx, tenantID, err := a.requirePermissionChecker(ctx, token, checks)
+	if err != nil {
+		log.Info("caller is not allowed to check permissions", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	now := time.Now()
+	decisions = make([]bool, len(checks))
+	permissionsByUser := make(map[int64][]models.Permission)
+	for i, check := range checks {
+		if allowed, ok := a.decisions.Get(tenantID, check.UserID, check.Action, check.Resource, now); ok {
+			decisions[i] = allowed
+			continue
+		}
+		permissions, ok := permissionsByUser[check.UserID]
+		if !ok {
+			ctx, permissions, err = a.tenantUserPermissions(ctx, tenantID, check.UserID)
+			if err != nil {
+				log.Error("failed to get roles", slog.String("error", err.Error()))
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+			permissionsByUser[check.UserID] = permissions
+		}
+		decisions[i] = allows(permissions, check.Action, check.Resource)
+		a.decisions.Set(tenantID, check.UserID, check.Action, check.Resource, decisions[i], now)
+	}
+	return decisions, nil
+}
+
+// requirePermissionChecker allows access tokens of apps and users, users
+// who aren't admins may check only themselves. Returns tenant of the caller.
+func (a *Auth) requirePermissionChecker(
+	ctx context.Context,
+	token string,
+	checks []models.PermissionCheck,
+) (context.Context, int64, error) {
+	ctx, claims, err := a.validateToken(ctx, token)
+	if err != nil {
+		return ctx, 0, err
+	}
+	if claims["token_type"] != "access" {
+		return ctx, 0, ErrTokenWrongType
+	}
+	tenantID := jwtlib.TenantID(claims)
+	if jwtlib.IsClientToken(claims) {
+		return ctx, tenantID, nil
+	}
+	callerID := claimsUserID(claims)
+	for _, check := range checks {
+		if check.UserID != callerID {
+			ctx, _, err = a.requireAdmin(ctx, token)
+			if err != nil {
+				return ctx, 0, err
+			}
+			break
+		}
+	}
+	return ctx, tenantID, nil
+}
+
+// tenantUserPermissions returns permissions of the user, users of other
+// tenants have none.
+func (a *Auth) tenantUserPermissions(
+	ctx context.Context,
+	tenantID int64,
+	userID int64,
+) (context.Context, []models.Permission, error) {
+	ctx, _, err := a.userStorage.GetUser(ctx, tenantID, int(userID))
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return ctx, nil, nil
+		}
+		return ctx, nil, err
+	}
+	ctx, roles, err := a.roleStorage.UserRoles(ctx, tenantID, userID)
+	if err != nil {
+		return ctx, nil, err
+	}
+	var permissions []models.Permission
+	for _, role := range roles {
+		permissions = append(permissions, role.Permissions...)
+	}
+	return ctx, permissions, nil
+}
+
+func allows(permissions []models.Permission, action string, resource string) bool {
+	for _, permission := range permissions {
+		if permission.Allows(action, resource) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,229 @@
+package auth_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"slices"
+	"sso/internal/domain/models"
+	jwtlib "sso/internal/lib/jwt"
+	"sso/storage"
+)
+
+// CreateRole creates role with given permissions in "action:resource"
+// format, "*" matches any action or resource. Only admins may create roles,
+// the role belongs to their tenant.
+func (a *Auth) CreateRole(
+	ctx context.Context,
+	token string,
+	name string,
+	description string,
+	permissions []string,
+) (roleID int64, err error) {
+	const op = "SERVICE LAYER: auth_service.CreateRole"
+
+	ctx, span := tracer.Start(ctx, "service layer: create role",
+		trace.WithAttributes(attribute.String("handler", "createRole")))
+	defer span.End()
+
+	log := a.log.With(
+		slog.String("info", op),
+		slog.String("role", name),
+	)
+
+	ctx, claims, err := a.requireAdmin(ctx, token)
+	if err != nil {
+		log.Info("caller is not allowed to create roles", slog.String("error", err.Error()))
+		return 0, err
+	}
+	role := models.Role{
+		Name:        name,
+		Description: description,
+		Permissions: make([]models.Permission, 0, len(permissions)),
+	}
+	for _, value := range permissions {
+		permission, ok := models.ParsePermission(value)
+		if !ok {
+			return 0, fmt.Errorf("%w: %s", ErrInvalidPermission, value)
+		}
+		role.Permissions = append(role.Permissions, permission)
+	}
+
+	ctx, roleID, err = a.roleStorage.SaveRole(ctx, jwtlib.TenantID(claims), role)
+	if err != nil {
+		if errors.Is(err, storage.ErrRoleExists) {
+			return 0, ErrRoleExists
+		}
+		log.Error("failed to save role", slog.String("error", err.Error()))
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("role created", slog.Int64("role-id", roleID))
+	return roleID, nil
+}
+
+// GrantRole binds role to the user. Tokens issued earlier keep old claims
+// until they are refreshed.
+func (a *Auth) GrantRole(
+	ctx context.Context,
+	token string,
+	userID int,
+	roleName string,
+) (success bool, err error) {
+	const op = "SERVICE LAYER: auth_service.GrantRole"
+
+	ctx, span := tracer.Start(ctx, "service layer: grant role",
+		trace.WithAttributes(attribute.String("handler", "grantRole")))
+	defer span.End()
+
+	log := a.log.With(
+		slog.String("info", op),
+		slog.Int("user-id", userID),
+		slog.String("role", roleName),
+	)
+
+	ctx, claims, err := a.requireAdmin(ctx, token)
+	if err != nil {
+		log.Info("caller is not allowed to grant roles", slog.String("error", err.Error()))
+		return false, err
+	}
+	// admins manage users of their own tenant only
+	ctx, _, err = a.userStorage.GetUser(ctx, jwtlib.TenantID(claims), userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return false, ErrUserNotFound
+		}
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	ctx, err = a.roleStorage.GrantRole(ctx, jwtlib.TenantID(claims), int64(userID), roleName)
+	if err != nil {
+		if errors.Is(err, storage.ErrRoleNotFound) {
+			return false, ErrRoleNotFound
+		}
+		log.Error("failed to grant role", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	a.decisions.InvalidateUser(int64(userID))
+	a.securityEvent(ctx, "role_granted", slog.Int("user_id", userID), slog.String("role", roleName))
+	return true, nil
+}
+
+// RevokeRole unbinds role from the user.
+func (a *Auth) RevokeRole(
+	ctx context.Context,
+	token string,
+	userID int,
+	roleName string,
+) (success bool, err error) {
+	const op = "SERVICE LAYER: auth_service.RevokeRole"
+
+	ctx, span := tracer.Start(ctx, "service layer: revoke role",
+		trace.WithAttributes(attribute.String("handler", "revokeRole")))
+	defer span.End()
+
+	log := a.log.With(
+		slog.String("info", op),
+		slog.Int("user-id", userID),
+		slog.String("role", roleName),
+	)
+
+	ctx, claims, err := a.requireAdmin(ctx, token)
+	if err != nil {
+		log.Info("caller is not allowed to revoke roles", slog.String("error", err.Error()))
+		return false, err
+	}
+	// admins manage users of their own tenant only
+	ctx, _, err = a.userStorage.GetUser(ctx, jwtlib.TenantID(claims), userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return false, ErrUserNotFound
+		}
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	ctx, err = a.roleStorage.RevokeRole(ctx, jwtlib.TenantID(claims), int64(userID), roleName)
+	if err != nil {
+		if errors.Is(err, storage.ErrRoleNotFound) {
+			return false, ErrRoleNotFound
+		}
+		log.Error("failed to revoke role", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	a.decisions.InvalidateUser(int64(userID))
+	a.securityEvent(ctx, "role_revoked", slog.Int("user_id", userID), slog.String("role", roleName))
+	return true, nil
+}
+
+// UserPermissions returns roles of the user and effective permissions
+// granted by them. Users may query themselves, admins anyone.
+func (a *Auth) UserPermissions(
+	ctx context.Context,
+	token string,
+	userID int,
+) (roles []string, permissions []string, err error) {
+	const op = "SERVICE LAYER: auth_service.UserPermissions"
+
+	ctx, span := tracer.Start(ctx, "service layer: user permissions",
+		trace.WithAttributes(attribute.String("handler", "userPermissions")))
+	defer span.End()
+
+	log := a.log.With(
+		slog.String("info", op),
+		slog.Int("user-id", userID),
+	)
+
+	ctx, claims, err := a.validateUserAccessToken(ctx, token)
+	if err != nil {
+		log.Info("failed validate token", slog.String("error", err.Error()))
+		return nil, nil, err
+	}
+	ctx, err = a.authorizeUserAccess(ctx, claims, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, userRoles, err := a.roleStorage.UserRoles(ctx, jwtlib.TenantID(claims), int64(userID))
+	if err != nil {
+		log.Error("failed to get roles", slog.String("error", err.Error()))
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return roleNames(userRoles), effectivePermissions(userRoles), nil
+}
+
+// requireAdmin checks that token is an access token of an admin, returns
+// its claims.
+func (a *Auth) requireAdmin(ctx context.Context, token string) (context.Context, jwt.MapClaims, error) {
+	ctx, claims, err := a.validateUserAccessToken(ctx, token)
+	if err != nil {
+		return ctx, nil, err
+	}
+	ctx, caller, err := a.userStorage.GetUser(ctx, jwtlib.TenantID(claims), int(claimsUserID(claims)))
+	if err != nil {
+		return ctx, nil, fmt.Errorf("requireAdmin: %w", err)
+	}
+	if !caller.IsUserAmin() {
+		return ctx, nil, ErrPermissionDenied
+	}
+	return ctx, claims, nil
+}
+
+func roleNames(roles []models.Role) []string {
+	names := make([]string, 0, len(roles))
+	for _, role := range roles {
+		names = append(names, role.Name)
+	}
+	return names
+}
+
+// effectivePermissions returns sorted union of permissions of all roles.
+func effectivePermissions(roles []models.Role) []string {
+	permissions := make([]string, 0)
+	for _, role := range roles {
+		for _, permission := range role.Permissions {
+			permissions = append(permissions, permission.String())
+		}
+	}
+	slices.Sort(permissions)
+	return slices.Compact(permissions)
+}
@@ -0,0 +1,104 @@
+package auth_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"slices"
+	"sso/internal/domain/models"
+	jwtlib "sso/internal/lib/jwt"
+	"sso/storage"
+	"strconv"
+	"strings"
+)
+
+// oidcScopes are standard OpenID Connect scopes every app may request.
+var oidcScopes = []string{"openid", "email"}
+
+// appTokenParams checks the app tokens are requested for and the requested
+// scopes. Tokens issued for an app have the app as audience, so they are
+// not accepted by other services. appID 0 means no particular app.
+func (a *Auth) appTokenParams(
+	ctx context.Context,
+	appID int,
+	scopes []string,
+) (context.Context, jwtlib.TokenParams, error) {
+	if appID == 0 {
+		scope, err := grantScopes(oidcScopes, scopes)
+		return ctx, jwtlib.TokenParams{Scope: scope}, err
+	}
+	ctx, app, err := a.appStorage.App(ctx, appID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return ctx, jwtlib.TokenParams{}, ErrInvalidClient
+		}
+		return ctx, jwtlib.TokenParams{}, fmt.Errorf("appTokenParams: %w", err)
+	}
+	scope, err := grantScopes(appScopes(app), scopes)
+	if err != nil {
+		return ctx, jwtlib.TokenParams{}, err
+	}
+	clientID := strconv.Itoa(app.ID)
+	return ctx, jwtlib.TokenParams{
+		Audience: clientID,
+		ClientID: clientID,
+		Scope:    scope,
+	}, nil
+}
+
+// refreshTokenParams returns claims of tokens issued by refresh: the app
+// can't be changed, scopes may only be narrowed (RFC 6749 section 6).
+func refreshTokenParams(claims jwt.MapClaims, appID int, scopes []string) (jwtlib.TokenParams, error) {
+	params := jwtlib.TokenParams{TenantID: jwtlib.TenantID(claims)}
+	params.ClientID, _ = claims["client_id"].(string)
+	params.Scope, _ = claims["scope"].(string)
+	if appID != 0 && strconv.Itoa(appID) != params.ClientID {
+		return jwtlib.TokenParams{}, ErrInvalidClient
+	}
+	if audience, err := claims.GetAudience(); err == nil && len(audience) > 0 {
+		params.Audience = audience[0]
+	}
+	if len(scopes) == 0 {
+		return params, nil
+	}
+	scope, err := grantScopes(strings.Fields(params.Scope), scopes)
+	if err != nil {
+		return jwtlib.TokenParams{}, err
+	}
+	params.Scope = scope
+	return params, nil
+}
+
+// appScopes returns scopes the app may request.
+func appScopes(app models.App) []string {
+	return append(slices.Clone(oidcScopes), app.AllowedScopes...)
+}
+
+// grantScopes checks that every requested scope is allowed, returns them
+// as space separated "scope" claim.
+func grantScopes(allowed []string, requested []string) (string, error) {
+	granted := make([]string, 0, len(requested))
+	for _, scope := range requested {
+		if !slices.Contains(allowed, scope) {
+			return "", fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+		}
+		if !slices.Contains(granted, scope) {
+			granted = append(granted, scope)
+		}
+	}
+	return strings.Join(granted, " "), nil
+}
+
+// checkAudience checks that token was issued for the expected audience,
+// empty expected audience accepts any token.
+func checkAudience(claims jwt.MapClaims, expected string) error {
+	if expected == "" {
+		return nil
+	}
+	audience, err := claims.GetAudience()
+	if err != nil || !slices.Contains(audience, expected) {
+		return ErrTokenWrongAudience
+	}
+	return nil
+}
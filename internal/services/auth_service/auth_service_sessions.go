@@ -0,0 +1,228 @@
+package auth_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+	"log/slog"
+	"sso/internal/domain/models"
+	jwtlib "sso/internal/lib/jwt"
+	"sso/storage"
+	"time"
+)
+
+// ListSessions returns active sessions of the user. The caller must be
+// the user or an admin. Session of the token used for the call is
+// returned as currentSessionID.
+func (a *Auth) ListSessions(
+	ctx context.Context,
+	token string,
+	userID int,
+) (sessions []models.Session, currentSessionID string, err error) {
+	const op = "SERVICE LAYER: auth_service.ListSessions"
+
+	ctx, span := tracer.Start(ctx, "service layer: list sessions",
+		trace.WithAttributes(attribute.String("handler", "listSessions")))
+	defer span.End()
+
+	log := a.log.With(
+		slog.String("info", op),
+		slog.Int("user-id", userID),
+	)
+
+	ctx, claims, err := a.validateUserAccessToken(ctx, token)
+	if err != nil {
+		log.Info("failed validate token", slog.String("error", err.Error()))
+		return nil, "", err
+	}
+	ctx, err = a.authorizeUserAccess(ctx, claims, userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	ctx, sessions, err = a.sessionStorage.ListSessions(ctx, int64(userID))
+	if err != nil {
+		log.Error("failed to list sessions", slog.String("error", err.Error()))
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+	currentSessionID, _ = claims["sid"].(string)
+	return sessions, currentSessionID, nil
+}
+
+// RevokeSession revokes all tokens of one session. The caller must own
+// the session or be an admin.
+func (a *Auth) RevokeSession(
+	ctx context.Context,
+	token string,
+	sessionID string,
+) (success bool, err error) {
+	const op = "SERVICE LAYER: auth_service.RevokeSession"
+
+	ctx, span := tracer.Start(ctx, "service layer: revoke session",
+		trace.WithAttributes(attribute.String("handler", "revokeSession")))
+	defer span.End()
+
+	log := a.log.With(
+		slog.String("info", op),
+		slog.String("session-id", sessionID),
+	)
+
+	ctx, claims, err := a.validateUserAccessToken(ctx, token)
+	if err != nil {
+		log.Info("failed validate token", slog.String("error", err.Error()))
+		return false, err
+	}
+	ctx, session, err := a.sessionStorage.GetSession(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			return false, ErrSessionNotFound
+		}
+		log.Error("failed to get session", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	ctx, err = a.authorizeUserAccess(ctx, claims, int(session.UserID))
+	if err != nil {
+		return false, err
+	}
+
+	ctx, err = a.revokeSession(ctx, session.ID, session.UserID)
+	if err != nil {
+		log.Error("failed to revoke session", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("session revoked")
+	return true, nil
+}
+
+// RevokeAllSessions invalidates every access and refresh token issued to
+// the user so far. The caller must be the user or an admin.
+func (a *Auth) RevokeAllSessions(
+	ctx context.Context,
+	token string,
+	userID int,
+) (success bool, err error) {
+	const op = "SERVICE LAYER: auth_service.RevokeAllSessions"
+
+	ctx, span := tracer.Start(ctx, "service layer: revoke all sessions",
+		trace.WithAttributes(attribute.String("handler", "revokeAllSessions")))
+	defer span.End()
+
+	log := a.log.With(
+		slog.String("info", op),
+		slog.Int("user-id", userID),
+	)
+
+	log.Info("starting validate token")
+	ctx, claims, err := a.validateUserAccessToken(ctx, token)
+	if err != nil {
+		log.Info("failed validate token", slog.String("error", err.Error()))
+		return false, err
+	}
+	ctx, err = a.authorizeUserAccess(ctx, claims, userID)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, err = a.revokeAllSessions(ctx, userID)
+	if err != nil {
+		log.Error("failed to revoke sessions", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	ctx, err = a.sessionStorage.DeleteUserSessions(ctx, int64(userID))
+	if err != nil {
+		log.Error("failed to delete sessions", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("all sessions revoked")
+	return true, nil
+}
+
+// revokeAllSessions records a watermark: tokens of the user issued before
+// it are rejected by validateToken. Must be called whenever credentials of
+// the user change (e.g. password change).
+func (a *Auth) revokeAllSessions(ctx context.Context, userID int) (context.Context, error) {
+	// iat has a second precision, rounding up guarantees that tokens issued
+	// in the current second are revoked too
+	revokedBefore := time.Now().Truncate(time.Second).Add(time.Second)
+	// no token issued before the watermark outlives refresh token ttl
+	return a.tokenStorage.SetRevokedBefore(ctx, int64(userID), revokedBefore, a.cfg.RefreshTokenTtl)
+}
+
+// startSession records new session of the user with client info taken
+// from grpc metadata and peer.
+func (a *Auth) startSession(ctx context.Context, sessionID string, userID int64) (context.Context, error) {
+	userAgent, ip := a.clientInfo(ctx)
+	now := time.Now()
+	return a.sessionStorage.SaveSession(ctx, models.Session{
+		ID:         sessionID,
+		UserID:     userID,
+		UserAgent:  userAgent,
+		IP:         ip,
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}, a.cfg.RefreshTokenTtl)
+}
+
+// touchSession updates last seen time of the session on refresh.
+func (a *Auth) touchSession(ctx context.Context, sessionID string, userID int64) (context.Context, error) {
+	ctx, session, err := a.sessionStorage.GetSession(ctx, sessionID)
+	if errors.Is(err, storage.ErrSessionNotFound) {
+		// session created before registry was introduced
+		return a.startSession(ctx, sessionID, userID)
+	}
+	if err != nil {
+		return ctx, err
+	}
+	session.UserAgent, session.IP = a.clientInfo(ctx)
+	session.LastSeenAt = time.Now()
+	return a.sessionStorage.SaveSession(ctx, session, a.cfg.RefreshTokenTtl)
+}
+
+// revokeSession revokes token family of the session and removes it from registry.
+func (a *Auth) revokeSession(ctx context.Context, sessionID string, userID int64) (context.Context, error) {
+	ctx, err := a.tokenStorage.RevokeFamily(ctx, sessionID, a.cfg.RefreshTokenTtl)
+	if err != nil {
+		return ctx, err
+	}
+	return a.sessionStorage.DeleteSession(ctx, sessionID, userID)
+}
+
+// clientInfo extracts user agent and ip of the client. Requests passed
+// through grpc-gateway carry original values in metadata.
+func (a *Auth) clientInfo(ctx context.Context) (userAgent string, ip string) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("grpcgateway-user-agent"); len(values) > 0 {
+		userAgent = values[0]
+	} else if values := md.Get("user-agent"); len(values) > 0 {
+		userAgent = values[0]
+	}
+	return userAgent, a.proxies.ClientIP(ctx)
+}
+
+// authorizeUserAccess checks that caller may manage resources of the user:
+// either it is the user or an admin of the same tenant.
+func (a *Auth) authorizeUserAccess(ctx context.Context, callerClaims jwt.MapClaims, userID int) (context.Context, error) {
+	if int(claimsUserID(callerClaims)) == userID {
+		return ctx, nil
+	}
+	tenantID := jwtlib.TenantID(callerClaims)
+	ctx, caller, err := a.userStorage.GetUser(ctx, tenantID, int(claimsUserID(callerClaims)))
+	if err != nil {
+		return ctx, fmt.Errorf("authorizeUserAccess: %w", err)
+	}
+	if !caller.IsUserAmin() {
+		return ctx, ErrPermissionDenied
+	}
+	ctx, _, err = a.userStorage.GetUser(ctx, tenantID, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return ctx, ErrPermissionDenied
+		}
+		return ctx, fmt.Errorf("authorizeUserAccess: %w", err)
+	}
+	return ctx, nil
+}
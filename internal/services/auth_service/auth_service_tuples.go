@@ -0,0 +1,270 @@
+package auth_service
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"slices"
+	"sso/internal/domain/models"
+	"sso/internal/lib/authz"
+	jwtlib "sso/internal/lib/jwt"
+	"strings"
+)
+
+// maxTupleDepth bounds userset expansion, so cycles of usersets
+// (a#r@b#r, b#r@a#r) and deep nesting can't hang checks.
+const maxTupleDepth = 8
+
+// WriteTuples stores relation tuples in the tenant of the caller. Tuples
+// are written by apps (client credentials token) delegating object level
+// checks or by admins.
+func (a *Auth) WriteTuples(
+	ctx context.Context,
+	token string,
+	tuples []models.Tuple,
+) (success bool, err error) {
+	const op = "SERVICE LAYER: auth_service.WriteTuples"
+
+	ctx, span := tracer.Start(ctx, "service layer: write tuples",
+		trace.WithAttributes(attribute.String("handler", "writeTuples")))
+	defer span.End()
+
+	log := a.log.With(
+		slog.String("info", op),
+		slog.Int("tuples", len(tuples)),
+	)
+
+	ctx, tenantID, err := a.requireTupleWriter(ctx, token)
+	if err != nil {
+		log.Info("caller is not allowed to write tuples", slog.String("error", err.Error()))
+		return false, err
+	}
+	if err := validateTuples(tuples); err != nil {
+		return false, err
+	}
+	ctx, err = a.tupleStorage.WriteTuples(ctx, tenantID, tuples)
+	if err != nil {
+		log.Error("failed to write tuples", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return true, nil
+}
+
+// DeleteTuples deletes relation tuples of the caller's tenant, missing
+// tuples are ignored.
+func (a *Auth) DeleteTuples(
+	ctx context.Context,
+	token string,
+	tuples []models.Tuple,
+) (success bool, err error) {
+	const op = "SERVICE LAYER: auth_service.DeleteTuples"
+
+	ctx, span := tracer.Start(ctx, "service layer: delete tuples",
+		trace.WithAttributes(attribute.String("handler", "deleteTuples")))
+	defer span.End()
+
+	log := a.log.With(
+		slog.String("info", op),
+		slog.Int("tuples", len(tuples)),
+	)
+
+	ctx, tenantID, err := a.requireTupleWriter(ctx, token)
+	if err != nil {
+		log.Info("caller is not allowed to delete tuples", slog.String("error", err.Error()))
+		return false, err
+	}
+	if err := validateTuples(tuples); err != nil {
+		return false, err
+	}
+	ctx, err = a.tupleStorage.DeleteTuples(ctx, tenantID, tuples)
+	if err != nil {
+		log.Error("failed to delete tuples", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return true, nil
+}
+
+// Check reports whether subject has relation to object either directly,
+// through a relation implying it or through membership in a userset.
+// Tuples of the caller's tenant are checked only.
+func (a *Auth) Check(
+	ctx context.Context,
+	token string,
+	object string,
+	relation string,
+	subject string,
+) (allowed bool, err error) {
+	const op = "SERVICE LAYER: auth_service.Check"
+
+	ctx, span := tracer.Start(ctx, "service layer: check",
+		trace.WithAttributes(attribute.String("handler", "check")))
+	defer span.End()
+
+	ctx, tenantID, err := a.requireTupleReader(ctx, token)
+	if err != nil {
+		a.log.Info("caller is not allowed to check tuples", slog.String("info", op), slog.String("error", err.Error()))
+		return false, err
+	}
+	if !models.ValidObject(object) || !models.ValidRelation(relation) || !models.ValidSubject(subject) {
+		return false, ErrInvalidTuple
+	}
+	ctx, allowed, err = a.check(ctx, tenantID, object, relation, subject, 0, make(map[string]struct{}))
+	if err != nil {
+		a.log.Error("failed to check relation", slog.String("info", op), slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return allowed, nil
+}
+
+// check walks usersets from object towards subject. path holds usersets
+// of the current branch only, so cycles are cut, while a userset which
+// failed on one branch (e.g. because of depth) is still tried on others.
+func (a *Auth) check(
+	ctx context.Context,
+	tenantID int64,
+	object string,
+	relation string,
+	subject string,
+	depth int,
+	path map[string]struct{},
+) (context.Context, bool, error) {
+	userset := object + "#" + relation
+	if userset == subject {
+		return ctx, true, nil
+	}
+	if _, ok := path[userset]; ok || depth >= maxTupleDepth {
+		return ctx, false, nil
+	}
+	path[userset] = struct{}{}
+	defer delete(path, userset)
+
+	rewrites := authz.Rewrites(a.cfg.Authz.RelationRewrites)
+	objectType, _, _ := strings.Cut(object, ":")
+	ctx, tuples, err := a.tupleStorage.Tuples(ctx, tenantID, object, rewrites.Implying(objectType, relation))
+	if err != nil {
+		return ctx, false, err
+	}
+	for _, tuple := range tuples {
+		if tuple.Subject == subject {
+			return ctx, true, nil
+		}
+	}
+	for _, tuple := range tuples {
+		usersetObject, usersetRelation, ok := models.SplitUserset(tuple.Subject)
+		if !ok {
+			continue
+		}
+		var allowed bool
+		ctx, allowed, err = a.check(ctx, tenantID, usersetObject, usersetRelation, subject, depth+1, path)
+		if err != nil || allowed {
+			return ctx, allowed, err
+		}
+	}
+	return ctx, false, nil
+}
+
+// ListObjects returns objects of objectType subject has relation to. Tuples
+// are walked from the subject towards objects, so cost depends on the
+// number of relations of the subject rather than on the number of objects.
+// Tuples of the caller's tenant are walked only.
+func (a *Auth) ListObjects(
+	ctx context.Context,
+	token string,
+	objectType string,
+	relation string,
+	subject string,
+) (objects []string, err error) {
+	const op = "SERVICE LAYER: auth_service.ListObjects"
+
+	ctx, span := tracer.Start(ctx, "service layer: list objects",
+		trace.WithAttributes(attribute.String("handler", "listObjects")))
+	defer span.End()
+
+	ctx, tenantID, err := a.requireTupleReader(ctx, token)
+	if err != nil {
+		a.log.Info("caller is not allowed to list objects", slog.String("info", op), slog.String("error", err.Error()))
+		return nil, err
+	}
+	if objectType == "" || strings.ContainsAny(objectType, ":#@") ||
+		!models.ValidRelation(relation) || !models.ValidSubject(subject) {
+		return nil, ErrInvalidTuple
+	}
+
+	rewrites := authz.Rewrites(a.cfg.Authz.RelationRewrites)
+	// usersets the subject belongs to, every one of them is a subject as well
+	reached := map[string]struct{}{subject: {}}
+	frontier := []string{subject}
+	for depth := 0; depth < maxTupleDepth && len(frontier) > 0; depth++ {
+		var tuples []models.Tuple
+		ctx, tuples, err = a.tupleStorage.TuplesBySubjects(ctx, tenantID, frontier)
+		if err != nil {
+			a.log.Error("failed to list objects", slog.String("info", op), slog.String("error", err.Error()))
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		frontier = frontier[:0]
+		for _, tuple := range tuples {
+			for _, implied := range rewrites.Implied(tuple.ObjectType(), tuple.Relation) {
+				userset := tuple.Object + "#" + implied
+				if _, ok := reached[userset]; ok {
+					continue
+				}
+				reached[userset] = struct{}{}
+				frontier = append(frontier, userset)
+			}
+		}
+	}
+
+	objects = make([]string, 0)
+	for userset := range reached {
+		object, rel, ok := models.SplitUserset(userset)
+		if ok && rel == relation && strings.HasPrefix(object, objectType+":") {
+			objects = append(objects, object)
+		}
+	}
+	slices.Sort(objects)
+	return objects, nil
+}
+
+// requireTupleWriter allows tokens of apps and access tokens of admins,
+// returns the tenant tuples are written to.
+func (a *Auth) requireTupleWriter(ctx context.Context, token string) (context.Context, int64, error) {
+	ctx, claims, err := a.validateToken(ctx, token)
+	if err != nil {
+		return ctx, 0, err
+	}
+	if claims["token_type"] != "access" {
+		return ctx, 0, ErrTokenWrongType
+	}
+	if jwtlib.IsClientToken(claims) {
+		return ctx, jwtlib.TenantID(claims), nil
+	}
+	ctx, claims, err = a.requireAdmin(ctx, token)
+	if err != nil {
+		return ctx, 0, err
+	}
+	return ctx, jwtlib.TenantID(claims), nil
+}
+
+// requireTupleReader allows access tokens of apps and users, returns the
+// tenant tuples are read from.
+func (a *Auth) requireTupleReader(ctx context.Context, token string) (context.Context, int64, error) {
+	ctx, claims, err := a.validateToken(ctx, token)
+	if err != nil {
+		return ctx, 0, err
+	}
+	if claims["token_type"] != "access" {
+		return ctx, 0, ErrTokenWrongType
+	}
+	return ctx, jwtlib.TenantID(claims), nil
+}
+
+func validateTuples(tuples []models.Tuple) error {
+	for _, tuple := range tuples {
+		if !models.ValidObject(tuple.Object) || !models.ValidRelation(tuple.Relation) || !models.ValidSubject(tuple.Subject) {
+			return fmt.Errorf("%w: %s", ErrInvalidTuple, tuple)
+		}
+	}
+	return nil
+}
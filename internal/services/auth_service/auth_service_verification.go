@@ -0,0 +1,164 @@
+package auth_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"net/url"
+	"sso/internal/domain/models"
+	jwtlib "sso/internal/lib/jwt"
+	"sso/internal/lib/mailer"
+	"sso/storage"
+	"time"
+)
+
+// resendThrottlePrefix keys throttling of verification emails.
+const resendThrottlePrefix = "verification_resend:"
+
+// VerifyEmail marks email of the user as verified. Token of the link is
+// accepted once and only while the user still has the email it was sent to.
+func (a *Auth) VerifyEmail(
+	ctx context.Context,
+	token string,
+) (success bool, err error) {
+	const op = "SERVICE LAYER: auth_service.VerifyEmail"
+
+	ctx, span := tracer.Start(ctx, "service layer: verify email",
+		trace.WithAttributes(attribute.String("handler", "verifyEmail")))
+	defer span.End()
+
+	log := a.log.With(slog.String("info", op))
+
+	ctx, user, _, err := a.useActionToken(ctx, token, jwtlib.ActionEmailVerification)
+	if err != nil {
+		log.Info("verification token rejected", slog.String("error", err.Error()))
+		return false, err
+	}
+	if user.EmailVerified {
+		return true, nil
+	}
+	ctx, err = a.userStorage.SetEmailVerified(ctx, user.TenantID, user.ID)
+	if err != nil {
+		log.Error("failed to verify email", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("email verified", slog.Int64("user-id", user.ID))
+	return true, nil
+}
+
+// ResendVerification sends verification link again. Unknown and already
+// verified emails are reported as success, so the call can't be used to
+// find out which emails are registered.
+func (a *Auth) ResendVerification(
+	ctx context.Context,
+	email string,
+	tenantID int64,
+) (success bool, err error) {
+	const op = "SERVICE LAYER: auth_service.ResendVerification"
+
+	ctx, span := tracer.Start(ctx, "service layer: resend verification",
+		trace.WithAttributes(attribute.String("handler", "resendVerification")))
+	defer span.End()
+
+	log := a.log.With(slog.String("info", op))
+
+	if tenantID == 0 {
+		tenantID = models.DefaultTenantID
+	}
+	ctx, user, err := a.userStorage.GetUser(ctx, tenantID, email)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return true, nil
+		}
+		log.Error("failed to get user", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	if user.EmailVerified {
+		return true, nil
+	}
+	ctx, first, err := a.throttleEmail(ctx, resendThrottlePrefix, user.ID, a.cfg.EmailVerification.ResendInterval)
+	if err != nil {
+		log.Error("failed to throttle verification", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	if !first {
+		return false, ErrTooManyRequests
+	}
+	if err := a.sendVerification(ctx, user); err != nil {
+		log.Error("failed to send verification", slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return true, nil
+}
+
+// sendVerification issues verification token for the user and delivers
+// the link to them.
+func (a *Auth) sendVerification(ctx context.Context, user models.User) error {
+	signingKey, err := a.keyRing.SigningKey(time.Now())
+	if err != nil {
+		return fmt.Errorf("sendVerification: %w", err)
+	}
+	token, err := jwtlib.NewActionToken(
+		user, a.cfg, signingKey, jwtlib.ActionEmailVerification, a.cfg.EmailVerification.TokenTtl,
+	)
+	if err != nil {
+		return fmt.Errorf("sendVerification: %w", err)
+	}
+	link := a.cfg.OIDC.Issuer + "/sso/email/verify?token=" + url.QueryEscape(token)
+	return a.sendEmail(ctx, user.Email, mailer.TemplateVerification, mailer.LinkData{
+		Email:    user.Email,
+		Link:     link,
+		ValidFor: a.cfg.EmailVerification.TokenTtl.String(),
+	})
+}
+
+// useActionToken checks token sent to the user by email and burns it, so
+// it can't be used twice. Returns the user the token was issued to and
+// claims of the token.
+func (a *Auth) useActionToken(
+	ctx context.Context,
+	token string,
+	action string,
+) (context.Context, models.User, jwt.MapClaims, error) {
+	tokenParsed, err := jwt.Parse(token, a.Keyfunc)
+	if err != nil {
+		return ctx, models.User{}, nil, ErrInvalidActionToken
+	}
+	claims, ok := tokenParsed.Claims.(jwt.MapClaims)
+	if !ok || claims["token_type"] != action || claims["iss"] != a.cfg.OIDC.Issuer {
+		return ctx, models.User{}, nil, ErrInvalidActionToken
+	}
+	userID, err := jwtlib.UserID(claims)
+	if err != nil {
+		return ctx, models.User{}, nil, ErrInvalidActionToken
+	}
+	tokenID, _ := claims["jti"].(string)
+	exp, _ := claims["exp"].(float64)
+	ttl := time.Until(time.Unix(int64(exp), 0))
+	if tokenID == "" || ttl <= 0 {
+		return ctx, models.User{}, nil, ErrInvalidActionToken
+	}
+
+	ctx, user, err := a.userStorage.GetUser(ctx, jwtlib.TenantID(claims), int(userID))
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return ctx, models.User{}, nil, ErrInvalidActionToken
+		}
+		return ctx, models.User{}, nil, fmt.Errorf("useActionToken: %w", err)
+	}
+	if claims["email"] != user.Email {
+		return ctx, models.User{}, nil, ErrInvalidActionToken
+	}
+	ctx, first, err := a.tokenStorage.MarkTokenUsed(ctx, tokenID, ttl)
+	if err != nil {
+		return ctx, models.User{}, nil, fmt.Errorf("useActionToken: %w", err)
+	}
+	if !first {
+		return ctx, models.User{}, nil, ErrInvalidActionToken
+	}
+	return ctx, user, claims, nil
+}
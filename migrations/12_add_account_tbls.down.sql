@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS deleted_users;
+DROP TABLE IF EXISTS login_history;
@@ -0,0 +1,20 @@
+-- successful sign-ins, handed out to users on data access requests
+CREATE TABLE IF NOT EXISTS login_history
+(
+    id         bigserial PRIMARY KEY,
+    user_id    INT         NOT NULL,
+    ip         TEXT        NOT NULL DEFAULT '',
+    user_agent TEXT        NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_history_user_id ON login_history (user_id, created_at);
+
+-- tombstones of deleted accounts, no personal data is kept
+CREATE TABLE IF NOT EXISTS deleted_users
+(
+    user_id    INT PRIMARY KEY,
+    tenant_id  INT         NOT NULL,
+    deleted_by INT         NOT NULL,
+    deleted_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
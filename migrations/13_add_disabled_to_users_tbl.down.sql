@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_users_tenant_id_id;
+ALTER TABLE users
+    DROP COLUMN IF EXISTS disabled;
@@ -0,0 +1,5 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS disabled BOOLEAN NOT NULL DEFAULT false;
+
+-- ListUsers pages through a tenant by id
+CREATE INDEX IF NOT EXISTS idx_users_tenant_id_id ON users (tenant_id, id);
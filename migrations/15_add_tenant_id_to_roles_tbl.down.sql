@@ -0,0 +1,21 @@
+-- admins of other tenants keep the default admin role, fails if the same
+-- role name is used in several tenants
+INSERT INTO user_roles(user_id, role_id)
+SELECT ur.user_id, (SELECT id FROM roles WHERE tenant_id = 1 AND name = 'admin')
+FROM user_roles ur
+         JOIN roles r ON r.id = ur.role_id
+WHERE r.tenant_id <> 1
+  AND r.name = 'admin'
+ON CONFLICT DO NOTHING;
+
+DELETE
+FROM roles
+WHERE tenant_id <> 1
+  AND name = 'admin';
+
+ALTER TABLE roles
+    DROP CONSTRAINT IF EXISTS roles_tenant_id_name_key;
+ALTER TABLE roles
+    ADD CONSTRAINT roles_name_key UNIQUE (name);
+ALTER TABLE roles
+    DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,37 @@
+-- roles belong to tenants, existing ones to the default tenant
+ALTER TABLE roles
+    ADD COLUMN IF NOT EXISTS tenant_id INT NOT NULL DEFAULT 1 REFERENCES organizations (id) ON DELETE CASCADE;
+ALTER TABLE roles
+    DROP CONSTRAINT IF EXISTS roles_name_key;
+ALTER TABLE roles
+    ADD CONSTRAINT roles_tenant_id_name_key UNIQUE (tenant_id, name);
+
+-- every tenant has its own admin role
+INSERT INTO roles(tenant_id, name, description)
+SELECT id, 'admin', 'Full access'
+FROM organizations
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions(role_id, action, resource)
+SELECT id, '*', '*'
+FROM roles
+WHERE name = 'admin'
+ON CONFLICT DO NOTHING;
+
+-- bindings to roles of another tenant move to the role of the same name
+-- in the tenant of the user, those without such a role are dropped
+INSERT INTO user_roles(user_id, role_id)
+SELECT ur.user_id, tr.id
+FROM user_roles ur
+         JOIN roles r ON r.id = ur.role_id
+         JOIN users u ON u.id = ur.user_id
+         JOIN roles tr ON tr.tenant_id = u.tenant_id AND tr.name = r.name
+WHERE r.tenant_id <> u.tenant_id
+ON CONFLICT DO NOTHING;
+
+DELETE
+FROM user_roles ur
+    USING roles r, users u
+WHERE r.id = ur.role_id
+  AND u.id = ur.user_id
+  AND r.tenant_id <> u.tenant_id;
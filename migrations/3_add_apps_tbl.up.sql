@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS apps
+(
+    id     serial PRIMARY KEY,
+    name   TEXT NOT NULL UNIQUE,
+    secret TEXT NOT NULL
+);
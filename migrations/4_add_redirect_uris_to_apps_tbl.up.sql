@@ -0,0 +1,2 @@
+ALTER TABLE apps
+    ADD COLUMN IF NOT EXISTS redirect_uris TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,4 @@
+-- plain secrets can't be restored, apps must get new secrets
+ALTER TABLE apps
+    ADD COLUMN IF NOT EXISTS secret TEXT NOT NULL DEFAULT '',
+    DROP COLUMN IF EXISTS secret_hash;
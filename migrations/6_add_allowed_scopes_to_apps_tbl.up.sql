@@ -0,0 +1,2 @@
+ALTER TABLE apps
+    ADD COLUMN IF NOT EXISTS allowed_scopes TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,39 @@
+CREATE TABLE IF NOT EXISTS organizations
+(
+    id         serial PRIMARY KEY,
+    name       TEXT        NOT NULL,
+    slug       TEXT        NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- every existing user belongs to the default tenant
+INSERT INTO organizations(id, name, slug)
+VALUES (1, 'Default', 'default')
+ON CONFLICT DO NOTHING;
+SELECT setval(pg_get_serial_sequence('organizations', 'id'), (SELECT max(id) FROM organizations));
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS tenant_id INT NOT NULL DEFAULT 1 REFERENCES organizations (id);
+
+-- the same email may be registered in several tenants independently,
+-- email stays in the key as the partition column
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS users_email_key;
+ALTER TABLE users
+    ADD CONSTRAINT users_tenant_id_email_key UNIQUE (tenant_id, email);
+
+CREATE TABLE IF NOT EXISTS organization_members
+(
+    org_id     INT         NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    user_id    INT         NOT NULL,
+    role       TEXT        NOT NULL DEFAULT 'member' CHECK (role IN ('owner', 'admin', 'member')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (org_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_organization_members_user_id ON organization_members (user_id);
+
+INSERT INTO organization_members(org_id, user_id, role)
+SELECT tenant_id, id, 'member'
+FROM users
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS roles
+(
+    id          INTEGER PRIMARY KEY,
+    name        TEXT NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions
+(
+    role_id  INTEGER NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    action   TEXT    NOT NULL,
+    resource TEXT    NOT NULL,
+    PRIMARY KEY (role_id, action, resource)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles
+(
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role_id INTEGER NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, role_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_roles_role_id ON user_roles (role_id);
+
+INSERT OR IGNORE INTO roles(name, description)
+VALUES ('admin', 'Full access');
+
+INSERT OR IGNORE INTO role_permissions(role_id, action, resource)
+SELECT id, '*', '*'
+FROM roles
+WHERE name = 'admin';
+
+INSERT OR IGNORE INTO user_roles(user_id, role_id)
+SELECT u.id, r.id
+FROM users u,
+     roles r
+WHERE u.is_admin
+  AND r.name = 'admin';
@@ -0,0 +1,43 @@
+CREATE TABLE IF NOT EXISTS organizations
+(
+    id         INTEGER PRIMARY KEY,
+    name       TEXT     NOT NULL,
+    slug       TEXT     NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT OR IGNORE INTO organizations(id, name, slug)
+VALUES (1, 'Default', 'default');
+
+-- sqlite can't alter constraints, so users table is rebuilt to make
+-- email unique within tenant
+CREATE TABLE users_new
+(
+    id        INTEGER PRIMARY KEY,
+    tenant_id INTEGER NOT NULL DEFAULT 1 REFERENCES organizations (id),
+    email     TEXT    NOT NULL,
+    pass_hash BLOB    NOT NULL,
+    is_admin  BOOLEAN NOT NULL DEFAULT FALSE,
+    UNIQUE (tenant_id, email)
+);
+INSERT INTO users_new(id, email, pass_hash, is_admin)
+SELECT id, email, pass_hash, is_admin
+FROM users;
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
+CREATE INDEX IF NOT EXISTS idx_email ON users (email);
+
+CREATE TABLE IF NOT EXISTS organization_members
+(
+    org_id     INTEGER  NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    user_id    INTEGER  NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role       TEXT     NOT NULL DEFAULT 'member' CHECK (role IN ('owner', 'admin', 'member')),
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (org_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_organization_members_user_id ON organization_members (user_id);
+
+INSERT OR IGNORE INTO organization_members(org_id, user_id, role)
+SELECT tenant_id, id, 'member'
+FROM users;
@@ -0,0 +1,6 @@
+ALTER TABLE users
+    ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT false;
+
+-- accounts registered before verification was introduced are trusted
+UPDATE users
+SET email_verified = true;
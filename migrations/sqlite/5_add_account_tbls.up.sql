@@ -0,0 +1,19 @@
+-- successful sign-ins, handed out to users on data access requests
+CREATE TABLE IF NOT EXISTS login_history
+(
+    id         INTEGER PRIMARY KEY,
+    user_id    INTEGER   NOT NULL,
+    ip         TEXT      NOT NULL DEFAULT '',
+    user_agent TEXT      NOT NULL DEFAULT '',
+    created_at DATETIME  NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_login_history_user_id ON login_history (user_id, created_at);
+
+-- tombstones of deleted accounts, no personal data is kept
+CREATE TABLE IF NOT EXISTS deleted_users
+(
+    user_id    INTEGER PRIMARY KEY,
+    tenant_id  INTEGER   NOT NULL,
+    deleted_by INTEGER   NOT NULL,
+    deleted_at DATETIME  NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
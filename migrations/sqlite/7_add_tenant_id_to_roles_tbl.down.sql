@@ -0,0 +1,31 @@
+-- admins of other tenants keep the default admin role, fails if the same
+-- role name is used in several tenants
+INSERT OR IGNORE INTO user_roles(user_id, role_id)
+SELECT ur.user_id, (SELECT id FROM roles WHERE tenant_id = 1 AND name = 'admin')
+FROM user_roles ur
+         JOIN roles r ON r.id = ur.role_id
+WHERE r.tenant_id <> 1
+  AND r.name = 'admin';
+
+DELETE
+FROM user_roles
+WHERE role_id IN (SELECT id FROM roles WHERE tenant_id <> 1 AND name = 'admin');
+DELETE
+FROM role_permissions
+WHERE role_id IN (SELECT id FROM roles WHERE tenant_id <> 1 AND name = 'admin');
+DELETE
+FROM roles
+WHERE tenant_id <> 1
+  AND name = 'admin';
+
+CREATE TABLE roles_old
+(
+    id          INTEGER PRIMARY KEY,
+    name        TEXT NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT ''
+);
+INSERT INTO roles_old(id, name, description)
+SELECT id, name, description
+FROM roles;
+DROP TABLE roles;
+ALTER TABLE roles_old RENAME TO roles;
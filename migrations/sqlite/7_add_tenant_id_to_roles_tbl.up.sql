@@ -0,0 +1,43 @@
+-- sqlite can't alter constraints, so roles table is rebuilt to make
+-- name unique within tenant, ids are kept for bindings and permissions
+CREATE TABLE roles_new
+(
+    id          INTEGER PRIMARY KEY,
+    tenant_id   INTEGER NOT NULL DEFAULT 1 REFERENCES organizations (id) ON DELETE CASCADE,
+    name        TEXT    NOT NULL,
+    description TEXT    NOT NULL DEFAULT '',
+    UNIQUE (tenant_id, name)
+);
+INSERT INTO roles_new(id, name, description)
+SELECT id, name, description
+FROM roles;
+DROP TABLE roles;
+ALTER TABLE roles_new RENAME TO roles;
+
+-- every tenant has its own admin role
+INSERT OR IGNORE INTO roles(tenant_id, name, description)
+SELECT id, 'admin', 'Full access'
+FROM organizations;
+
+INSERT OR IGNORE INTO role_permissions(role_id, action, resource)
+SELECT id, '*', '*'
+FROM roles
+WHERE name = 'admin';
+
+-- bindings to roles of another tenant move to the role of the same name
+-- in the tenant of the user, those without such a role are dropped
+INSERT OR IGNORE INTO user_roles(user_id, role_id)
+SELECT ur.user_id, tr.id
+FROM user_roles ur
+         JOIN roles r ON r.id = ur.role_id
+         JOIN users u ON u.id = ur.user_id
+         JOIN roles tr ON tr.tenant_id = u.tenant_id AND tr.name = r.name
+WHERE r.tenant_id <> u.tenant_id;
+
+DELETE
+FROM user_roles
+WHERE EXISTS(SELECT 1
+             FROM roles r
+                      JOIN users u ON u.id = user_roles.user_id
+             WHERE r.id = user_roles.role_id
+               AND r.tenant_id <> u.tenant_id);
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"github.com/XSAM/otelsql"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -14,9 +15,20 @@ import (
 	"sso/internal/config"
 	"sso/internal/domain/models"
 	"sso/storage"
+	"strings"
 )
 
-const ErrCodeUserAlreadyExists = "23505"
+const (
+	ErrCodeUserAlreadyExists   = "23505"
+	ErrCodeForeignKeyViolation = "23503"
+)
+
+// selectUser derives is_admin from the admin role of the user's tenant, the
+// column itself is kept only for rollback of RBAC migration.
+const selectUser = `SELECT id, tenant_id, email, pass_hash, email_verified, disabled, EXISTS(
+	SELECT 1 FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+	WHERE ur.user_id = users.id AND r.tenant_id = users.tenant_id AND r.name = 'admin'
+) FROM users `
 
 type Storage struct {
 	dbRead  *sql.DB
@@ -49,19 +61,28 @@ func (s *Storage) Stop() error {
 	return fmt.Errorf("%w, %w", err1, err2)
 }
 
-// SaveUser saves user to db.
-func (s *Storage) SaveUser(ctx context.Context, email string, passHash []byte) (context.Context, int64, error) {
+// SaveUser saves user to db and makes them a member of their tenant.
+func (s *Storage) SaveUser(ctx context.Context, tenantID int64, email string, passHash []byte) (context.Context, int64, error) {
 	ctx, span := tracer.Start(ctx, "data layer Patroni: SaveUser",
 		trace.WithAttributes(attribute.String("handler", "SaveUser")))
 	defer span.End()
 
+	tx, err := s.dbWrite.BeginTx(ctx, nil)
+	if err != nil {
+		return ctx, 0, fmt.Errorf("DATA LAYER: storage.postgres.SaveUser: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
 	var id int
-	query := "INSERT INTO users(email, pass_hash) VALUES($1, $2) RETURNING id"
-	err := s.dbWrite.QueryRowContext(ctx, query, email, passHash).Scan(&id)
+	query := "INSERT INTO users(tenant_id, email, pass_hash) VALUES($1, $2, $3) RETURNING id"
+	err = tx.QueryRowContext(ctx, query, tenantID, email, passHash).Scan(&id)
 	// https://stackoverflow.com/questions/34963064/go-pq-and-postgres-appropriate-error-handling-for-constraints
 	if err, ok := err.(*pgconn.PgError); ok {
-		if err.Code == ErrCodeUserAlreadyExists {
+		switch err.Code {
+		case ErrCodeUserAlreadyExists:
 			return ctx, int64(0), storage.ErrUserExists
+		case ErrCodeForeignKeyViolation:
+			return ctx, int64(0), storage.ErrOrgNotFound
 		}
 	}
 	if err != nil {
@@ -70,11 +91,18 @@ func (s *Storage) SaveUser(ctx context.Context, email string, passHash []byte) (
 			err,
 		)
 	}
+	query = "INSERT INTO organization_members(org_id, user_id, role) VALUES($1, $2, $3)"
+	if _, err := tx.ExecContext(ctx, query, tenantID, id, models.OrgRoleMember); err != nil {
+		return ctx, 0, fmt.Errorf("DATA LAYER: storage.postgres.SaveUser: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return ctx, 0, fmt.Errorf("DATA LAYER: storage.postgres.SaveUser: %w", err)
+	}
 
 	return ctx, int64(id), nil
 }
 
-func (s *Storage) GetUser(ctx context.Context, value any) (context.Context, models.User, error) {
+func (s *Storage) GetUser(ctx context.Context, tenantID int64, value any) (context.Context, models.User, error) {
 	ctx, span := tracer.Start(ctx, "data layer Patroni: GetUser",
 		trace.WithAttributes(attribute.String("handler", "GetUser")))
 	defer span.End()
@@ -82,11 +110,11 @@ func (s *Storage) GetUser(ctx context.Context, value any) (context.Context, mode
 	var row *sql.Row
 	switch sqlParam := value.(type) {
 	case int:
-		query := "SELECT id, email, pass_hash, is_admin FROM users WHERE (id = $1);"
-		row = s.dbRead.QueryRowContext(ctx, query, sqlParam)
+		query := selectUser + "WHERE (tenant_id = $1 AND id = $2);"
+		row = s.dbRead.QueryRowContext(ctx, query, tenantID, sqlParam)
 	case string:
-		query := "SELECT id, email, pass_hash, is_admin FROM users WHERE (email = $1);"
-		row = s.dbRead.QueryRowContext(ctx, query, sqlParam)
+		query := selectUser + "WHERE (tenant_id = $1 AND email = $2);"
+		row = s.dbRead.QueryRowContext(ctx, query, tenantID, sqlParam)
 	default:
 		return ctx, models.User{}, fmt.Errorf(
 			"DATA LAYER: storage.postgres.GetUser: %w",
@@ -94,8 +122,7 @@ func (s *Storage) GetUser(ctx context.Context, value any) (context.Context, mode
 		)
 	}
 
-	var user models.User
-	err := row.Scan(&user.ID, &user.Email, &user.PassHash, &user.IsAdmin)
+	user, err := scanUser(row)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return ctx, models.User{}, fmt.Errorf(
@@ -110,3 +137,842 @@ func (s *Storage) GetUser(ctx context.Context, value any) (context.Context, mode
 	}
 	return ctx, user, nil
 }
+
+// ListUsers returns page of users matching the filter.
+func (s *Storage) ListUsers(ctx context.Context, filter models.UserFilter) (context.Context, []models.User, error) {
+	ctx, span := tracer.Start(ctx, "data layer Patroni: ListUsers",
+		trace.WithAttributes(attribute.String("handler", "ListUsers")))
+	defer span.End()
+
+	query := selectUser + "WHERE tenant_id = $1 AND id > $2"
+	args := []any{filter.TenantID, filter.AfterID}
+	if filter.EmailPrefix != "" {
+		args = append(args, likePrefix(filter.EmailPrefix))
+		query += fmt.Sprintf(" AND email LIKE $%d ESCAPE '\\'", len(args))
+	}
+	if filter.IsAdmin != nil {
+		args = append(args, *filter.IsAdmin)
+		query += fmt.Sprintf(` AND EXISTS(
+			SELECT 1 FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+			WHERE ur.user_id = users.id AND r.tenant_id = users.tenant_id AND r.name = 'admin'
+		) = $%d`, len(args))
+	}
+	if filter.Disabled != nil {
+		args = append(args, *filter.Disabled)
+		query += fmt.Sprintf(" AND disabled = $%d", len(args))
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY id LIMIT $%d", len(args))
+
+	rows, err := s.dbRead.QueryContext(ctx, query, args...)
+	if err != nil {
+		return ctx, nil, fmt.Errorf("DATA LAYER: storage.postgres.ListUsers: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]models.User, 0, filter.Limit)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return ctx, nil, fmt.Errorf("DATA LAYER: storage.postgres.ListUsers: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return ctx, nil, fmt.Errorf("DATA LAYER: storage.postgres.ListUsers: %w", err)
+	}
+	return ctx, users, nil
+}
+
+// SetDisabled disables or enables the user.
+func (s *Storage) SetDisabled(ctx context.Context, tenantID int64, userID int64, disabled bool) (context.Context, error) {
+	ctx, span := tracer.Start(ctx, "data layer Patroni: SetDisabled",
+		trace.WithAttributes(attribute.String("handler", "SetDisabled")))
+	defer span.End()
+
+	query := "UPDATE users SET disabled = $1 WHERE id = $2 AND tenant_id = $3"
+	res, err := s.dbWrite.ExecContext(ctx, query, disabled, userID, tenantID)
+	if err != nil {
+		return ctx, fmt.Errorf("DATA LAYER: storage.postgres.SetDisabled: %w", err)
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return ctx, fmt.Errorf("DATA LAYER: storage.postgres.SetDisabled: %w", storage.ErrUserNotFound)
+	}
+	return ctx, nil
+}
+
+// scanUser scans row selected by selectUser.
+func scanUser(row interface{ Scan(dest ...any) error }) (models.User, error) {
+	var user models.User
+	err := row.Scan(&user.ID, &user.TenantID, &user.Email, &user.PassHash, &user.EmailVerified, &user.Disabled, &user.IsAdmin)
+	return user, err
+}
+
+// likePrefix makes LIKE pattern matching values starting with prefix,
+// wildcards in the prefix match literally.
+func likePrefix(prefix string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	return replacer.Replace(prefix) + "%"
+}
+
+// SetEmailVerified marks email of the user as verified.
+func (s *Storage) SetEmailVerified(ctx context.Context, tenantID int64, userID int64) (context.Context, error) {
+	ctx, span := tracer.Start(ctx, "data layer Patroni: SetEmailVerified",
+		trace.WithAttributes(attribute.String("handler", "SetEmailVerified")))
+	defer span.End()
+
+	query := "UPDATE users SET email_verified = true WHERE id = $1 AND tenant_id = $2"
+	res, err := s.dbWrite.ExecContext(ctx, query, userID, tenantID)
+	if err != nil {
+		return ctx, fmt.Errorf("DATA LAYER: storage.postgres.SetEmailVerified: %w", err)
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return ctx, fmt.Errorf("DATA LAYER: storage.postgres.SetEmailVerified: %w", storage.ErrUserNotFound)
+	}
+	return ctx, nil
+}
+
+// UpdatePassword replaces password hash of the user.
+func (s *Storage) UpdatePassword(ctx context.Context, tenantID int64, userID int64, passHash []byte) (context.Context, error) {
+	ctx, span := tracer.Start(ctx, "data layer Patroni: UpdatePassword",
+		trace.WithAttributes(attribute.String("handler", "UpdatePassword")))
+	defer span.End()
+
+	query := "UPDATE users SET pass_hash = $1 WHERE id = $2 AND tenant_id = $3"
+	res, err := s.dbWrite.ExecContext(ctx, query, passHash, userID, tenantID)
+	if err != nil {
+		return ctx, fmt.Errorf("DATA LAYER: storage.postgres.UpdatePassword: %w", err)
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return ctx, fmt.Errorf("DATA LAYER: storage.postgres.UpdatePassword: %w", storage.ErrUserNotFound)
+	}
+	return ctx, nil
+}
+
+// UpdateEmail replaces email of the user if it is still oldEmail and
+// marks the new one verified. Email is the partition key of users, so
+// Postgres moves the row to another partition (delete + insert under the
+// hood). The row is locked first, a concurrent change of the same user
+// waits for the move instead of failing on the already moved tuple.
+func (s *Storage) UpdateEmail(
+	ctx context.Context,
+	tenantID int64,
+	userID int64,
+	oldEmail string,
+	newEmail string,
+) (context.Context, error) {
+	ctx, span := tracer.Start(ctx, "data layer Patroni: UpdateEmail",
+		trace.WithAttributes(attribute.String("handler", "UpdateEmail")))
+	defer span.End()
+
+	tx, err := s.dbWrite.BeginTx(ctx, nil)
+	if err != nil {
+		return ctx, fmt.Errorf("DATA LAYER: storage.postgres.UpdateEmail: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var locked int64
+	query := "SELECT id FROM users WHERE id = $1 AND email = $2 AND tenant_id = $3 FOR UPDATE"
+	err = tx.QueryRowContext(ctx, query, userID, oldEmail, tenantID).Scan(&locked)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ctx, fmt.Errorf("DATA LAYER: storage.postgres.UpdateEmail: %w", storage.ErrUserNotFound)
+		}
+		return ctx, fmt.Errorf("DATA LAYER: storage.postgres.UpdateEmail: %w", err)
+	}
+	query = "UPDATE users SET email = $1, email_verified = true WHERE id = $2 AND email = $3 AND tenant_id = $4"
+	_, err = tx.ExecContext(ctx, query, newEmail, userID, oldEmail, tenantID)
+	if err, ok := err.(*pgconn.PgError); ok && err.Code == ErrCodeUserAlreadyExists {
+		return ctx, storage.ErrUserExists
+	}
+	if err != nil {
+		return ctx, fmt.Errorf("DATA LAYER: storage.postgres.UpdateEmail: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return ctx, fmt.Errorf("DATA LAYER: storage.postgres.UpdateEmail: %w", err)
+	}
+	return ctx, nil
+}
+
+// DeleteUser removes the user row from its partition and every binding of
+// the user. Those tables can't reference partitioned users, so they are
+// cleaned up explicitly in the same transaction.
+func (s *Storage) DeleteUser(ctx context.Context, tenantID int64, userID int64, deletedBy int64) (context.Context, error) {
+	ctx, span := tracer.Start(ctx, "data layer Patroni: DeleteUser",
+		trace.WithAttributes(attribute.String("handler", "DeleteUser")))
+	defer span.End()
+
+	tx, err := s.dbWrite.BeginTx(ctx, nil)
+	if err != nil {
+		return ctx, fmt.Errorf("DATA LAYER: storage.postgres.DeleteUser: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var deleted int64
+	query := "DELETE FROM users WHERE id = $1 AND tenant_id = $2 RETURNING id"
+	err = tx.QueryRowContext(ctx, query, userID, tenantID).Scan(&deleted)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ctx, fmt.Errorf("DATA LAYER: storage.postgres.DeleteUser: %w", storage.ErrUserNotFound)
+		}
+		return ctx, fmt.Errorf("DATA LAYER: storage.postgres.DeleteUser: %w", err)
+	}
+	queries := []string{
+		"DELETE FROM user_roles WHERE user_id = $1",
+		"DELETE FROM organization_members WHERE user_id = $1",
+		"DELETE FROM group_members WHERE user_id = $1",
+		"DELETE FROM relation_tuples WHERE subject = 'user:' || $1::text",
+		"DELETE FROM login_history WHERE user_id = $1",
+	}
+	for _, query := range queries {
+		if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+			return ctx, fmt.Errorf("DATA LAYER: storage.postgres.DeleteUser: %w", err)
+		}
+	}
+	query = "INSERT INTO deleted_users(user_id, tenant_id, deleted_by) VALUES($1, $2, $3)"
+	if _, err := tx.ExecContext(ctx, query, userID, tenantID, deletedBy); err != nil {
+		return ctx, fmt.Errorf("DATA LAYER: storage.postgres.DeleteUser: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return ctx, fmt.Errorf("DATA LAYER: storage.postgres.DeleteUser: %w", err)
+	}
+	return ctx, nil
+}
+
+func (s *Storage) SaveLogin(ctx context.Context, login models.Login) (context.Context, error) {
+	ctx, span := tracer.Start(ctx, "data layer Patroni: SaveLogin",
+		trace.WithAttributes(attribute.String("handler", "SaveLogin")))
+	defer span.End()
+
+	query := "INSERT INTO login_history(user_id, ip, user_agent, created_at) VALUES($1, $2, $3, $4)"
+	_, err := s.dbWrite.ExecContext(ctx, query, login.UserID, login.IP, login.UserAgent, login.CreatedAt)
+	if err != nil {
+		return ctx, fmt.Errorf("DATA LAYER: storage.postgres.SaveLogin: %w", err)
+	}
+	return ctx, nil
+}
+
+func (s *Storage) LoginHistory(ctx context.Context, userID int64) (context.Context, []models.Login, error) {
+	ctx, span := tracer.Start(ctx, "data layer Patroni: LoginHistory",
+		trace.WithAttributes(attribute.String("handler", "LoginHistory")))
+	defer span.End()
+
+	query := "SELECT ip, user_agent, created_at FROM login_history WHERE user_id = $1 ORDER BY created_at DESC"
+	rows, err := s.dbRead.QueryContext(ctx, query, userID)
+	if err != nil {
+		return ctx, nil, fmt.Errorf("DATA LAYER: storage.postgres.LoginHistory: %w", err)
+	}
+	defer rows.Close()
+
+	logins := make([]models.Login, 0)
+	for rows.Next() {
+		login := models.Login{UserID: userID}
+		if err := rows.Scan(&login.IP, &login.UserAgent, &login.CreatedAt); err != nil {
+			return ctx, nil, fmt.Errorf("DATA LAYER: storage.postgres.LoginHistory: %w", err)
+		}
+		logins = append(logins, login)
+	}
+	if err := rows.Err(); err != nil {
+		return ctx, nil, fmt.Errorf("DATA LAYER: storage.postgres.LoginHistory: %w", err)
+	}
+	return ctx, logins, nil
+}
+
+// App returns app by id.
+func (s *Storage) App(ctx context.Context, id int) (context.Context, models.App, error) {
+	ctx, span := tracer.Start(ctx, "data layer Patroni: App",
+		trace.WithAttributes(attribute.String("handler", "App")))
+	defer span.End()
+
+	query := "SELECT id, name, secret_hash, redirect_uris, allowed_scopes, tenant_id FROM apps WHERE (id = $1);"
+	row := s.dbRead.QueryRowContext(ctx, query, id)
+
+	var app models.App
+	typeMap := pgtype.NewMap()
+	err := row.Scan(
+		&app.ID, &app.Name, &app.SecretHash,
+		typeMap.SQLScanner(&app.RedirectURIs),
+		typeMap.SQLScanner(&app.AllowedScopes),
+		&app.TenantID,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ctx, models.App{}, fmt.Errorf(
+				"DATA LAYER: storage.postgres.App: %w",
+				storage.ErrAppNotFound,
+			)
+		}
+		return ctx, models.App{}, fmt.Errorf(
+			"DATA LAYER: storage.postgres.App: %w",
+			err,
+		)
+	}
+	return ctx, app, nil
+}
+
+// SaveApp registers app, returns its id (client_id).
+func (s *Storage) SaveApp(ctx context.Context, app models.App) (context.Context, int, error) {
+	ctx, span := tracer.Start(ctx, "data layer Patroni: SaveApp",
+		trace.WithAttributes(attribute.String("handler", "SaveApp")))
+	defer span.End()
+
+	redirectURIs, allowedScopes := app.RedirectURIs, app.AllowedScopes
+	if redirectURIs == nil {
+		redirectURIs = []string{}
+	}
+	if allowedScopes == nil {
+		allowedScopes = []string{}
+	}
+	var id int
+	query := "INSERT INTO apps(name, secret_hash, redirect_uris, allowed_scopes, tenant_id) VALUES($1, $2, $3, $4, $5) RETURNING id"
+	err := s.dbWrite.QueryRowContext(ctx, query, app.Name, app.SecretHash, redirectURIs, allowedScopes, app.TenantID).Scan(&id)
+	if err, ok := err.(*pgconn.PgError); ok {
+		if err.Code == ErrCodeUserAlreadyExists {
+			return ctx, 0, storage.ErrAppExists
+		}
+	}
+	if err != nil {
+		return ctx, 0, fmt.Errorf(
+			"DATA LAYER: storage.postgres.SaveApp: %w",
+			err,
+		)
+	}
+	return ctx, id, nil
+}
+
+// SaveRole creates role with its permissions in one transaction.
+func (s *Storage) SaveRole(ctx context.Context, tenantID int64, role models.Role) (context.Context, int64, error) {
+	ctx, span := tracer.Start(ctx, "data layer Patroni: SaveRole",
+		trace.WithAttributes(attribute.String("handler", "SaveRole")))
+	defer span.End()
+
+	tx, err := s.dbWrite.BeginTx(ctx, nil)
+	if err != nil {
+		return ctx, 0, fmt.Errorf("DATA LAYER: storage.postgres.SaveRole: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var id int64
+	query := "INSERT INTO roles(tenant_id, name, description) VALUES($1, $2, $3) RETURNING id"
+	err = tx.QueryRowContext(ctx, query, tenantID, role.Name, role.Description).Scan(&id)
+	if err, ok := err.(*pgconn.PgError); ok {
+		if err.Code == ErrCodeUserAlreadyExists {
+			return ctx, 0, storage.ErrRoleExists
+		}
+	}
+	if err != nil {
+		return ctx, 0, fmt.Errorf("DATA LAYER: storage.postgres.SaveRole: %w", err)
+	}
+
+	query = "INSERT INTO role_permissions(role_id, action, resource) VALUES($1, $2, $3) ON CONFLICT DO NOTHING"
+	for _, permission := range role.Permissions {
+		if _, err := tx.ExecContext(ctx, query, id, permission.Action, permission.Resource); err != nil {
+			return ctx, 0, fmt.Errorf("DATA LAYER: storage.postgres.SaveRole: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return ctx, 0, fmt.Errorf("DATA LAYER: storage.postgres.SaveRole: %w", err)
+	}
+	return ctx, id, nil
+}
+
+// GrantRole binds role of the tenant to user, granting already granted role is not an error.
+func (s *Storage) GrantRole(ctx context.Context, tenantID int64, userID int64, roleName string) (context.Context, error) {
+	ctx, span := tracer.Start(ctx, "data layer Patroni: GrantRole",
+		trace.WithAttributes(attribute.String("handler", "GrantRole")))
+	defer span.End()
+
+	query := `INSERT INTO user_roles(user_id, role_id)
+		SELECT $1, id FROM roles WHERE tenant_id = $2 AND name = $3
+		ON CONFLICT DO NOTHING`
+	res, err := s.dbWrite.ExecContext(ctx, query, userID, tenantID, roleName)
+	if err != nil {
+		return ctx, fmt.Errorf("DATA LAYER: storage.postgres.GrantRole: %w", err)
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		// either role doesn't exist or it is already granted
+		var exists bool
+		query = "SELECT EXISTS(SELECT 1 FROM roles WHERE tenant_id = $1 AND name = $2)"
+		err = s.dbWrite.QueryRowContext(ctx, query, tenantID, roleName).Scan(&exists)
+		if err != nil {
+			return ctx, fmt.Errorf("DATA LAYER: storage.postgres.GrantRole: %w", err)
+		}
+		if !exists {
+			return ctx, fmt.Errorf("DATA LAYER: storage.postgres.GrantRole: %w", storage.ErrRoleNotFound)
+		}
+	}
+	return ctx, nil
+}
+
+// RevokeRole unbinds role of the tenant from user, revoking not granted role is not an error.
+func (s *Storage) RevokeRole(ctx context.Context, tenantID int64, userID int64, roleName string) (context.Context, error) {
+	ctx, span := tracer.Start(ctx, "data layer Patroni: RevokeRole",
+		trace.WithAttributes(attribute.String("handler", "RevokeRole")))
+	defer span.End()
+
+	var exists bool
+	query := "SELECT EXISTS(SELECT 1 FROM roles WHERE tenant_id = $1 AND name = $2)"
+	err := s.dbWrite.QueryRowContext(ctx, query, tenantID, roleName).Scan(&exists)
+	if err != nil {
+		return ctx, fmt.Errorf("DATA LAYER: storage.postgres.RevokeRole: %w", err)
+	}
+	if !exists {
+		return ctx, fmt.Errorf("DATA LAYER: storage.postgres.RevokeRole: %w", storage.ErrRoleNotFound)
+	}
+
+	query = `DELETE FROM user_roles
+		WHERE user_id = $1 AND role_id = (SELECT id FROM roles WHERE tenant_id = $2 AND name = $3)`
+	if _, err := s.dbWrite.ExecContext(ctx, query, userID, tenantID, roleName); err != nil {
+		return ctx, fmt.Errorf("DATA LAYER: storage.postgres.RevokeRole: %w", err)
+	}
+	return ctx, nil
+}
+
+// UserRoles returns roles of the tenant bound to user with their permissions.
+func (s *Storage) UserRoles(ctx context.Context, tenantID int64, userID int64) (context.Context, []models.Role, error) {
+	ctx, span := tracer.Start(ctx, "data layer Patroni: UserRoles",
+		trace.WithAttributes(attribute.String("handler", "UserRoles")))
+	defer span.End()
+
+	query := `SELECT r.id, r.name, r.description, rp.action, rp.resource
+		FROM user_roles ur
+		JOIN roles r ON r.id = ur.role_id
+		LEFT JOIN role_permissions rp ON rp.role_id = r.id
+		WHERE ur.user_id = $1 AND r.tenant_id = $2
+		ORDER BY r.name, rp.action, rp.resource`
+	rows, err := s.dbRead.QueryContext(ctx, query, userID, tenantID)
+	if err != nil {
+		return ctx, nil, fmt.Errorf("DATA LAYER: storage.postgres.UserRoles: %w", err)
+	}
+	defer rows.Close()
+
+	roles := make([]models.Role, 0)
+	for rows.Next() {
+		var role models.Role
+		var action, resource sql.NullString
+		if err := rows.Scan(&role.ID, &role.Name, &role.Description, &action, &resource); err != nil {
+			return ctx, nil, fmt.Errorf("DATA LAYER: storage.postgres.UserRoles: %w", err)
+		}
+		if len(roles) == 0 || roles[len(roles)-1].ID != role.ID {
+			roles = append(roles, role)
+		}
+		if action.Valid {
+			last := &roles[len(roles)-1]
+			last.Permissions = append(last.Permissions, models.Permission{
+				Action:   action.String,
+				Resource: resource.String,
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return ctx, nil, fmt.Errorf("DATA LAYER: storage.postgres.UserRoles: %w", err)
+	}
+	return ctx, roles, nil
+}
+
+// WriteTuples stores relation tuples in one transaction.
+func (s *Storage) WriteTuples(ctx context.Context, tenantID int64, tuples []models.Tuple) (context.Context, error) {
+	ctx, span := tracer.Start(ctx, "data layer Patroni: WriteTuples",
+		trace.WithAttributes(attribute.String("handler", "WriteTuples")))
+	defer span.End()
+
+	query := `INSERT INTO relation_tuples(tenant_id, object_type, object_id, relation, subject)
+		VALUES($1, $2, $3, $4, $5) ON CONFLICT DO NOTHING`
+	return ctx, s.execTuples(ctx, query, tenantID, tuples, "WriteTuples")
+}
+
+// DeleteTuples deletes relation tuples in one transaction, missing tuples are skipped.
+func (s *Storage) DeleteTuples(ctx context.Context, tenantID int64, tuples []models.Tuple) (context.Context, error) {
+	ctx, span := tracer.Start(ctx, "data layer Patroni: DeleteTuples",
+		trace.WithAttributes(attribute.String("handler", "DeleteTuples")))
+	defer span.End()
+
+	query := `DELETE FROM relation_tuples
+		WHERE tenant_id = $1 AND object_type = $2 AND object_id = $3 AND relation = $4 AND subject = $5`
+	return ctx, s.execTuples(ctx, query, tenantID, tuples, "DeleteTuples")
+}
+
+func (s *Storage) execTuples(ctx context.Context, query string, tenantID int64, tuples []models.Tuple, handler string) error {
+	tx, err := s.dbWrite.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("DATA LAYER: storage.postgres.%s: %w", handler, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, tuple := range tuples {
+		objectType, objectID, _ := strings.Cut(tuple.Object, ":")
+		if _, err := tx.ExecContext(ctx, query, tenantID, objectType, objectID, tuple.Relation, tuple.Subject); err != nil {
+			return fmt.Errorf("DATA LAYER: storage.postgres.%s: %w", handler, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("DATA LAYER: storage.postgres.%s: %w", handler, err)
+	}
+	return nil
+}
+
+// Tuples returns tuples of the object with any of relations.
+func (s *Storage) Tuples(ctx context.Context, tenantID int64, object string, relations []string) (context.Context, []models.Tuple, error) {
+	ctx, span := tracer.Start(ctx, "data layer Patroni: Tuples",
+		trace.WithAttributes(attribute.String("handler", "Tuples")))
+	defer span.End()
+
+	objectType, objectID, _ := strings.Cut(object, ":")
+	query := `SELECT object_type, object_id, relation, subject FROM relation_tuples
+		WHERE tenant_id = $1 AND object_type = $2 AND object_id = $3 AND relation = ANY($4)`
+	tuples, err := s.queryTuples(ctx, query, tenantID, objectType, objectID, relations)
+	if err != nil {
+		return ctx, nil, fmt.Errorf("DATA LAYER: storage.postgres.Tuples: %w", err)
+	}
+	return ctx, tuples, nil
+}
+
+// TuplesBySubjects returns tuples granting relations to any of subjects.
+func (s *Storage) TuplesBySubjects(ctx context.Context, tenantID int64, subjects []string) (context.Context, []models.Tuple, error) {
+	ctx, span := tracer.Start(ctx, "data layer Patroni: TuplesBySubjects",
+		trace.WithAttributes(attribute.String("handler", "TuplesBySubjects")))
+	defer span.End()
+
+	query := `SELECT object_type, object_id, relation, subject FROM relation_tuples
+		WHERE tenant_id = $1 AND subject = ANY($2)`
+	tuples, err := s.queryTuples(ctx, query, tenantID, subjects)
+	if err != nil {
+		return ctx, nil, fmt.Errorf("DATA LAYER: storage.postgres.TuplesBySubjects: %w", err)
+	}
+	return ctx, tuples, nil
+}
+
+func (s *Storage) queryTuples(ctx context.Context, query string, args ...any) ([]models.Tuple, error) {
+	rows, err := s.dbRead.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tuples := make([]models.Tuple, 0)
+	for rows.Next() {
+		var objectType, objectID string
+		var tuple models.Tuple
+		if err := rows.Scan(&objectType, &objectID, &tuple.Relation, &tuple.Subject); err != nil {
+			return nil, err
+		}
+		tuple.Object = objectType + ":" + objectID
+		tuples = append(tuples, tuple)
+	}
+	return tuples, rows.Err()
+}
+
+// SaveOrganization creates organization with its admin role, returns its
+// id (tenant id).
+func (s *Storage) SaveOrganization(ctx context.Context, org models.Organization) (context.Context, int64, error) {
+	ctx, span := tracer.Start(ctx, "data layer Patroni: SaveOrganization",
+		trace.WithAttributes(attribute.String("handler", "SaveOrganization")))
+	defer span.End()
+
+	tx, err := s.dbWrite.BeginTx(ctx, nil)
+	if err != nil {
+		return ctx, 0, fmt.Errorf("DATA LAYER: storage.postgres.SaveOrganization: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var id int64
+	query := "INSERT INTO organizations(name, slug) VALUES($1, $2) RETURNING id"
+	err = tx.QueryRowContext(ctx, query, org.Name, org.Slug).Scan(&id)
+	if err, ok := err.(*pgconn.PgError); ok {
+		if err.Code == ErrCodeUserAlreadyExists {
+			return ctx, 0, storage.ErrOrgExists
+		}
+	}
+	if err != nil {
+		return ctx, 0, fmt.Errorf("DATA LAYER: storage.postgres.SaveOrganization: %w", err)
+	}
+	query = `WITH role AS (
+			INSERT INTO roles(tenant_id, name, description) VALUES($1, $2, 'Full access') RETURNING id
+		)
+		INSERT INTO role_permissions(role_id, action, resource) SELECT id, '*', '*' FROM role`
+	if _, err := tx.ExecContext(ctx, query, id, models.AdminRole); err != nil {
+		return ctx, 0, fmt.Errorf("DATA LAYER: storage.postgres.SaveOrganization: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return ctx, 0, fmt.Errorf("DATA LAYER: storage.postgres.SaveOrganization: %w", err)
+	}
+	return ctx, id, nil
+}
+
+// Organization returns organization by id.
+func (s *Storage) Organization(ctx context.Context, id int64) (context.Context, models.Organization, error) {
+	ctx, span := tracer.Start(ctx, "data layer Patroni: Organization",
+		trace.WithAttributes(attribute.String("handler", "Organization")))
+	defer span.End()
+
+	var org models.Organization
+	query := "SELECT id, name, slug, created_at FROM organizations WHERE id = $1"
+	err := s.dbRead.QueryRowContext(ctx, query, id).Scan(&org.ID, &org.Name, &org.Slug, &org.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ctx, models.Organization{}, fmt.Errorf(
+				"DATA LAYER: storage.postgres.Organization: %w",
+				storage.ErrOrgNotFound,
+			)
+		}
+		return ctx, models.Organization{}, fmt.Errorf("DATA LAYER: storage.postgres.Organization: %w", err)
+	}
+	return ctx, org, nil
+}
+
+// SaveMember adds user to organization or changes their role.
+func (s *Storage) SaveMember(ctx context.Context, orgID int64, userID int64, role string) (context.Context, error) {
+	ctx, span := tracer.Start(ctx, "data layer Patroni: SaveMember",
+		trace.WithAttributes(attribute.String("handler", "SaveMember")))
+	defer span.End()
+
+	query := `INSERT INTO organization_members(org_id, user_id, role) VALUES($1, $2, $3)
+		ON CONFLICT (org_id, user_id) DO UPDATE SET role = EXCLUDED.role`
+	_, err := s.dbWrite.ExecContext(ctx, query, orgID, userID, role)
+	if err, ok := err.(*pgconn.PgError); ok {
+		if err.Code == ErrCodeForeignKeyViolation {
+			return ctx, storage.ErrOrgNotFound
+		}
+	}
+	if err != nil {
+		return ctx, fmt.Errorf("DATA LAYER: storage.postgres.SaveMember: %w", err)
+	}
+	return ctx, nil
+}
+
+// DeleteMember removes user from organization.
+func (s *Storage) DeleteMember(ctx context.Context, orgID int64, userID int64) (context.Context, error) {
+	ctx, span := tracer.Start(ctx, "data layer Patroni: DeleteMember",
+		trace.WithAttributes(attribute.String("handler", "DeleteMember")))
+	defer span.End()
+
+	query := "DELETE FROM organization_members WHERE org_id = $1 AND user_id = $2"
+	res, err := s.dbWrite.ExecContext(ctx, query, orgID, userID)
+	if err != nil {
+		return ctx, fmt.Errorf("DATA LAYER: storage.postgres.DeleteMember: %w", err)
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return ctx, fmt.Errorf("DATA LAYER: storage.postgres.DeleteMember: %w", storage.ErrMemberNotFound)
+	}
+	return ctx, nil
+}
+
+// MemberRole returns role of the user in organization.
+func (s *Storage) MemberRole(ctx context.Context, orgID int64, userID int64) (context.Context, string, error) {
+	ctx, span := tracer.Start(ctx, "data layer Patroni: MemberRole",
+		trace.WithAttributes(attribute.String("handler", "MemberRole")))
+	defer span.End()
+
+	var role string
+	query := "SELECT role FROM organization_members WHERE org_id = $1 AND user_id = $2"
+	err := s.dbRead.QueryRowContext(ctx, query, orgID, userID).Scan(&role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ctx, "", fmt.Errorf("DATA LAYER: storage.postgres.MemberRole: %w", storage.ErrMemberNotFound)
+		}
+		return ctx, "", fmt.Errorf("DATA LAYER: storage.postgres.MemberRole: %w", err)
+	}
+	return ctx, role, nil
+}
+
+// UserOrganizations returns organizations the user is a member of.
+func (s *Storage) UserOrganizations(ctx context.Context, userID int64) (context.Context, []models.OrgMembership, error) {
+	ctx, span := tracer.Start(ctx, "data layer Patroni: UserOrganizations",
+		trace.WithAttributes(attribute.String("handler", "UserOrganizations")))
+	defer span.End()
+
+	query := `SELECT o.id, o.name, o.slug, o.created_at, m.role
+		FROM organization_members m
+		JOIN organizations o ON o.id = m.org_id
+		WHERE m.user_id = $1
+		ORDER BY o.id`
+	rows, err := s.dbRead.QueryContext(ctx, query, userID)
+	if err != nil {
+		return ctx, nil, fmt.Errorf("DATA LAYER: storage.postgres.UserOrganizations: %w", err)
+	}
+	defer rows.Close()
+
+	memberships := make([]models.OrgMembership, 0)
+	for rows.Next() {
+		membership := models.OrgMembership{UserID: userID}
+		org := &membership.Organization
+		if err := rows.Scan(&org.ID, &org.Name, &org.Slug, &org.CreatedAt, &membership.Role); err != nil {
+			return ctx, nil, fmt.Errorf("DATA LAYER: storage.postgres.UserOrganizations: %w", err)
+		}
+		memberships = append(memberships, membership)
+	}
+	if err := rows.Err(); err != nil {
+		return ctx, nil, fmt.Errorf("DATA LAYER: storage.postgres.UserOrganizations: %w", err)
+	}
+	return ctx, memberships, nil
+}
+
+// SaveGroup creates group within tenant, returns its id.
+func (s *Storage) SaveGroup(ctx context.Context, group models.Group) (context.Context, int64, error) {
+	ctx, span := tracer.Start(ctx, "data layer Patroni: SaveGroup",
+		trace.WithAttributes(attribute.String("handler", "SaveGroup")))
+	defer span.End()
+
+	var id int64
+	query := "INSERT INTO groups(tenant_id, name) VALUES($1, $2) RETURNING id"
+	err := s.dbWrite.QueryRowContext(ctx, query, group.TenantID, group.Name).Scan(&id)
+	if err, ok := err.(*pgconn.PgError); ok {
+		switch err.Code {
+		case ErrCodeUserAlreadyExists:
+			return ctx, 0, storage.ErrGroupExists
+		case ErrCodeForeignKeyViolation:
+			return ctx, 0, storage.ErrOrgNotFound
+		}
+	}
+	if err != nil {
+		return ctx, 0, fmt.Errorf("DATA LAYER: storage.postgres.SaveGroup: %w", err)
+	}
+	return ctx, id, nil
+}
+
+// Group returns group by id.
+func (s *Storage) Group(ctx context.Context, id int64) (context.Context, models.Group, error) {
+	ctx, span := tracer.Start(ctx, "data layer Patroni: Group",
+		trace.WithAttributes(attribute.String("handler", "Group")))
+	defer span.End()
+
+	var group models.Group
+	query := "SELECT id, tenant_id, name, created_at FROM groups WHERE id = $1"
+	err := s.dbRead.QueryRowContext(ctx, query, id).Scan(&group.ID, &group.TenantID, &group.Name, &group.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ctx, models.Group{}, fmt.Errorf("DATA LAYER: storage.postgres.Group: %w", storage.ErrGroupNotFound)
+		}
+		return ctx, models.Group{}, fmt.Errorf("DATA LAYER: storage.postgres.Group: %w", err)
+	}
+	return ctx, group, nil
+}
+
+// AddGroupMember adds user to group, existing membership is kept.
+func (s *Storage) AddGroupMember(ctx context.Context, groupID int64, userID int64) (context.Context, error) {
+	ctx, span := tracer.Start(ctx, "data layer Patroni: AddGroupMember",
+		trace.WithAttributes(attribute.String("handler", "AddGroupMember")))
+	defer span.End()
+
+	query := "INSERT INTO group_members(group_id, user_id) VALUES($1, $2) ON CONFLICT DO NOTHING"
+	_, err := s.dbWrite.ExecContext(ctx, query, groupID, userID)
+	if err, ok := err.(*pgconn.PgError); ok {
+		if err.Code == ErrCodeForeignKeyViolation {
+			return ctx, storage.ErrGroupNotFound
+		}
+	}
+	if err != nil {
+		return ctx, fmt.Errorf("DATA LAYER: storage.postgres.AddGroupMember: %w", err)
+	}
+	return ctx, nil
+}
+
+func (s *Storage) RemoveGroupMember(ctx context.Context, groupID int64, userID int64) (context.Context, error) {
+	ctx, span := tracer.Start(ctx, "data layer Patroni: RemoveGroupMember",
+		trace.WithAttributes(attribute.String("handler", "RemoveGroupMember")))
+	defer span.End()
+
+	query := "DELETE FROM group_members WHERE group_id = $1 AND user_id = $2"
+	if _, err := s.dbWrite.ExecContext(ctx, query, groupID, userID); err != nil {
+		return ctx, fmt.Errorf("DATA LAYER: storage.postgres.RemoveGroupMember: %w", err)
+	}
+	return ctx, nil
+}
+
+// AddSubgroup nests child into parent. Changes of nesting are serialized
+// with an advisory lock, so two concurrent calls can't build a cycle
+// neither of them sees.
+func (s *Storage) AddSubgroup(ctx context.Context, parentID int64, childID int64) (context.Context, error) {
+	ctx, span := tracer.Start(ctx, "data layer Patroni: AddSubgroup",
+		trace.WithAttributes(attribute.String("handler", "AddSubgroup")))
+	defer span.End()
+
+	if parentID == childID {
+		return ctx, storage.ErrGroupCycle
+	}
+	tx, err := s.dbWrite.BeginTx(ctx, nil)
+	if err != nil {
+		return ctx, fmt.Errorf("DATA LAYER: storage.postgres.AddSubgroup: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('group_subgroups'))"); err != nil {
+		return ctx, fmt.Errorf("DATA LAYER: storage.postgres.AddSubgroup: %w", err)
+	}
+	// parent must not be reachable from child, otherwise the new edge closes a cycle
+	var cycle bool
+	query := `WITH RECURSIVE descendants(id) AS (
+			SELECT child_id FROM group_subgroups WHERE parent_id = $1
+			UNION
+			SELECT s.child_id FROM group_subgroups s JOIN descendants d ON s.parent_id = d.id
+		)
+		SELECT EXISTS(SELECT 1 FROM descendants WHERE id = $2)`
+	if err := tx.QueryRowContext(ctx, query, childID, parentID).Scan(&cycle); err != nil {
+		return ctx, fmt.Errorf("DATA LAYER: storage.postgres.AddSubgroup: %w", err)
+	}
+	if cycle {
+		return ctx, storage.ErrGroupCycle
+	}
+	query = "INSERT INTO group_subgroups(parent_id, child_id) VALUES($1, $2) ON CONFLICT DO NOTHING"
+	_, err = tx.ExecContext(ctx, query, parentID, childID)
+	if err, ok := err.(*pgconn.PgError); ok {
+		if err.Code == ErrCodeForeignKeyViolation {
+			return ctx, storage.ErrGroupNotFound
+		}
+	}
+	if err != nil {
+		return ctx, fmt.Errorf("DATA LAYER: storage.postgres.AddSubgroup: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return ctx, fmt.Errorf("DATA LAYER: storage.postgres.AddSubgroup: %w", err)
+	}
+	return ctx, nil
+}
+
+func (s *Storage) RemoveSubgroup(ctx context.Context, parentID int64, childID int64) (context.Context, error) {
+	ctx, span := tracer.Start(ctx, "data layer Patroni: RemoveSubgroup",
+		trace.WithAttributes(attribute.String("handler", "RemoveSubgroup")))
+	defer span.End()
+
+	query := "DELETE FROM group_subgroups WHERE parent_id = $1 AND child_id = $2"
+	if _, err := s.dbWrite.ExecContext(ctx, query, parentID, childID); err != nil {
+		return ctx, fmt.Errorf("DATA LAYER: storage.postgres.RemoveSubgroup: %w", err)
+	}
+	return ctx, nil
+}
+
+// UserGroups walks nesting from groups of the user upwards. UNION drops
+// already visited groups, so the walk ends even on a cycle.
+func (s *Storage) UserGroups(ctx context.Context, userID int64) (context.Context, []models.Group, error) {
+	ctx, span := tracer.Start(ctx, "data layer Patroni: UserGroups",
+		trace.WithAttributes(attribute.String("handler", "UserGroups")))
+	defer span.End()
+
+	query := `WITH RECURSIVE effective(id) AS (
+			SELECT group_id FROM group_members WHERE user_id = $1
+			UNION
+			SELECT s.parent_id FROM group_subgroups s JOIN effective e ON s.child_id = e.id
+		)
+		SELECT g.id, g.tenant_id, g.name, g.created_at
+		FROM groups g
+		JOIN effective e ON e.id = g.id
+		ORDER BY g.name`
+	rows, err := s.dbRead.QueryContext(ctx, query, userID)
+	if err != nil {
+		return ctx, nil, fmt.Errorf("DATA LAYER: storage.postgres.UserGroups: %w", err)
+	}
+	defer rows.Close()
+
+	groups := make([]models.Group, 0)
+	for rows.Next() {
+		var group models.Group
+		if err := rows.Scan(&group.ID, &group.TenantID, &group.Name, &group.CreatedAt); err != nil {
+			return ctx, nil, fmt.Errorf("DATA LAYER: storage.postgres.UserGroups: %w", err)
+		}
+		groups = append(groups, group)
+	}
+	if err := rows.Err(); err != nil {
+		return ctx, nil, fmt.Errorf("DATA LAYER: storage.postgres.UserGroups: %w", err)
+	}
+	return ctx, groups, nil
+}
@@ -2,10 +2,18 @@ package redis
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"sso/internal/config"
+	"sso/internal/domain/models"
+	"sso/storage"
+	"strconv"
 	"time"
 )
 
@@ -16,8 +24,8 @@ type Cache struct {
 func New(cfg *config.Config) *Cache {
 	redisClient := redis.NewClient(&redis.Options{
 		Addr:     cfg.RedisAddress,
-		Password: "", // no password set
-		DB:       0,  // use default DB
+		Password: cfg.RedisPassword,
+		DB:       0, // use default DB
 	})
 	// Enable tracing instrumentation.
 	if err := redisotel.InstrumentTracing(redisClient); err != nil {
@@ -31,6 +39,51 @@ func New(cfg *config.Config) *Cache {
 	return &Cache{client: redisClient}
 }
 
+var tracer = otel.Tracer("sso service")
+
+const (
+	usedTokenPrefix     = "used:"
+	revokedFamilyPrefix = "family:"
+	revokedBeforePrefix = "revoked_before:"
+	// watermark of tokens without session
+	sessionlessRevokedBeforePrefix = "sessionless_revoked_before:"
+	sessionPrefix                  = "session:"
+	userSessionsPrefix             = "user_sessions:"
+	authCodePrefix                 = "auth_code:"
+	loginFailuresPrefix            = "login_failures:"
+	loginLockPrefix                = "login_lock:"
+	rateLimitPrefix                = "rate_limit:"
+)
+
+// takeTokenScript implements token bucket, the bucket is a hash of tokens
+// left and time of the last refill. Time of redis is used, so clocks of
+// instances don't matter.
+var takeTokenScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+  tokens = burst
+  ts = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  wait = math.ceil((1 - tokens) / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate))
+return {allowed, wait}
+`)
+
 //
 //func TestNew() *Cache {
 //	redisClient := redis.NewClient(&redis.Options{
@@ -45,41 +98,490 @@ func (s *Cache) SaveToken(
 	ctx context.Context,
 	token string,
 	ttl time.Duration,
-) error {
+) (context.Context, error) {
 	const op = "DATA LAYER: storage.redis.SaveToken"
 
+	ctx, span := tracer.Start(ctx, "data layer Redis: SaveToken",
+		trace.WithAttributes(attribute.String("handler", "SaveToken")))
+	defer span.End()
+
 	err := s.client.Set(ctx, token, true, ttl).Err()
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return ctx, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return nil
+	return ctx, nil
 }
 
 func (s *Cache) GetToken(
 	ctx context.Context,
 	token string,
-) (string, error) {
+) (context.Context, string, error) {
 	const op = "DATA LAYER: storage.redis.GetToken"
 
+	ctx, span := tracer.Start(ctx, "data layer Redis: GetToken",
+		trace.WithAttributes(attribute.String("handler", "GetToken")))
+	defer span.End()
+
 	val, err := s.client.Get(ctx, token).Result()
 	if err != nil {
-		return "", fmt.Errorf("%s: %w", op, err)
+		return ctx, "", fmt.Errorf("%s: %w", op, err)
 	}
-	return val, nil
+	return ctx, val, nil
 }
 
 func (s *Cache) CheckTokenExists(
 	ctx context.Context,
 	token string,
-) (int64, error) {
+) (context.Context, int64, error) {
 	const op = "DATA LAYER: storage.redis.CheckTokenExists"
 
+	ctx, span := tracer.Start(ctx, "data layer Redis: CheckTokenExists",
+		trace.WithAttributes(attribute.String("handler", "CheckTokenExists")))
+	defer span.End()
+
 	val, err := s.client.Exists(ctx, token).Result()
 	if err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
+		return ctx, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return ctx, val, nil
+}
+
+func (s *Cache) MarkTokenUsed(
+	ctx context.Context,
+	tokenID string,
+	ttl time.Duration,
+) (context.Context, bool, error) {
+	const op = "DATA LAYER: storage.redis.MarkTokenUsed"
+
+	ctx, span := tracer.Start(ctx, "data layer Redis: MarkTokenUsed",
+		trace.WithAttributes(attribute.String("handler", "MarkTokenUsed")))
+	defer span.End()
+
+	// SetNX is atomic, so only one of concurrent refreshes wins
+	firstUse, err := s.client.SetNX(ctx, usedTokenPrefix+tokenID, true, ttl).Result()
+	if err != nil {
+		return ctx, false, fmt.Errorf("%s: %w", op, err)
+	}
+	return ctx, firstUse, nil
+}
+
+func (s *Cache) RevokeFamily(
+	ctx context.Context,
+	familyID string,
+	ttl time.Duration,
+) (context.Context, error) {
+	const op = "DATA LAYER: storage.redis.RevokeFamily"
+
+	ctx, span := tracer.Start(ctx, "data layer Redis: RevokeFamily",
+		trace.WithAttributes(attribute.String("handler", "RevokeFamily")))
+	defer span.End()
+
+	err := s.client.Set(ctx, revokedFamilyPrefix+familyID, true, ttl).Err()
+	if err != nil {
+		return ctx, fmt.Errorf("%s: %w", op, err)
+	}
+	return ctx, nil
+}
+
+func (s *Cache) CheckFamilyRevoked(
+	ctx context.Context,
+	familyID string,
+) (context.Context, bool, error) {
+	const op = "DATA LAYER: storage.redis.CheckFamilyRevoked"
+
+	ctx, span := tracer.Start(ctx, "data layer Redis: CheckFamilyRevoked",
+		trace.WithAttributes(attribute.String("handler", "CheckFamilyRevoked")))
+	defer span.End()
+
+	val, err := s.client.Exists(ctx, revokedFamilyPrefix+familyID).Result()
+	if err != nil {
+		return ctx, false, fmt.Errorf("%s: %w", op, err)
+	}
+	return ctx, val == 1, nil
+}
+
+func (s *Cache) SetRevokedBefore(
+	ctx context.Context,
+	userID int64,
+	revokedBefore time.Time,
+	ttl time.Duration,
+) (context.Context, error) {
+	const op = "DATA LAYER: storage.redis.SetRevokedBefore"
+
+	ctx, span := tracer.Start(ctx, "data layer Redis: SetRevokedBefore",
+		trace.WithAttributes(attribute.String("handler", "SetRevokedBefore")))
+	defer span.End()
+
+	key := revokedBeforePrefix + strconv.FormatInt(userID, 10)
+	err := s.client.Set(ctx, key, revokedBefore.Unix(), ttl).Err()
+	if err != nil {
+		return ctx, fmt.Errorf("%s: %w", op, err)
+	}
+	return ctx, nil
+}
+
+func (s *Cache) GetRevokedBefore(
+	ctx context.Context,
+	userID int64,
+) (context.Context, time.Time, error) {
+	const op = "DATA LAYER: storage.redis.GetRevokedBefore"
+
+	ctx, span := tracer.Start(ctx, "data layer Redis: GetRevokedBefore",
+		trace.WithAttributes(attribute.String("handler", "GetRevokedBefore")))
+	defer span.End()
+
+	key := revokedBeforePrefix + strconv.FormatInt(userID, 10)
+	val, err := s.client.Get(ctx, key).Int64()
+	if errors.Is(err, redis.Nil) {
+		return ctx, time.Time{}, nil
+	}
+	if err != nil {
+		return ctx, time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return ctx, time.Unix(val, 0), nil
+}
+
+func (s *Cache) SetSessionlessRevokedBefore(
+	ctx context.Context,
+	userID int64,
+	revokedBefore time.Time,
+	ttl time.Duration,
+) (context.Context, error) {
+	const op = "DATA LAYER: storage.redis.SetSessionlessRevokedBefore"
+
+	ctx, span := tracer.Start(ctx, "data layer Redis: SetSessionlessRevokedBefore",
+		trace.WithAttributes(attribute.String("handler", "SetSessionlessRevokedBefore")))
+	defer span.End()
+
+	key := sessionlessRevokedBeforePrefix + strconv.FormatInt(userID, 10)
+	err := s.client.Set(ctx, key, revokedBefore.Unix(), ttl).Err()
+	if err != nil {
+		return ctx, fmt.Errorf("%s: %w", op, err)
+	}
+	return ctx, nil
+}
+
+func (s *Cache) GetSessionlessRevokedBefore(
+	ctx context.Context,
+	userID int64,
+) (context.Context, time.Time, error) {
+	const op = "DATA LAYER: storage.redis.GetSessionlessRevokedBefore"
+
+	ctx, span := tracer.Start(ctx, "data layer Redis: GetSessionlessRevokedBefore",
+		trace.WithAttributes(attribute.String("handler", "GetSessionlessRevokedBefore")))
+	defer span.End()
+
+	key := sessionlessRevokedBeforePrefix + strconv.FormatInt(userID, 10)
+	val, err := s.client.Get(ctx, key).Int64()
+	if errors.Is(err, redis.Nil) {
+		return ctx, time.Time{}, nil
+	}
+	if err != nil {
+		return ctx, time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return ctx, time.Unix(val, 0), nil
+}
+
+func (s *Cache) SaveSession(
+	ctx context.Context,
+	session models.Session,
+	ttl time.Duration,
+) (context.Context, error) {
+	const op = "DATA LAYER: storage.redis.SaveSession"
+
+	ctx, span := tracer.Start(ctx, "data layer Redis: SaveSession",
+		trace.WithAttributes(attribute.String("handler", "SaveSession")))
+	defer span.End()
+
+	value, err := json.Marshal(session)
+	if err != nil {
+		return ctx, fmt.Errorf("%s: %w", op, err)
+	}
+	userKey := userSessionsPrefix + strconv.FormatInt(session.UserID, 10)
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, sessionPrefix+session.ID, value, ttl)
+		pipe.SAdd(ctx, userKey, session.ID)
+		// index lives as long as the freshest session of the user
+		pipe.Expire(ctx, userKey, ttl)
+		return nil
+	})
+	if err != nil {
+		return ctx, fmt.Errorf("%s: %w", op, err)
+	}
+	return ctx, nil
+}
+
+func (s *Cache) GetSession(
+	ctx context.Context,
+	sessionID string,
+) (context.Context, models.Session, error) {
+	const op = "DATA LAYER: storage.redis.GetSession"
+
+	ctx, span := tracer.Start(ctx, "data layer Redis: GetSession",
+		trace.WithAttributes(attribute.String("handler", "GetSession")))
+	defer span.End()
+
+	value, err := s.client.Get(ctx, sessionPrefix+sessionID).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return ctx, models.Session{}, fmt.Errorf("%s: %w", op, storage.ErrSessionNotFound)
+	}
+	if err != nil {
+		return ctx, models.Session{}, fmt.Errorf("%s: %w", op, err)
+	}
+	var session models.Session
+	if err := json.Unmarshal(value, &session); err != nil {
+		return ctx, models.Session{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return ctx, session, nil
+}
+
+func (s *Cache) ListSessions(
+	ctx context.Context,
+	userID int64,
+) (context.Context, []models.Session, error) {
+	const op = "DATA LAYER: storage.redis.ListSessions"
+
+	ctx, span := tracer.Start(ctx, "data layer Redis: ListSessions",
+		trace.WithAttributes(attribute.String("handler", "ListSessions")))
+	defer span.End()
+
+	userKey := userSessionsPrefix + strconv.FormatInt(userID, 10)
+	sessionIDs, err := s.client.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return ctx, nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(sessionIDs) == 0 {
+		return ctx, []models.Session{}, nil
+	}
+
+	keys := make([]string, 0, len(sessionIDs))
+	for _, sessionID := range sessionIDs {
+		keys = append(keys, sessionPrefix+sessionID)
+	}
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return ctx, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sessions := make([]models.Session, 0, len(values))
+	expired := make([]any, 0)
+	for i, value := range values {
+		raw, ok := value.(string)
+		if !ok {
+			// session expired, but index still references it
+			expired = append(expired, sessionIDs[i])
+			continue
+		}
+		var session models.Session
+		if err := json.Unmarshal([]byte(raw), &session); err != nil {
+			return ctx, nil, fmt.Errorf("%s: %w", op, err)
+		}
+		sessions = append(sessions, session)
+	}
+	if len(expired) > 0 {
+		if err := s.client.SRem(ctx, userKey, expired...).Err(); err != nil {
+			return ctx, nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	return ctx, sessions, nil
+}
+
+func (s *Cache) DeleteSession(
+	ctx context.Context,
+	sessionID string,
+	userID int64,
+) (context.Context, error) {
+	const op = "DATA LAYER: storage.redis.DeleteSession"
+
+	ctx, span := tracer.Start(ctx, "data layer Redis: DeleteSession",
+		trace.WithAttributes(attribute.String("handler", "DeleteSession")))
+	defer span.End()
+
+	userKey := userSessionsPrefix + strconv.FormatInt(userID, 10)
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, sessionPrefix+sessionID)
+		pipe.SRem(ctx, userKey, sessionID)
+		return nil
+	})
+	if err != nil {
+		return ctx, fmt.Errorf("%s: %w", op, err)
+	}
+	return ctx, nil
+}
+
+func (s *Cache) DeleteUserSessions(
+	ctx context.Context,
+	userID int64,
+) (context.Context, error) {
+	const op = "DATA LAYER: storage.redis.DeleteUserSessions"
+
+	ctx, span := tracer.Start(ctx, "data layer Redis: DeleteUserSessions",
+		trace.WithAttributes(attribute.String("handler", "DeleteUserSessions")))
+	defer span.End()
+
+	userKey := userSessionsPrefix + strconv.FormatInt(userID, 10)
+	sessionIDs, err := s.client.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return ctx, fmt.Errorf("%s: %w", op, err)
+	}
+	keys := make([]string, 0, len(sessionIDs)+1)
+	for _, sessionID := range sessionIDs {
+		keys = append(keys, sessionPrefix+sessionID)
+	}
+	keys = append(keys, userKey)
+	if err := s.client.Del(ctx, keys...).Err(); err != nil {
+		return ctx, fmt.Errorf("%s: %w", op, err)
+	}
+	return ctx, nil
+}
+
+func (s *Cache) SaveAuthCode(
+	ctx context.Context,
+	code string,
+	authCode models.AuthCode,
+	ttl time.Duration,
+) (context.Context, error) {
+	const op = "DATA LAYER: storage.redis.SaveAuthCode"
+
+	ctx, span := tracer.Start(ctx, "data layer Redis: SaveAuthCode",
+		trace.WithAttributes(attribute.String("handler", "SaveAuthCode")))
+	defer span.End()
+
+	value, err := json.Marshal(authCode)
+	if err != nil {
+		return ctx, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := s.client.Set(ctx, authCodePrefix+code, value, ttl).Err(); err != nil {
+		return ctx, fmt.Errorf("%s: %w", op, err)
+	}
+	return ctx, nil
+}
+
+func (s *Cache) TakeAuthCode(
+	ctx context.Context,
+	code string,
+) (context.Context, models.AuthCode, error) {
+	const op = "DATA LAYER: storage.redis.TakeAuthCode"
+
+	ctx, span := tracer.Start(ctx, "data layer Redis: TakeAuthCode",
+		trace.WithAttributes(attribute.String("handler", "TakeAuthCode")))
+	defer span.End()
+
+	// GETDEL is atomic, so concurrent exchanges of one code can't both succeed
+	value, err := s.client.GetDel(ctx, authCodePrefix+code).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return ctx, models.AuthCode{}, fmt.Errorf("%s: %w", op, storage.ErrAuthCodeNotFound)
+	}
+	if err != nil {
+		return ctx, models.AuthCode{}, fmt.Errorf("%s: %w", op, err)
+	}
+	var authCode models.AuthCode
+	if err := json.Unmarshal(value, &authCode); err != nil {
+		return ctx, models.AuthCode{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return ctx, authCode, nil
+}
+
+func (s *Cache) RegisterFailure(
+	ctx context.Context,
+	key string,
+	window time.Duration,
+) (context.Context, int64, error) {
+	const op = "DATA LAYER: storage.redis.RegisterFailure"
+
+	ctx, span := tracer.Start(ctx, "data layer Redis: RegisterFailure",
+		trace.WithAttributes(attribute.String("handler", "RegisterFailure")))
+	defer span.End()
+
+	// every failure prolongs the window, so the counter is forgotten only
+	// after window without failures
+	var incr *redis.IntCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, loginFailuresPrefix+key)
+		pipe.Expire(ctx, loginFailuresPrefix+key, window)
+		return nil
+	})
+	if err != nil {
+		return ctx, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return ctx, incr.Val(), nil
+}
+
+func (s *Cache) ResetFailures(
+	ctx context.Context,
+	key string,
+) (context.Context, error) {
+	const op = "DATA LAYER: storage.redis.ResetFailures"
+
+	ctx, span := tracer.Start(ctx, "data layer Redis: ResetFailures",
+		trace.WithAttributes(attribute.String("handler", "ResetFailures")))
+	defer span.End()
+
+	err := s.client.Del(ctx, loginFailuresPrefix+key, loginLockPrefix+key).Err()
+	if err != nil {
+		return ctx, fmt.Errorf("%s: %w", op, err)
+	}
+	return ctx, nil
+}
+
+func (s *Cache) Lock(
+	ctx context.Context,
+	key string,
+	duration time.Duration,
+) (context.Context, error) {
+	const op = "DATA LAYER: storage.redis.Lock"
+
+	ctx, span := tracer.Start(ctx, "data layer Redis: Lock",
+		trace.WithAttributes(attribute.String("handler", "Lock")))
+	defer span.End()
+
+	err := s.client.Set(ctx, loginLockPrefix+key, true, duration).Err()
+	if err != nil {
+		return ctx, fmt.Errorf("%s: %w", op, err)
+	}
+	return ctx, nil
+}
+
+func (s *Cache) LockedFor(
+	ctx context.Context,
+	key string,
+) (context.Context, time.Duration, error) {
+	const op = "DATA LAYER: storage.redis.LockedFor"
+
+	ctx, span := tracer.Start(ctx, "data layer Redis: LockedFor",
+		trace.WithAttributes(attribute.String("handler", "LockedFor")))
+	defer span.End()
+
+	// PTTL returns a negative duration for missing keys
+	ttl, err := s.client.PTTL(ctx, loginLockPrefix+key).Result()
+	if err != nil {
+		return ctx, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if ttl < 0 {
+		return ctx, 0, nil
+	}
+	return ctx, ttl, nil
+}
+
+func (s *Cache) TakeToken(
+	ctx context.Context,
+	key string,
+	rate float64,
+	burst int,
+) (context.Context, bool, time.Duration, error) {
+	const op = "DATA LAYER: storage.redis.TakeToken"
+
+	ctx, span := tracer.Start(ctx, "data layer Redis: TakeToken",
+		trace.WithAttributes(attribute.String("handler", "TakeToken")))
+	defer span.End()
+
+	// the script expects tokens per millisecond
+	res, err := takeTokenScript.Run(ctx, s.client, []string{rateLimitPrefix + key}, rate/1000, burst).Int64Slice()
+	if err != nil {
+		return ctx, false, 0, fmt.Errorf("%s: %w", op, err)
 	}
-	return val, nil
+	return ctx, res[0] == 1, time.Duration(res[1]) * time.Millisecond, nil
 }
 
 //func main() {
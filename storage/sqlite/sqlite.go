@@ -8,6 +8,7 @@ import (
 	"github.com/mattn/go-sqlite3"
 	"sso/internal/domain/models"
 	"sso/storage"
+	"strings"
 )
 
 type Storage struct {
@@ -30,11 +31,17 @@ func (s *Storage) Stop() error {
 }
 
 // SaveUser saves user to db.
-func (s *Storage) SaveUser(ctx context.Context, email string, passHash []byte) (int64, error) {
+func (s *Storage) SaveUser(ctx context.Context, tenantID int64, email string, passHash []byte) (int64, error) {
 	const op = "DATA LAYER: storage.sqlite.SaveUser"
 
-	query := "INSERT INTO users(email, pass_hash) VALUES(?, ?)"
-	res, err := s.db.ExecContext(ctx, query, email, passHash)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query := "INSERT INTO users(tenant_id, email, pass_hash) VALUES(?, ?, ?)"
+	res, err := tx.ExecContext(ctx, query, tenantID, email, passHash)
 	if err != nil {
 		var sqliteErr sqlite3.Error
 		if errors.As(err, &sqliteErr) && errors.Is(sqliteErr.ExtendedCode, sqlite3.ErrConstraintUnique) {
@@ -48,25 +55,37 @@ func (s *Storage) SaveUser(ctx context.Context, email string, passHash []byte) (
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
+	query = "INSERT INTO organization_members(org_id, user_id, role) VALUES(?, ?, ?)"
+	if _, err := tx.ExecContext(ctx, query, tenantID, id, models.OrgRoleMember); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
 
 	return id, nil
 }
 
-func (s *Storage) GetUser(ctx context.Context, value any) (models.User, error) {
+// selectUser derives is_admin from the admin role of the user's tenant.
+const selectUser = `SELECT id, tenant_id, email, pass_hash, email_verified, disabled, EXISTS(
+	SELECT 1 FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+	WHERE ur.user_id = users.id AND r.tenant_id = users.tenant_id AND r.name = 'admin'
+) FROM users `
+
+func (s *Storage) GetUser(ctx context.Context, tenantID int64, value any) (models.User, error) {
 	const op = "DATA LAYER: storage.sqlite.User"
 	var row *sql.Row
-	query := "SELECT id, email, pass_hash, is_admin FROM users WHERE (email = ? AND ? IS NOT NULL) OR (id = ? AND ? IS NOT NULL);"
+	query := selectUser + "WHERE tenant_id = ? AND ((email = ? AND ? IS NOT NULL) OR (id = ? AND ? IS NOT NULL));"
 	switch sqlParam := value.(type) {
 	case int:
-		row = s.db.QueryRowContext(ctx, query, nil, nil, sqlParam, sqlParam)
+		row = s.db.QueryRowContext(ctx, query, tenantID, nil, nil, sqlParam, sqlParam)
 	case string:
-		row = s.db.QueryRowContext(ctx, query, sqlParam, sqlParam, nil, nil)
+		row = s.db.QueryRowContext(ctx, query, tenantID, sqlParam, sqlParam, nil, nil)
 	default:
 		return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrWrongParamType)
 	}
 
-	var user models.User
-	err := row.Scan(&user.ID, &user.Email, &user.PassHash, &user.IsAdmin)
+	user, err := scanUser(row)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
@@ -77,13 +96,209 @@ func (s *Storage) GetUser(ctx context.Context, value any) (models.User, error) {
 	return user, nil
 }
 
+// ListUsers returns page of users matching the filter.
+func (s *Storage) ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, error) {
+	const op = "DATA LAYER: storage.sqlite.ListUsers"
+
+	query := selectUser + "WHERE tenant_id = ? AND id > ?"
+	args := []any{filter.TenantID, filter.AfterID}
+	if filter.EmailPrefix != "" {
+		query += ` AND email LIKE ? ESCAPE '\'`
+		args = append(args, likePrefix(filter.EmailPrefix))
+	}
+	if filter.IsAdmin != nil {
+		query += ` AND EXISTS(
+			SELECT 1 FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+			WHERE ur.user_id = users.id AND r.tenant_id = users.tenant_id AND r.name = 'admin'
+		) = ?`
+		args = append(args, *filter.IsAdmin)
+	}
+	if filter.Disabled != nil {
+		query += " AND disabled = ?"
+		args = append(args, *filter.Disabled)
+	}
+	query += " ORDER BY id LIMIT ?"
+	args = append(args, filter.Limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	users := make([]models.User, 0, filter.Limit)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return users, nil
+}
+
+// SetDisabled disables or enables the user.
+func (s *Storage) SetDisabled(ctx context.Context, tenantID int64, userID int64, disabled bool) error {
+	const op = "DATA LAYER: storage.sqlite.SetDisabled"
+
+	query := "UPDATE users SET disabled = ? WHERE id = ? AND tenant_id = ?"
+	res, err := s.db.ExecContext(ctx, query, disabled, userID, tenantID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	return nil
+}
+
+// scanUser scans row selected by selectUser.
+func scanUser(row interface{ Scan(dest ...any) error }) (models.User, error) {
+	var user models.User
+	err := row.Scan(&user.ID, &user.TenantID, &user.Email, &user.PassHash, &user.EmailVerified, &user.Disabled, &user.IsAdmin)
+	return user, err
+}
+
+// likePrefix makes LIKE pattern matching values starting with prefix,
+// wildcards in the prefix match literally.
+func likePrefix(prefix string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	return replacer.Replace(prefix) + "%"
+}
+
+// SetEmailVerified marks email of the user as verified.
+func (s *Storage) SetEmailVerified(ctx context.Context, tenantID int64, userID int64) error {
+	const op = "DATA LAYER: storage.sqlite.SetEmailVerified"
+
+	query := "UPDATE users SET email_verified = true WHERE id = ? AND tenant_id = ?"
+	res, err := s.db.ExecContext(ctx, query, userID, tenantID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	return nil
+}
+
+// UpdatePassword replaces password hash of the user.
+func (s *Storage) UpdatePassword(ctx context.Context, tenantID int64, userID int64, passHash []byte) error {
+	const op = "DATA LAYER: storage.sqlite.UpdatePassword"
+
+	query := "UPDATE users SET pass_hash = ? WHERE id = ? AND tenant_id = ?"
+	res, err := s.db.ExecContext(ctx, query, passHash, userID, tenantID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	return nil
+}
+
+// UpdateEmail replaces email of the user if it is still oldEmail and
+// marks the new one verified.
+func (s *Storage) UpdateEmail(ctx context.Context, tenantID int64, userID int64, oldEmail string, newEmail string) error {
+	const op = "DATA LAYER: storage.sqlite.UpdateEmail"
+
+	query := "UPDATE users SET email = ?, email_verified = true WHERE id = ? AND email = ? AND tenant_id = ?"
+	res, err := s.db.ExecContext(ctx, query, newEmail, userID, oldEmail, tenantID)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && errors.Is(sqliteErr.ExtendedCode, sqlite3.ErrConstraintUnique) {
+			return fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	return nil
+}
+
+// DeleteUser removes the user with their role bindings and memberships.
+func (s *Storage) DeleteUser(ctx context.Context, tenantID int64, userID int64, deletedBy int64) error {
+	const op = "DATA LAYER: storage.sqlite.DeleteUser"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var exists bool
+	query := "SELECT EXISTS(SELECT 1 FROM users WHERE id = ? AND tenant_id = ?)"
+	if err := tx.QueryRowContext(ctx, query, userID, tenantID).Scan(&exists); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !exists {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	queries := []string{
+		"DELETE FROM users WHERE id = ?",
+		"DELETE FROM user_roles WHERE user_id = ?",
+		"DELETE FROM organization_members WHERE user_id = ?",
+		"DELETE FROM login_history WHERE user_id = ?",
+	}
+	for _, query := range queries {
+		if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	query = "INSERT INTO deleted_users(user_id, tenant_id, deleted_by) VALUES(?, ?, ?)"
+	if _, err := tx.ExecContext(ctx, query, userID, tenantID, deletedBy); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *Storage) SaveLogin(ctx context.Context, login models.Login) error {
+	const op = "DATA LAYER: storage.sqlite.SaveLogin"
+
+	query := "INSERT INTO login_history(user_id, ip, user_agent, created_at) VALUES(?, ?, ?, ?)"
+	if _, err := s.db.ExecContext(ctx, query, login.UserID, login.IP, login.UserAgent, login.CreatedAt); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *Storage) LoginHistory(ctx context.Context, userID int64) ([]models.Login, error) {
+	const op = "DATA LAYER: storage.sqlite.LoginHistory"
+
+	query := "SELECT ip, user_agent, created_at FROM login_history WHERE user_id = ? ORDER BY created_at DESC"
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	logins := make([]models.Login, 0)
+	for rows.Next() {
+		login := models.Login{UserID: userID}
+		if err := rows.Scan(&login.IP, &login.UserAgent, &login.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		logins = append(logins, login)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return logins, nil
+}
+
 // App returns app by id.
 func (s *Storage) App(ctx context.Context, id int) (models.App, error) {
 	const op = "DATA LAYER: storage.sqlite.App"
-	query := "SELECT id, name, secret FROM apps WHERE id = ?"
+	query := "SELECT id, name, secret_hash FROM apps WHERE id = ?"
 	row := s.db.QueryRowContext(ctx, query, id)
 	var app models.App
-	err := row.Scan(&app.ID, &app.Name, &app.Secret)
+	err := row.Scan(&app.ID, &app.Name, &app.SecretHash)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return models.App{}, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
@@ -92,3 +307,122 @@ func (s *Storage) App(ctx context.Context, id int) (models.App, error) {
 	}
 	return app, nil
 }
+
+// SaveRole creates role with its permissions.
+func (s *Storage) SaveRole(ctx context.Context, tenantID int64, role models.Role) (int64, error) {
+	const op = "DATA LAYER: storage.sqlite.SaveRole"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query := "INSERT INTO roles(tenant_id, name, description) VALUES(?, ?, ?)"
+	res, err := tx.ExecContext(ctx, query, tenantID, role.Name, role.Description)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && errors.Is(sqliteErr.ExtendedCode, sqlite3.ErrConstraintUnique) {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrRoleExists)
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	query = "INSERT OR IGNORE INTO role_permissions(role_id, action, resource) VALUES(?, ?, ?)"
+	for _, permission := range role.Permissions {
+		if _, err := tx.ExecContext(ctx, query, id, permission.Action, permission.Resource); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return id, nil
+}
+
+// GrantRole binds role of the tenant to user.
+func (s *Storage) GrantRole(ctx context.Context, tenantID int64, userID int64, roleName string) error {
+	const op = "DATA LAYER: storage.sqlite.GrantRole"
+
+	roleID, err := s.roleID(ctx, tenantID, roleName)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	query := "INSERT OR IGNORE INTO user_roles(user_id, role_id) VALUES(?, ?)"
+	if _, err := s.db.ExecContext(ctx, query, userID, roleID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// RevokeRole unbinds role of the tenant from user.
+func (s *Storage) RevokeRole(ctx context.Context, tenantID int64, userID int64, roleName string) error {
+	const op = "DATA LAYER: storage.sqlite.RevokeRole"
+
+	roleID, err := s.roleID(ctx, tenantID, roleName)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	query := "DELETE FROM user_roles WHERE user_id = ? AND role_id = ?"
+	if _, err := s.db.ExecContext(ctx, query, userID, roleID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// UserRoles returns roles of the tenant bound to user with their permissions.
+func (s *Storage) UserRoles(ctx context.Context, tenantID int64, userID int64) ([]models.Role, error) {
+	const op = "DATA LAYER: storage.sqlite.UserRoles"
+
+	query := `SELECT r.id, r.name, r.description, rp.action, rp.resource
+		FROM user_roles ur
+		JOIN roles r ON r.id = ur.role_id
+		LEFT JOIN role_permissions rp ON rp.role_id = r.id
+		WHERE ur.user_id = ? AND r.tenant_id = ?
+		ORDER BY r.name, rp.action, rp.resource`
+	rows, err := s.db.QueryContext(ctx, query, userID, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	roles := make([]models.Role, 0)
+	for rows.Next() {
+		var role models.Role
+		var action, resource sql.NullString
+		if err := rows.Scan(&role.ID, &role.Name, &role.Description, &action, &resource); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if len(roles) == 0 || roles[len(roles)-1].ID != role.ID {
+			roles = append(roles, role)
+		}
+		if action.Valid {
+			last := &roles[len(roles)-1]
+			last.Permissions = append(last.Permissions, models.Permission{
+				Action:   action.String,
+				Resource: resource.String,
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return roles, nil
+}
+
+func (s *Storage) roleID(ctx context.Context, tenantID int64, roleName string) (int64, error) {
+	var id int64
+	query := "SELECT id FROM roles WHERE tenant_id = ? AND name = ?"
+	err := s.db.QueryRowContext(ctx, query, tenantID, roleName).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, storage.ErrRoleNotFound
+		}
+		return 0, err
+	}
+	return id, nil
+}
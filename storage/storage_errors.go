@@ -3,8 +3,19 @@ package storage
 import "errors"
 
 var (
-	ErrUserExists     = errors.New("user already exists")
-	ErrUserNotFound   = errors.New("user not found")
-	ErrAppNotFound    = errors.New("app not found")
-	ErrWrongParamType = errors.New("wrong param type")
+	ErrUserExists       = errors.New("user already exists")
+	ErrUserNotFound     = errors.New("user not found")
+	ErrAppNotFound      = errors.New("app not found")
+	ErrAppExists        = errors.New("app already exists")
+	ErrWrongParamType   = errors.New("wrong param type")
+	ErrSessionNotFound  = errors.New("session not found")
+	ErrAuthCodeNotFound = errors.New("authorization code not found")
+	ErrRoleNotFound     = errors.New("role not found")
+	ErrRoleExists       = errors.New("role already exists")
+	ErrOrgNotFound      = errors.New("organization not found")
+	ErrOrgExists        = errors.New("organization already exists")
+	ErrMemberNotFound   = errors.New("organization member not found")
+	ErrGroupNotFound    = errors.New("group not found")
+	ErrGroupExists      = errors.New("group already exists")
+	ErrGroupCycle       = errors.New("group nesting cycle")
 )
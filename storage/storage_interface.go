@@ -6,20 +6,157 @@ import (
 	"time"
 )
 
+// UserStorage keeps user accounts, every query is scoped by tenant.
 type UserStorage interface {
+	// SaveUser saves user to the tenant and makes them a member of it
 	SaveUser(
 		ctx context.Context,
+		tenantID int64,
 		email string,
 		passHash []byte,
 	) (context.Context, int64, error)
 	GetUser(
 		ctx context.Context,
+		tenantID int64,
 		value any,
 	) (context.Context, models.User, error)
+	// ListUsers returns page of users of the tenant ordered by id
+	ListUsers(ctx context.Context, filter models.UserFilter) (context.Context, []models.User, error)
+	// changes of the user below match the user of the tenant only
+	SetDisabled(ctx context.Context, tenantID int64, userID int64, disabled bool) (context.Context, error)
+	SetEmailVerified(ctx context.Context, tenantID int64, userID int64) (context.Context, error)
+	UpdatePassword(ctx context.Context, tenantID int64, userID int64, passHash []byte) (context.Context, error)
+	UpdateEmail(
+		ctx context.Context,
+		tenantID int64,
+		userID int64,
+		oldEmail string,
+		newEmail string,
+	) (context.Context, error)
+	// DeleteUser removes the user with everything bound to them and
+	// leaves a tombstone without personal data
+	DeleteUser(ctx context.Context, tenantID int64, userID int64, deletedBy int64) (context.Context, error)
+	SaveLogin(ctx context.Context, login models.Login) (context.Context, error)
+	// LoginHistory returns sign-ins of the user, the latest first
+	LoginHistory(ctx context.Context, userID int64) (context.Context, []models.Login, error)
+}
+
+type OrganizationStorage interface {
+	// SaveOrganization creates organization with its admin role
+	SaveOrganization(ctx context.Context, org models.Organization) (context.Context, int64, error)
+	Organization(ctx context.Context, id int64) (context.Context, models.Organization, error)
+	// SaveMember adds user to organization or changes their role
+	SaveMember(ctx context.Context, orgID int64, userID int64, role string) (context.Context, error)
+	DeleteMember(ctx context.Context, orgID int64, userID int64) (context.Context, error)
+	MemberRole(ctx context.Context, orgID int64, userID int64) (context.Context, string, error)
+	UserOrganizations(ctx context.Context, userID int64) (context.Context, []models.OrgMembership, error)
+}
+
+type AppStorage interface {
+	SaveApp(
+		ctx context.Context,
+		app models.App,
+	) (context.Context, int, error)
+	App(
+		ctx context.Context,
+		id int,
+	) (context.Context, models.App, error)
+}
+
+// RoleStorage keeps roles of tenants, role names are unique within tenant.
+type RoleStorage interface {
+	SaveRole(ctx context.Context, tenantID int64, role models.Role) (context.Context, int64, error)
+	GrantRole(ctx context.Context, tenantID int64, userID int64, roleName string) (context.Context, error)
+	RevokeRole(ctx context.Context, tenantID int64, userID int64, roleName string) (context.Context, error)
+	// UserRoles returns roles of the tenant bound to the user with their permissions
+	UserRoles(ctx context.Context, tenantID int64, userID int64) (context.Context, []models.Role, error)
+}
+
+// TupleStorage keeps relation tuples, every query is scoped by tenant.
+type TupleStorage interface {
+	// WriteTuples stores tuples, already stored ones are skipped
+	WriteTuples(ctx context.Context, tenantID int64, tuples []models.Tuple) (context.Context, error)
+	DeleteTuples(ctx context.Context, tenantID int64, tuples []models.Tuple) (context.Context, error)
+	// Tuples returns tuples of the object with any of relations
+	Tuples(ctx context.Context, tenantID int64, object string, relations []string) (context.Context, []models.Tuple, error)
+	// TuplesBySubjects returns tuples granting relations to any of subjects
+	TuplesBySubjects(ctx context.Context, tenantID int64, subjects []string) (context.Context, []models.Tuple, error)
+}
+
+type GroupStorage interface {
+	SaveGroup(ctx context.Context, group models.Group) (context.Context, int64, error)
+	Group(ctx context.Context, id int64) (context.Context, models.Group, error)
+	AddGroupMember(ctx context.Context, groupID int64, userID int64) (context.Context, error)
+	// RemoveGroupMember and RemoveSubgroup ignore missing memberships
+	RemoveGroupMember(ctx context.Context, groupID int64, userID int64) (context.Context, error)
+	// AddSubgroup nests child into parent, returns ErrGroupCycle if parent
+	// is already nested into child
+	AddSubgroup(ctx context.Context, parentID int64, childID int64) (context.Context, error)
+	RemoveSubgroup(ctx context.Context, parentID int64, childID int64) (context.Context, error)
+	// UserGroups returns groups the user is a member of directly or
+	// through subgroups
+	UserGroups(ctx context.Context, userID int64) (context.Context, []models.Group, error)
 }
 
 type TokenStorage interface {
 	SaveToken(ctx context.Context, token string, ttl time.Duration) (context.Context, error)
 	GetToken(ctx context.Context, token string) (context.Context, string, error)
 	CheckTokenExists(ctx context.Context, token string) (context.Context, int64, error)
+	// MarkTokenUsed marks refresh token as rotated, returns false if it was already used
+	MarkTokenUsed(ctx context.Context, tokenID string, ttl time.Duration) (context.Context, bool, error)
+	RevokeFamily(ctx context.Context, familyID string, ttl time.Duration) (context.Context, error)
+	CheckFamilyRevoked(ctx context.Context, familyID string) (context.Context, bool, error)
+	// SetRevokedBefore invalidates all tokens of the user issued before revokedBefore
+	SetRevokedBefore(ctx context.Context, userID int64, revokedBefore time.Time, ttl time.Duration) (context.Context, error)
+	// GetRevokedBefore returns zero time if sessions of the user were never revoked
+	GetRevokedBefore(ctx context.Context, userID int64) (context.Context, time.Time, error)
+	// SetSessionlessRevokedBefore is SetRevokedBefore for tokens without
+	// session (issued before sessions were introduced) only
+	SetSessionlessRevokedBefore(ctx context.Context, userID int64, revokedBefore time.Time, ttl time.Duration) (context.Context, error)
+	GetSessionlessRevokedBefore(ctx context.Context, userID int64) (context.Context, time.Time, error)
+}
+
+// LoginAttemptStorage counts failed logins and keeps temporary locks,
+// key identifies an account or a client ip
+type LoginAttemptStorage interface {
+	// RegisterFailure returns the number of failures of key, the counter
+	// expires after window without failures
+	RegisterFailure(ctx context.Context, key string, window time.Duration) (context.Context, int64, error)
+	// ResetFailures forgets failures and the lock of key
+	ResetFailures(ctx context.Context, key string) (context.Context, error)
+	Lock(ctx context.Context, key string, duration time.Duration) (context.Context, error)
+	// LockedFor returns the remaining lock duration, zero if key is not locked
+	LockedFor(ctx context.Context, key string) (context.Context, time.Duration, error)
+}
+
+// RateLimitStorage keeps token buckets shared by all instances of the service
+type RateLimitStorage interface {
+	// TakeToken takes a token from the bucket of key refilled with rate
+	// tokens per second up to burst, if the bucket is empty returns false
+	// and time until the next token
+	TakeToken(ctx context.Context, key string, rate float64, burst int) (context.Context, bool, time.Duration, error)
+}
+
+// Cache is implemented by both redis backends, standalone and sentinel
+type Cache interface {
+	TokenStorage
+	SessionStorage
+	AuthCodeStorage
+	LoginAttemptStorage
+	RateLimitStorage
+}
+
+type SessionStorage interface {
+	// SaveSession creates or updates session, ttl is prolonged on every save
+	SaveSession(ctx context.Context, session models.Session, ttl time.Duration) (context.Context, error)
+	GetSession(ctx context.Context, sessionID string) (context.Context, models.Session, error)
+	ListSessions(ctx context.Context, userID int64) (context.Context, []models.Session, error)
+	DeleteSession(ctx context.Context, sessionID string, userID int64) (context.Context, error)
+	DeleteUserSessions(ctx context.Context, userID int64) (context.Context, error)
+}
+
+type AuthCodeStorage interface {
+	SaveAuthCode(ctx context.Context, code string, authCode models.AuthCode, ttl time.Duration) (context.Context, error)
+	// TakeAuthCode returns and deletes the code, so it can be exchanged only once
+	TakeAuthCode(ctx context.Context, code string) (context.Context, models.AuthCode, error)
 }
@@ -0,0 +1,177 @@
+package tests
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	ssov1 "sso/protos/proto/sso/gen"
+	"sso/tests/suite"
+	"strings"
+	"testing"
+)
+
+func TestAdminUsers_ListUsers(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	adminLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "admin@test.com",
+		Password: "test",
+	})
+	require.NoError(t, err)
+
+	// emails share a unique prefix, so the filter selects only them
+	prefix := strings.ToLower(gofakeit.LetterN(12)) + "_"
+	registered := make(map[int64]bool)
+	for i := 0; i < 3; i++ {
+		respReg, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+			Email:    prefix + gofakeit.Email(),
+			Password: suite.RandomFakePassword(),
+		})
+		require.NoError(t, err)
+		registered[respReg.GetUserId()] = true
+	}
+
+	listed := make(map[int64]bool)
+	cursor := ""
+	for page := 0; ; page++ {
+		require.Less(t, page, 3, "pagination must end")
+		respList, err := testSuite.AdminClient.ListUsers(ctx, &ssov1.ListUsersRequest{
+			Token:       adminLogin.GetAccessToken(),
+			Cursor:      cursor,
+			PageSize:    2,
+			EmailPrefix: prefix,
+		})
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(respList.GetUsers()), 2)
+		for _, user := range respList.GetUsers() {
+			assert.True(t, strings.HasPrefix(user.GetEmail(), prefix))
+			listed[user.GetUserId()] = true
+		}
+		if respList.GetNextCursor() == "" {
+			break
+		}
+		cursor = respList.GetNextCursor()
+	}
+	assert.Equal(t, registered, listed)
+
+	isAdmin := true
+	respAdmins, err := testSuite.AdminClient.ListUsers(ctx, &ssov1.ListUsersRequest{
+		Token:       adminLogin.GetAccessToken(),
+		EmailPrefix: prefix,
+		IsAdmin:     &isAdmin,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, respAdmins.GetUsers())
+
+	_, err = testSuite.AdminClient.ListUsers(ctx, &ssov1.ListUsersRequest{
+		Token:  adminLogin.GetAccessToken(),
+		Cursor: "not a cursor",
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestAdminUsers_DisableEnable(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	adminLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "admin@test.com",
+		Password: "test",
+	})
+	require.NoError(t, err)
+
+	email := gofakeit.Email()
+	password := suite.RandomFakePassword()
+	respReg, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	_, err = testSuite.AdminClient.DisableUser(ctx, &ssov1.DisableUserRequest{
+		Token:  adminLogin.GetAccessToken(),
+		UserId: respReg.GetUserId(),
+	})
+	require.NoError(t, err)
+
+	respGet, err := testSuite.AdminClient.GetUser(ctx, &ssov1.GetUserRequest{
+		Token:  adminLogin.GetAccessToken(),
+		UserId: respReg.GetUserId(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, email, respGet.GetUser().GetEmail())
+	assert.True(t, respGet.GetUser().GetDisabled())
+
+	_, err = testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = testSuite.AuthClient.Refresh(ctx, &ssov1.RefreshRequest{RefreshToken: respLogin.GetRefreshToken()})
+	require.Error(t, err)
+
+	_, err = testSuite.AdminClient.EnableUser(ctx, &ssov1.EnableUserRequest{
+		Token:  adminLogin.GetAccessToken(),
+		UserId: respReg.GetUserId(),
+	})
+	require.NoError(t, err)
+	_, err = testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+}
+
+func TestAdminUsers_SetAdmin(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	adminLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "admin@test.com",
+		Password: "test",
+	})
+	require.NoError(t, err)
+
+	email := gofakeit.Email()
+	password := suite.RandomFakePassword()
+	respReg, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	_, err = testSuite.AdminClient.SetAdmin(ctx, &ssov1.SetAdminRequest{
+		Token:   adminLogin.GetAccessToken(),
+		UserId:  respReg.GetUserId(),
+		IsAdmin: true,
+	})
+	require.NoError(t, err)
+	respIsAdmin, err := testSuite.AuthClient.IsAdmin(ctx, &ssov1.IsAdminRequest{UserId: respReg.GetUserId()})
+	require.NoError(t, err)
+	assert.True(t, respIsAdmin.GetIsAdmin())
+
+	_, err = testSuite.AdminClient.SetAdmin(ctx, &ssov1.SetAdminRequest{
+		Token:   adminLogin.GetAccessToken(),
+		UserId:  respReg.GetUserId(),
+		IsAdmin: false,
+	})
+	require.NoError(t, err)
+	respIsAdmin, err = testSuite.AuthClient.IsAdmin(ctx, &ssov1.IsAdminRequest{UserId: respReg.GetUserId()})
+	require.NoError(t, err)
+	assert.False(t, respIsAdmin.GetIsAdmin())
+}
+
+func TestAdminUsers_NotAdmin(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	email := gofakeit.Email()
+	password := suite.RandomFakePassword()
+	respReg, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	_, err = testSuite.AdminClient.ListUsers(ctx, &ssov1.ListUsersRequest{Token: respLogin.GetAccessToken()})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = testSuite.AdminClient.SetAdmin(ctx, &ssov1.SetAdminRequest{
+		Token:   respLogin.GetAccessToken(),
+		UserId:  respReg.GetUserId(),
+		IsAdmin: true,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"encoding/json"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	ssov1 "sso/protos/proto/sso/gen"
+	"sso/tests/suite"
+	"testing"
+)
+
+func TestExportMyData_HappyPath(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	email := gofakeit.Email()
+	password := suite.RandomFakePassword()
+	respReg, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	respExport, err := testSuite.AuthClient.ExportMyData(ctx, &ssov1.ExportMyDataRequest{Token: respLogin.GetAccessToken()})
+	require.NoError(t, err)
+
+	var archive struct {
+		Profile struct {
+			ID    int64  `json:"id"`
+			Email string `json:"email"`
+		} `json:"profile"`
+		Sessions      []json.RawMessage `json:"sessions"`
+		LoginHistory  []json.RawMessage `json:"login_history"`
+		Roles         []json.RawMessage `json:"roles"`
+		Organizations []struct {
+			ID int64 `json:"id"`
+		} `json:"organizations"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(respExport.GetArchive()), &archive))
+	assert.Equal(t, respReg.GetUserId(), archive.Profile.ID)
+	assert.Equal(t, email, archive.Profile.Email)
+	assert.Len(t, archive.Sessions, 1)
+	assert.Len(t, archive.LoginHistory, 1)
+	require.Len(t, archive.Organizations, 1)
+	assert.Equal(t, int64(1), archive.Organizations[0].ID)
+	// the archive never carries password hash
+	assert.NotContains(t, respExport.GetArchive(), "pass")
+}
+
+func TestDeleteAccount_Self(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	email := gofakeit.Email()
+	password := suite.RandomFakePassword()
+	_, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	_, err = testSuite.AuthClient.DeleteAccount(ctx, &ssov1.DeleteAccountRequest{
+		Token:    respLogin.GetAccessToken(),
+		Password: suite.RandomFakePassword(),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	respDelete, err := testSuite.AuthClient.DeleteAccount(ctx, &ssov1.DeleteAccountRequest{
+		Token:    respLogin.GetAccessToken(),
+		Password: password,
+	})
+	require.NoError(t, err)
+	assert.True(t, respDelete.GetSuccess())
+
+	_, err = testSuite.AuthClient.Refresh(ctx, &ssov1.RefreshRequest{RefreshToken: respLogin.GetRefreshToken()})
+	require.Error(t, err)
+	_, err = testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.Error(t, err)
+
+	// the email is free again
+	_, err = testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+}
+
+func TestDeleteAccount_ByAdmin(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	adminLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "admin@test.com",
+		Password: "test",
+	})
+	require.NoError(t, err)
+
+	email := gofakeit.Email()
+	password := suite.RandomFakePassword()
+	respReg, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	// users can't delete each other
+	otherEmail := gofakeit.Email()
+	respOther, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: otherEmail, Password: password})
+	require.NoError(t, err)
+	_, err = testSuite.AuthClient.DeleteAccount(ctx, &ssov1.DeleteAccountRequest{
+		Token:  respLogin.GetAccessToken(),
+		UserId: respOther.GetUserId(),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = testSuite.AuthClient.DeleteAccount(ctx, &ssov1.DeleteAccountRequest{
+		Token:  adminLogin.GetAccessToken(),
+		UserId: respReg.GetUserId(),
+	})
+	require.NoError(t, err)
+
+	_, err = testSuite.AuthClient.ExportMyData(ctx, &ssov1.ExportMyDataRequest{Token: respLogin.GetAccessToken()})
+	require.Error(t, err)
+	_, err = testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.Error(t, err)
+}
@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	ssov1 "sso/protos/proto/sso/gen"
+	"sso/tests/suite"
+	"strconv"
+	"testing"
+)
+
+func TestAudience_LoginForApp(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	appID, err := strconv.Atoi(suite.AppID)
+	require.NoError(t, err)
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "user@test.com",
+		Password: "test",
+		AppId:    int64(appID),
+		Scopes:   []string{"calc:read", "calc:write"},
+	})
+	require.NoError(t, err)
+
+	tokenParsed, _, err := jwt.NewParser().ParseUnverified(respLogin.GetAccessToken(), jwt.MapClaims{})
+	require.NoError(t, err)
+	claims := tokenParsed.Claims.(jwt.MapClaims)
+	assert.Equal(t, suite.AppID, claims["aud"])
+	assert.Equal(t, suite.AppID, claims["client_id"])
+	assert.Equal(t, "calc:read calc:write", claims["scope"])
+
+	_, err = testSuite.AuthClient.Validate(ctx, &ssov1.ValidateRequest{
+		Token:    respLogin.GetAccessToken(),
+		Audience: suite.AppID,
+	})
+	require.NoError(t, err)
+
+	// token minted for the calculator is not accepted by other services
+	_, err = testSuite.AuthClient.Validate(ctx, &ssov1.ValidateRequest{
+		Token:    respLogin.GetAccessToken(),
+		Audience: "another-service",
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	respIntrospect, err := testSuite.AuthClient.Introspect(ctx, &ssov1.IntrospectRequest{
+		Token:        respLogin.GetAccessToken(),
+		ClientId:     suite.AppID,
+		ClientSecret: suite.AppSecret,
+		Audience:     "another-service",
+	})
+	require.NoError(t, err)
+	assert.False(t, respIntrospect.GetActive())
+}
+
+func TestAudience_ScopeNotAllowed_FailCase(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	appID, err := strconv.Atoi(suite.AppID)
+	require.NoError(t, err)
+	_, err = testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "user@test.com",
+		Password: "test",
+		AppId:    int64(appID),
+		Scopes:   []string{"calc:admin"},
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestAudience_RefreshNarrowsScopes(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	appID, err := strconv.Atoi(suite.AppID)
+	require.NoError(t, err)
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "user@test.com",
+		Password: "test",
+		AppId:    int64(appID),
+		Scopes:   []string{"calc:read"},
+	})
+	require.NoError(t, err)
+
+	// scopes can't be widened on refresh
+	_, err = testSuite.AuthClient.Refresh(ctx, &ssov1.RefreshRequest{
+		RefreshToken: respLogin.GetRefreshToken(),
+		Scopes:       []string{"calc:read", "calc:write"},
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	respRefresh, err := testSuite.AuthClient.Refresh(ctx, &ssov1.RefreshRequest{
+		RefreshToken: respLogin.GetRefreshToken(),
+	})
+	require.NoError(t, err)
+	tokenParsed, _, err := jwt.NewParser().ParseUnverified(respRefresh.GetAccessToken(), jwt.MapClaims{})
+	require.NoError(t, err)
+	claims := tokenParsed.Claims.(jwt.MapClaims)
+	assert.Equal(t, suite.AppID, claims["aud"])
+	assert.Equal(t, "calc:read", claims["scope"])
+}
@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	ssov1 "sso/protos/proto/sso/gen"
+	"sso/tests/suite"
+	"testing"
+)
+
+func TestChangeEmail_HappyPath(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	email := gofakeit.Email()
+	password := suite.RandomFakePassword()
+	respReg, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	newEmail := gofakeit.Email()
+	respRequest, err := testSuite.AuthClient.RequestEmailChange(ctx, &ssov1.RequestEmailChangeRequest{
+		Token:    respLogin.GetAccessToken(),
+		NewEmail: newEmail,
+	})
+	require.NoError(t, err)
+	assert.True(t, respRequest.GetSuccess())
+
+	// email stays the same until the link is opened
+	_, err = testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	token := testSuite.EmailChangeToken(respReg.GetUserId(), email, newEmail)
+	respConfirm, err := testSuite.AuthClient.ConfirmEmailChange(ctx, &ssov1.ConfirmEmailChangeRequest{Token: token})
+	require.NoError(t, err)
+	assert.True(t, respConfirm.GetSuccess())
+
+	// links are single-use
+	_, err = testSuite.AuthClient.ConfirmEmailChange(ctx, &ssov1.ConfirmEmailChangeRequest{Token: token})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// tokens carrying the old email are revoked
+	_, err = testSuite.AuthClient.Refresh(ctx, &ssov1.RefreshRequest{RefreshToken: respLogin.GetRefreshToken()})
+	require.Error(t, err)
+
+	_, err = testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.Error(t, err)
+	respLogin, err = testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: newEmail, Password: password})
+	require.NoError(t, err)
+
+	respInfo, err := testSuite.AuthClient.UserInfo(ctx, &ssov1.UserInfoRequest{AccessToken: respLogin.GetAccessToken()})
+	require.NoError(t, err)
+	assert.Equal(t, newEmail, respInfo.GetEmail())
+	assert.True(t, respInfo.GetEmailVerified())
+}
+
+func TestChangeEmail_EmailTaken(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	email := gofakeit.Email()
+	password := suite.RandomFakePassword()
+	respReg, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	takenEmail := gofakeit.Email()
+	_, err = testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: takenEmail, Password: password})
+	require.NoError(t, err)
+
+	_, err = testSuite.AuthClient.RequestEmailChange(ctx, &ssov1.RequestEmailChangeRequest{
+		Token:    respLogin.GetAccessToken(),
+		NewEmail: takenEmail,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	// the email may be taken after the link was sent
+	token := testSuite.EmailChangeToken(respReg.GetUserId(), email, takenEmail)
+	_, err = testSuite.AuthClient.ConfirmEmailChange(ctx, &ssov1.ConfirmEmailChangeRequest{Token: token})
+	require.Error(t, err)
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	_, err = testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+}
+
+func TestChangeEmail_StaleLink(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	email := gofakeit.Email()
+	password := suite.RandomFakePassword()
+	respReg, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	first := testSuite.EmailChangeToken(respReg.GetUserId(), email, gofakeit.Email())
+	second := testSuite.EmailChangeToken(respReg.GetUserId(), email, gofakeit.Email())
+	_, err = testSuite.AuthClient.ConfirmEmailChange(ctx, &ssov1.ConfirmEmailChangeRequest{Token: first})
+	require.NoError(t, err)
+
+	// the second link was issued for the old email
+	_, err = testSuite.AuthClient.ConfirmEmailChange(ctx, &ssov1.ConfirmEmailChangeRequest{Token: second})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestChangeEmail_FailCases(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	email := gofakeit.Email()
+	password := suite.RandomFakePassword()
+	_, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		token    string
+		newEmail string
+		code     codes.Code
+	}{
+		{"invalid email", respLogin.GetAccessToken(), "not-an-email", codes.InvalidArgument},
+		{"same email", respLogin.GetAccessToken(), email, codes.InvalidArgument},
+		{"refresh token", respLogin.GetRefreshToken(), gofakeit.Email(), codes.Unauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := testSuite.AuthClient.RequestEmailChange(ctx, &ssov1.RequestEmailChangeRequest{
+				Token:    tt.token,
+				NewEmail: tt.newEmail,
+			})
+			require.Error(t, err)
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	ssov1 "sso/protos/proto/sso/gen"
+	"sso/tests/suite"
+	"testing"
+	"time"
+)
+
+func TestChangePassword_RevokesAllSessions(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	email := gofakeit.Email()
+	oldPassword := suite.RandomFakePassword()
+	_, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: oldPassword})
+	require.NoError(t, err)
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: oldPassword})
+	require.NoError(t, err)
+
+	newPassword := suite.RandomFakePassword()
+	respChange, err := testSuite.AuthClient.ChangePassword(ctx, &ssov1.ChangePasswordRequest{
+		Token:       respLogin.GetAccessToken(),
+		OldPassword: oldPassword,
+		NewPassword: newPassword,
+	})
+	require.NoError(t, err)
+	assert.True(t, respChange.GetSuccess())
+
+	_, err = testSuite.AuthClient.Refresh(ctx, &ssov1.RefreshRequest{RefreshToken: respLogin.GetRefreshToken()})
+	require.Error(t, err)
+
+	_, err = testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: oldPassword})
+	require.Error(t, err)
+	_, err = testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: newPassword})
+	require.NoError(t, err)
+}
+
+func TestChangePassword_KeepCurrentSession(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	email := gofakeit.Email()
+	password := suite.RandomFakePassword()
+	_, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+	current, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+	other, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	_, err = testSuite.AuthClient.ChangePassword(ctx, &ssov1.ChangePasswordRequest{
+		Token:              current.GetAccessToken(),
+		OldPassword:        password,
+		NewPassword:        suite.RandomFakePassword(),
+		KeepCurrentSession: true,
+	})
+	require.NoError(t, err)
+
+	_, err = testSuite.AuthClient.Refresh(ctx, &ssov1.RefreshRequest{RefreshToken: current.GetRefreshToken()})
+	require.NoError(t, err)
+	_, err = testSuite.AuthClient.Refresh(ctx, &ssov1.RefreshRequest{RefreshToken: other.GetRefreshToken()})
+	require.Error(t, err)
+}
+
+func TestChangePassword_KeepCurrentSessionRevokesLegacyTokens(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	email := gofakeit.Email()
+	password := suite.RandomFakePassword()
+	respReg, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+	current, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	// tokens issued before sessions were introduced have no sid
+	legacy := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"token_type": "access",
+		"uid":        respReg.GetUserId(),
+		"email":      email,
+		"iat":        time.Now().Unix(),
+		"exp":        time.Now().Add(time.Hour).Unix(),
+	})
+	legacyToken, err := legacy.SignedString([]byte(testSuite.Cfg.ServiceSecret))
+	require.NoError(t, err)
+	_, err = testSuite.AuthClient.Validate(ctx, &ssov1.ValidateRequest{Token: legacyToken})
+	require.NoError(t, err)
+
+	_, err = testSuite.AuthClient.ChangePassword(ctx, &ssov1.ChangePasswordRequest{
+		Token:              current.GetAccessToken(),
+		OldPassword:        password,
+		NewPassword:        suite.RandomFakePassword(),
+		KeepCurrentSession: true,
+	})
+	require.NoError(t, err)
+
+	_, err = testSuite.AuthClient.Validate(ctx, &ssov1.ValidateRequest{Token: legacyToken})
+	require.Error(t, err)
+	_, err = testSuite.AuthClient.Refresh(ctx, &ssov1.RefreshRequest{RefreshToken: current.GetRefreshToken()})
+	require.NoError(t, err)
+}
+
+func TestChangePassword_FailCases(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	email := gofakeit.Email()
+	password := suite.RandomFakePassword()
+	_, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		token       string
+		oldPassword string
+		newPassword string
+		code        codes.Code
+	}{
+		{"wrong old password", respLogin.GetAccessToken(), suite.RandomFakePassword(), suite.RandomFakePassword(), codes.InvalidArgument},
+		{"same password", respLogin.GetAccessToken(), password, password, codes.InvalidArgument},
+		{"too short", respLogin.GetAccessToken(), password, "short", codes.InvalidArgument},
+		{"refresh token", respLogin.GetRefreshToken(), password, suite.RandomFakePassword(), codes.Unauthenticated},
+		{"empty new password", respLogin.GetAccessToken(), password, "", codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := testSuite.AuthClient.ChangePassword(ctx, &ssov1.ChangePasswordRequest{
+				Token:       tt.token,
+				OldPassword: tt.oldPassword,
+				NewPassword: tt.newPassword,
+			})
+			require.Error(t, err)
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+
+	// nothing above changed the password
+	_, err = testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+}
@@ -0,0 +1,207 @@
+package tests
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	ssov1 "sso/protos/proto/sso/gen"
+	"sso/tests/suite"
+	"strconv"
+	"testing"
+)
+
+func TestCheckPermission_WildcardResource(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	respAdmin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "admin@test.com",
+		Password: "test",
+	})
+	require.NoError(t, err)
+
+	roleName := "calc-reader-" + gofakeit.UUID()
+	_, err = testSuite.AuthClient.CreateRole(ctx, &ssov1.CreateRoleRequest{
+		Token:       respAdmin.GetAccessToken(),
+		Name:        roleName,
+		Permissions: []string{"read:calc:tasks:*"},
+	})
+	require.NoError(t, err)
+	respReg, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    gofakeit.Email(),
+		Password: suite.RandomFakePassword(),
+	})
+	require.NoError(t, err)
+	subject := strconv.FormatInt(respReg.GetUserId(), 10)
+
+	// nothing is allowed before the role is granted, the decision is cached
+	respCheck, err := testSuite.AuthClient.CheckPermission(ctx, &ssov1.CheckPermissionRequest{
+		Token:    respAdmin.GetAccessToken(),
+		Subject:  subject,
+		Action:   "read",
+		Resource: "calc:tasks:42",
+	})
+	require.NoError(t, err)
+	assert.False(t, respCheck.GetAllowed())
+
+	_, err = testSuite.AuthClient.GrantRole(ctx, &ssov1.GrantRoleRequest{
+		Token:  respAdmin.GetAccessToken(),
+		UserId: respReg.GetUserId(),
+		Role:   roleName,
+	})
+	require.NoError(t, err)
+
+	respBatch, err := testSuite.AuthClient.CheckPermissions(ctx, &ssov1.CheckPermissionsRequest{
+		Token: respAdmin.GetAccessToken(),
+		Checks: []*ssov1.CheckPermissionRequest{
+			{Subject: subject, Action: "read", Resource: "calc:tasks:42"},
+			{Subject: subject, Action: "read", Resource: "calc:tasks:42:result"},
+			{Subject: subject, Action: "write", Resource: "calc:tasks:42"},
+			{Subject: subject, Action: "read", Resource: "calc:users:1"},
+			{Subject: subject, Action: "read", Resource: "calc:tasks"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, true, false, false, false}, respBatch.GetAllowed())
+
+	_, err = testSuite.AuthClient.RevokeRole(ctx, &ssov1.RevokeRoleRequest{
+		Token:  respAdmin.GetAccessToken(),
+		UserId: respReg.GetUserId(),
+		Role:   roleName,
+	})
+	require.NoError(t, err)
+	respCheck, err = testSuite.AuthClient.CheckPermission(ctx, &ssov1.CheckPermissionRequest{
+		Token:    respAdmin.GetAccessToken(),
+		Subject:  subject,
+		Action:   "read",
+		Resource: "calc:tasks:42",
+	})
+	require.NoError(t, err)
+	assert.False(t, respCheck.GetAllowed())
+}
+
+func TestCheckPermission_AdminRole(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "admin@test.com",
+		Password: "test",
+	})
+	require.NoError(t, err)
+	respUserInfo, err := testSuite.AuthClient.UserInfo(ctx, &ssov1.UserInfoRequest{AccessToken: respLogin.GetAccessToken()})
+	require.NoError(t, err)
+
+	respCheck, err := testSuite.AuthClient.CheckPermission(ctx, &ssov1.CheckPermissionRequest{
+		Token:    respLogin.GetAccessToken(),
+		Subject:  respUserInfo.GetSub(),
+		Action:   "delete",
+		Resource: "calc:tasks:42",
+	})
+	require.NoError(t, err)
+	assert.True(t, respCheck.GetAllowed())
+}
+
+func TestCheckPermission_FailCases(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "admin@test.com",
+		Password: "test",
+	})
+	require.NoError(t, err)
+	token := respLogin.GetAccessToken()
+
+	tests := []struct {
+		name string
+		req  *ssov1.CheckPermissionRequest
+	}{
+		{name: "no token", req: &ssov1.CheckPermissionRequest{Subject: "1", Action: "read", Resource: "calc:tasks:1"}},
+		{name: "no subject", req: &ssov1.CheckPermissionRequest{Token: token, Action: "read", Resource: "calc:tasks:1"}},
+		{name: "subject is not user id", req: &ssov1.CheckPermissionRequest{Token: token, Subject: "calculator", Action: "read", Resource: "calc:tasks:1"}},
+		{name: "no action", req: &ssov1.CheckPermissionRequest{Token: token, Subject: "1", Resource: "calc:tasks:1"}},
+		{name: "no resource", req: &ssov1.CheckPermissionRequest{Token: token, Subject: "1", Action: "read"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := testSuite.AuthClient.CheckPermission(ctx, tt.req)
+			require.Error(t, err)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		})
+	}
+}
+
+func TestCheckPermissions_BatchTooLarge(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "admin@test.com",
+		Password: "test",
+	})
+	require.NoError(t, err)
+
+	checks := make([]*ssov1.CheckPermissionRequest, 101)
+	for i := range checks {
+		checks[i] = &ssov1.CheckPermissionRequest{Subject: "1", Action: "read", Resource: "calc:tasks:" + strconv.Itoa(i)}
+	}
+	_, err = testSuite.AuthClient.CheckPermissions(ctx, &ssov1.CheckPermissionsRequest{
+		Token:  respLogin.GetAccessToken(),
+		Checks: checks,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestCheckPermission_CallerScope(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	respAdmin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "admin@test.com",
+		Password: "test",
+	})
+	require.NoError(t, err)
+	respAdminInfo, err := testSuite.AuthClient.UserInfo(ctx, &ssov1.UserInfoRequest{AccessToken: respAdmin.GetAccessToken()})
+	require.NoError(t, err)
+
+	email, password := gofakeit.Email(), suite.RandomFakePassword()
+	respReg, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+		TenantId: acmeTenantID,
+	})
+	require.NoError(t, err)
+	respAcme, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		TenantId: acmeTenantID,
+	})
+	require.NoError(t, err)
+
+	// users check themselves only
+	respCheck, err := testSuite.AuthClient.CheckPermission(ctx, &ssov1.CheckPermissionRequest{
+		Token:    respAcme.GetAccessToken(),
+		Subject:  strconv.FormatInt(respReg.GetUserId(), 10),
+		Action:   "delete",
+		Resource: "calc:tasks:42",
+	})
+	require.NoError(t, err)
+	assert.False(t, respCheck.GetAllowed())
+
+	_, err = testSuite.AuthClient.CheckPermission(ctx, &ssov1.CheckPermissionRequest{
+		Token:    respAcme.GetAccessToken(),
+		Subject:  respAdminInfo.GetSub(),
+		Action:   "delete",
+		Resource: "calc:tasks:42",
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = testSuite.AuthClient.CheckPermissions(ctx, &ssov1.CheckPermissionsRequest{
+		Token: "bad token",
+		Checks: []*ssov1.CheckPermissionRequest{
+			{Subject: respAdminInfo.GetSub(), Action: "delete", Resource: "calc:tasks:42"},
+		},
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	ssov1 "sso/protos/proto/sso/gen"
+	"sso/tests/suite"
+	"testing"
+)
+
+func TestGroups_NestedMembership(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	respAdmin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "admin@test.com",
+		Password: "test",
+	})
+	require.NoError(t, err)
+	adminToken := respAdmin.GetAccessToken()
+
+	// engineering <- backend <- oncall
+	suffix := gofakeit.UUID()
+	groupIDs := make(map[string]int64)
+	for _, name := range []string{"engineering", "backend", "oncall"} {
+		respGroup, err := testSuite.AuthClient.CreateGroup(ctx, &ssov1.CreateGroupRequest{
+			Token: adminToken,
+			Name:  name + "-" + suffix,
+		})
+		require.NoError(t, err)
+		groupIDs[name] = respGroup.GetGroupId()
+	}
+	_, err = testSuite.AuthClient.AddSubgroup(ctx, &ssov1.AddSubgroupRequest{
+		Token:    adminToken,
+		ParentId: groupIDs["engineering"],
+		ChildId:  groupIDs["backend"],
+	})
+	require.NoError(t, err)
+	_, err = testSuite.AuthClient.AddSubgroup(ctx, &ssov1.AddSubgroupRequest{
+		Token:    adminToken,
+		ParentId: groupIDs["backend"],
+		ChildId:  groupIDs["oncall"],
+	})
+	require.NoError(t, err)
+
+	// engineering can't be nested into its own subgroup
+	_, err = testSuite.AuthClient.AddSubgroup(ctx, &ssov1.AddSubgroupRequest{
+		Token:    adminToken,
+		ParentId: groupIDs["oncall"],
+		ChildId:  groupIDs["engineering"],
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	email := gofakeit.Email()
+	password := suite.RandomFakePassword()
+	respReg, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+	_, err = testSuite.AuthClient.AddGroupMember(ctx, &ssov1.AddGroupMemberRequest{
+		Token:   adminToken,
+		GroupId: groupIDs["oncall"],
+		UserId:  respReg.GetUserId(),
+	})
+	require.NoError(t, err)
+
+	expected := []any{"backend-" + suffix, "engineering-" + suffix, "oncall-" + suffix}
+
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+	tokenParsed, err := jwt.Parse(respLogin.GetAccessToken(), func(token *jwt.Token) (any, error) {
+		return []byte(testSuite.Cfg.ServiceSecret), nil
+	})
+	require.NoError(t, err)
+	claims := tokenParsed.Claims.(jwt.MapClaims)
+	assert.Equal(t, expected, claims["groups"])
+
+	respGroups, err := testSuite.AuthClient.GetUserGroups(ctx, &ssov1.GetUserGroupsRequest{
+		Token:  respLogin.GetAccessToken(),
+		UserId: respReg.GetUserId(),
+	})
+	require.NoError(t, err)
+	names := make([]any, 0, len(respGroups.GetGroups()))
+	for _, group := range respGroups.GetGroups() {
+		names = append(names, group.GetName())
+	}
+	assert.Equal(t, expected, names)
+
+	// unnesting drops groups above the subgroup
+	_, err = testSuite.AuthClient.RemoveSubgroup(ctx, &ssov1.RemoveSubgroupRequest{
+		Token:    adminToken,
+		ParentId: groupIDs["backend"],
+		ChildId:  groupIDs["oncall"],
+	})
+	require.NoError(t, err)
+	respGroups, err = testSuite.AuthClient.GetUserGroups(ctx, &ssov1.GetUserGroupsRequest{
+		Token:  adminToken,
+		UserId: respReg.GetUserId(),
+	})
+	require.NoError(t, err)
+	require.Len(t, respGroups.GetGroups(), 1)
+	assert.Equal(t, "oncall-"+suffix, respGroups.GetGroups()[0].GetName())
+}
+
+func TestGroups_NotAdmin(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "user@test.com",
+		Password: "test",
+	})
+	require.NoError(t, err)
+
+	_, err = testSuite.AuthClient.CreateGroup(ctx, &ssov1.CreateGroupRequest{
+		Token: respLogin.GetAccessToken(),
+		Name:  "group-" + gofakeit.UUID(),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
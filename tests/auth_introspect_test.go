@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	ssov1 "sso/protos/proto/sso/gen"
+	"sso/tests/suite"
+	"strconv"
+	"testing"
+)
+
+func TestIntrospect_HappyPath(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	email := gofakeit.Email()
+	password := suite.RandomFakePassword()
+	respReg, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	respIntrospect, err := testSuite.AuthClient.Introspect(ctx, &ssov1.IntrospectRequest{
+		Token:        respLogin.GetAccessToken(),
+		ClientId:     suite.AppID,
+		ClientSecret: suite.AppSecret,
+	})
+	require.NoError(t, err)
+	require.True(t, respIntrospect.GetActive())
+	require.Equal(t, strconv.FormatInt(respReg.GetUserId(), 10), respIntrospect.GetSub())
+	require.Equal(t, email, respIntrospect.GetEmail())
+	require.Equal(t, "access", respIntrospect.GetTokenType())
+	require.Greater(t, respIntrospect.GetExp(), respIntrospect.GetIat())
+}
+
+func TestIntrospect_RevokedToken(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	email := gofakeit.Email()
+	password := suite.RandomFakePassword()
+	_, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+	_, err = testSuite.AuthClient.Logout(ctx, &ssov1.LogoutRequest{Token: respLogin.GetAccessToken()})
+	require.NoError(t, err)
+
+	for _, token := range []string{respLogin.GetAccessToken(), "not a token"} {
+		respIntrospect, err := testSuite.AuthClient.Introspect(ctx, &ssov1.IntrospectRequest{
+			Token:        token,
+			ClientId:     suite.AppID,
+			ClientSecret: suite.AppSecret,
+		})
+		require.NoError(t, err)
+		require.False(t, respIntrospect.GetActive())
+		require.Empty(t, respIntrospect.GetSub())
+	}
+}
+
+func TestIntrospect_InvalidClient_FailCase(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "user@test.com",
+		Password: "test",
+	})
+	require.NoError(t, err)
+
+	_, err = testSuite.AuthClient.Introspect(ctx, &ssov1.IntrospectRequest{
+		Token:        respLogin.GetAccessToken(),
+		ClientId:     suite.AppID,
+		ClientSecret: "wrong-secret",
+	})
+	require.Error(t, err)
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
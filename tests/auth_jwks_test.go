@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sso/internal/config"
+	jwtlib "sso/internal/lib/jwt"
+	ssov1 "sso/protos/proto/sso/gen"
+	"sso/tests/suite"
+	"testing"
+	"time"
+)
+
+func TestJwks_KidHeader_HappyPath(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "admin@test.com",
+		Password: "test",
+	})
+	require.NoError(t, err)
+
+	tokenParsed, _, err := jwt.NewParser().ParseUnverified(respLogin.GetAccessToken(), jwt.MapClaims{})
+	require.NoError(t, err)
+	kid, ok := tokenParsed.Header["kid"].(string)
+	require.True(t, ok)
+
+	// token must be signed by one of configured keys
+	var algorithm string
+	for _, key := range testSuite.Cfg.SigningKeys {
+		if key.Kid == kid {
+			algorithm = key.Algorithm
+		}
+	}
+	require.NotEmpty(t, algorithm)
+	assert.Equal(t, algorithm, tokenParsed.Method.Alg())
+
+	respJwks, err := testSuite.AuthClient.Jwks(ctx, &ssov1.JwksRequest{})
+	require.NoError(t, err)
+
+	// symmetric keys must never be published
+	for _, key := range respJwks.GetKeys() {
+		assert.NotEqual(t, "HS256", key.GetAlg())
+		assert.Equal(t, "sig", key.GetUse())
+	}
+}
+
+func TestJwks_ValidateUnknownKid_FailCase(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	// token with kid which is not in the key ring
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"token_type": "access",
+		"uid":        1,
+		"email":      "admin@test.com",
+		"exp":        4102444800,
+	})
+	token.Header["kid"] = "unknown-kid"
+	tokenString, err := token.SignedString([]byte(testSuite.Cfg.ServiceSecret))
+	require.NoError(t, err)
+
+	_, err = testSuite.AuthClient.Validate(ctx, &ssov1.ValidateRequest{Token: tokenString})
+	require.Error(t, err)
+}
+
+func TestJwks_ValidateWithoutKid_HappyPath(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	email := gofakeit.Email()
+	respReg, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: suite.RandomFakePassword(),
+	})
+	require.NoError(t, err)
+
+	// tokens issued before key rotation have no kid and are signed with
+	// service_secret, they must stay valid whatever keys are configured
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"token_type": "access",
+		"uid":        respReg.GetUserId(),
+		"email":      email,
+		"iat":        time.Now().Unix(),
+		"exp":        time.Now().Add(time.Hour).Unix(),
+	})
+	tokenString, err := token.SignedString([]byte(testSuite.Cfg.ServiceSecret))
+	require.NoError(t, err)
+
+	respValidate, err := testSuite.AuthClient.Validate(ctx, &ssov1.ValidateRequest{Token: tokenString})
+	require.NoError(t, err)
+	assert.True(t, respValidate.GetSuccess())
+
+	// algorithm of the legacy key can't be chosen by the token
+	token = jwt.NewWithClaims(jwt.SigningMethodHS512, token.Claims)
+	tokenString, err = token.SignedString([]byte(testSuite.Cfg.ServiceSecret))
+	require.NoError(t, err)
+	_, err = testSuite.AuthClient.Validate(ctx, &ssov1.ValidateRequest{Token: tokenString})
+	require.Error(t, err)
+}
+
+func TestJwks_WithoutKidAfterLegacyNotAfter_FailCase(t *testing.T) {
+	cfg := &config.Config{
+		ServiceSecret:  "service very secret",
+		LegacyNotAfter: time.Now().Add(-time.Hour),
+	}
+	keyRing, err := jwtlib.NewKeyRing(cfg)
+	require.NoError(t, err)
+
+	_, err = keyRing.VerificationKey("", time.Now())
+	assert.ErrorIs(t, err, jwtlib.ErrUnknownKey)
+	// the legacy key verifies until the cutoff only
+	_, err = keyRing.VerificationKey("", cfg.LegacyNotAfter.Add(-time.Minute))
+	assert.NoError(t, err)
+}
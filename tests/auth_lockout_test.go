@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	ssov1 "sso/protos/proto/sso/gen"
+	"sso/tests/suite"
+	"testing"
+)
+
+func TestLockout_LockAndUnlock(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+	if !testSuite.Cfg.Lockout.Enabled {
+		t.Skip("lockout is disabled")
+	}
+
+	email := gofakeit.Email()
+	password := suite.RandomFakePassword()
+	respReg, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < testSuite.Cfg.Lockout.AccountThreshold; i++ {
+		_, err = testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+			Email:    email,
+			Password: password + "wrong",
+		})
+		require.Error(t, err)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+
+	// the correct password is rejected too while the account is locked
+	var header metadata.MD
+	_, err = testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+	}, grpc.Header(&header))
+	require.Error(t, err)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.NotEmpty(t, header.Get("retry-after"))
+
+	var retryInfo *errdetails.RetryInfo
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok {
+			retryInfo = info
+		}
+	}
+	require.NotNil(t, retryInfo)
+	assert.Positive(t, retryInfo.GetRetryDelay().AsDuration())
+
+	userLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "user@test.com",
+		Password: "test",
+	})
+	require.NoError(t, err)
+	_, err = testSuite.AdminClient.UnlockUser(ctx, &ssov1.UnlockUserRequest{
+		Token:  userLogin.GetAccessToken(),
+		UserId: respReg.GetUserId(),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	adminLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "admin@test.com",
+		Password: "test",
+	})
+	require.NoError(t, err)
+	respUnlock, err := testSuite.AdminClient.UnlockUser(ctx, &ssov1.UnlockUserRequest{
+		Token:  adminLogin.GetAccessToken(),
+		UserId: respReg.GetUserId(),
+	})
+	require.NoError(t, err)
+	assert.True(t, respUnlock.GetSuccess())
+
+	_, err = testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+}
+
+func TestLockout_UnknownEmail(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+	if !testSuite.Cfg.Lockout.Enabled {
+		t.Skip("lockout is disabled")
+	}
+
+	// unknown emails are locked like existing ones, so locks don't tell
+	// which accounts exist
+	email := gofakeit.Email()
+	for i := 0; i < testSuite.Cfg.Lockout.AccountThreshold; i++ {
+		_, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+			Email:    email,
+			Password: suite.RandomFakePassword(),
+		})
+		require.Error(t, err)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+	_, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: suite.RandomFakePassword(),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
@@ -32,7 +32,7 @@ func TestLogin_Login_HappyPath(t *testing.T) {
 	require.True(t, ok)
 
 	// check out token consists correct information
-	assert.Equal(t, int64(44), int64(claims["uid"].(float64)))
+	assert.Equal(t, "44", claims["sub"].(string))
 	assert.Equal(t, "admin@test.com", claims["email"].(string))
 
 	// checking token expiration time might be only approximate
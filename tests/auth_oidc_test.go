@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+	ssov1 "sso/protos/proto/sso/gen"
+	"sso/tests/suite"
+	"strconv"
+	"testing"
+)
+
+func TestOIDC_Discovery(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	resp, err := testSuite.AuthClient.OpenIDConfiguration(ctx, &ssov1.OpenIDConfigurationRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, testSuite.Cfg.OIDC.Issuer, resp.GetIssuer())
+	assert.Equal(t, testSuite.Cfg.OIDC.Issuer+"/.well-known/jwks.json", resp.GetJwksUri())
+	assert.Equal(t, testSuite.Cfg.OIDC.Issuer+"/userinfo", resp.GetUserinfoEndpoint())
+	assert.Contains(t, resp.GetSubjectTypesSupported(), "public")
+	assert.NotEmpty(t, resp.GetIdTokenSigningAlgValuesSupported())
+}
+
+func TestOIDC_LoginIDToken(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	email := gofakeit.Email()
+	password := suite.RandomFakePassword()
+	respReg, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+	require.NotEmpty(t, respLogin.GetIdToken())
+
+	tokenParsed, err := jwt.Parse(respLogin.GetIdToken(), func(token *jwt.Token) (any, error) {
+		return []byte(testSuite.Cfg.ServiceSecret), nil
+	})
+	require.NoError(t, err)
+	claims, ok := tokenParsed.Claims.(jwt.MapClaims)
+	require.True(t, ok)
+	assert.Equal(t, testSuite.Cfg.OIDC.Issuer, claims["iss"])
+	assert.Equal(t, testSuite.Cfg.OIDC.Audience, claims["aud"])
+	assert.Equal(t, strconv.FormatInt(respReg.GetUserId(), 10), claims["sub"])
+	assert.Equal(t, email, claims["email"])
+
+	// ID token only identifies the user, it can't be used as access token
+	_, err = testSuite.AuthClient.Validate(ctx, &ssov1.ValidateRequest{Token: respLogin.GetIdToken()})
+	require.Error(t, err)
+}
+
+func TestOIDC_UserInfo(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	email := gofakeit.Email()
+	password := suite.RandomFakePassword()
+	respReg, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	bearerCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+respLogin.GetAccessToken())
+	resp, err := testSuite.AuthClient.UserInfo(bearerCtx, &ssov1.UserInfoRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, strconv.FormatInt(respReg.GetUserId(), 10), resp.GetSub())
+	assert.Equal(t, email, resp.GetEmail())
+
+	// refresh token is not accepted
+	_, err = testSuite.AuthClient.UserInfo(ctx, &ssov1.UserInfoRequest{AccessToken: respLogin.GetRefreshToken()})
+	require.Error(t, err)
+}
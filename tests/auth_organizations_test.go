@@ -0,0 +1,176 @@
+package tests
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	ssov1 "sso/protos/proto/sso/gen"
+	"sso/tests/suite"
+	"strconv"
+	"testing"
+)
+
+// acmeTenantID is organization seeded by test migrations
+const acmeTenantID = 2
+
+func TestOrganizations_SameEmailInTenants(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	email := gofakeit.Email()
+	defaultPassword := suite.RandomFakePassword()
+	acmePassword := suite.RandomFakePassword()
+
+	respDefault, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: defaultPassword})
+	require.NoError(t, err)
+	respAcme, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: acmePassword,
+		TenantId: acmeTenantID,
+	})
+	require.NoError(t, err)
+	assert.NotEqual(t, respDefault.GetUserId(), respAcme.GetUserId())
+
+	// accounts are independent, password of one tenant is useless in another
+	_, err = testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: defaultPassword,
+		TenantId: acmeTenantID,
+	})
+	require.Error(t, err)
+
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: acmePassword,
+		TenantId: acmeTenantID,
+	})
+	require.NoError(t, err)
+	tokenParsed, err := jwt.Parse(respLogin.GetAccessToken(), func(token *jwt.Token) (any, error) {
+		return []byte(testSuite.Cfg.ServiceSecret), nil
+	})
+	require.NoError(t, err)
+	claims := tokenParsed.Claims.(jwt.MapClaims)
+	assert.Equal(t, float64(acmeTenantID), claims["tenant_id"])
+	assert.Equal(t, strconv.FormatInt(respAcme.GetUserId(), 10), claims["sub"])
+}
+
+func TestOrganizations_RegisterUnknownTenant(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	_, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    gofakeit.Email(),
+		Password: suite.RandomFakePassword(),
+		TenantId: 1 << 40,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestOrganizations_Membership(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	respAdmin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "admin@test.com",
+		Password: "test",
+	})
+	require.NoError(t, err)
+	adminToken := respAdmin.GetAccessToken()
+
+	slug := "org-" + gofakeit.UUID()
+	respOrg, err := testSuite.AuthClient.CreateOrganization(ctx, &ssov1.CreateOrganizationRequest{
+		Token: adminToken,
+		Name:  "Test organization",
+		Slug:  slug,
+	})
+	require.NoError(t, err)
+
+	_, err = testSuite.AuthClient.CreateOrganization(ctx, &ssov1.CreateOrganizationRequest{
+		Token: adminToken,
+		Name:  "Duplicate",
+		Slug:  slug,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	email := gofakeit.Email()
+	password := suite.RandomFakePassword()
+	respReg, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+	userToken := respLogin.GetAccessToken()
+
+	// plain users can't create organizations or manage foreign members
+	_, err = testSuite.AuthClient.CreateOrganization(ctx, &ssov1.CreateOrganizationRequest{
+		Token: userToken,
+		Name:  "Forbidden",
+		Slug:  "org-" + gofakeit.UUID(),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = testSuite.AuthClient.AddOrganizationMember(ctx, &ssov1.AddOrganizationMemberRequest{
+		Token:  adminToken,
+		OrgId:  respOrg.GetOrgId(),
+		UserId: respReg.GetUserId(),
+	})
+	require.NoError(t, err)
+
+	respList, err := testSuite.AuthClient.ListOrganizations(ctx, &ssov1.ListOrganizationsRequest{Token: userToken})
+	require.NoError(t, err)
+	roles := make(map[int64]string)
+	for _, org := range respList.GetOrganizations() {
+		roles[org.GetId()] = org.GetRole()
+	}
+	assert.Equal(t, map[int64]string{1: "member", respOrg.GetOrgId(): "member"}, roles)
+
+	// members can leave organizations, but not their own tenant
+	_, err = testSuite.AuthClient.RemoveOrganizationMember(ctx, &ssov1.RemoveOrganizationMemberRequest{
+		Token:  userToken,
+		OrgId:  1,
+		UserId: respReg.GetUserId(),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = testSuite.AuthClient.RemoveOrganizationMember(ctx, &ssov1.RemoveOrganizationMemberRequest{
+		Token:  userToken,
+		OrgId:  respOrg.GetOrgId(),
+		UserId: respReg.GetUserId(),
+	})
+	require.NoError(t, err)
+}
+
+func TestOrganizations_AdminOfAnotherTenant(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	respReg, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    gofakeit.Email(),
+		Password: suite.RandomFakePassword(),
+		TenantId: acmeTenantID,
+	})
+	require.NoError(t, err)
+
+	// admin of the default tenant can't manage roles of acme users
+	respAdmin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "admin@test.com",
+		Password: "test",
+	})
+	require.NoError(t, err)
+	_, err = testSuite.AuthClient.GrantRole(ctx, &ssov1.GrantRoleRequest{
+		Token:  respAdmin.GetAccessToken(),
+		UserId: respReg.GetUserId(),
+		Role:   "admin",
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = testSuite.AuthClient.RevokeRole(ctx, &ssov1.RevokeRoleRequest{
+		Token:  respAdmin.GetAccessToken(),
+		UserId: respReg.GetUserId(),
+		Role:   "admin",
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
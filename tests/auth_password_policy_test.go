@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	ssov1 "sso/protos/proto/sso/gen"
+	"sso/tests/suite"
+	"strings"
+	"testing"
+)
+
+// policyViolations returns rules listed in details of the error and
+// fields of its bad request violations
+func policyViolations(t *testing.T, err error) (rules map[string]string, fields []string) {
+	st := status.Convert(err)
+	require.Equal(t, codes.InvalidArgument, st.Code())
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			assert.Equal(t, "WEAK_PASSWORD", d.GetReason())
+			rules = d.GetMetadata()
+		case *errdetails.BadRequest:
+			for _, violation := range d.GetFieldViolations() {
+				fields = append(fields, violation.GetField())
+			}
+		}
+	}
+	require.NotEmpty(t, rules)
+	return rules, fields
+}
+
+func TestPasswordPolicy_Register(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+	policy := testSuite.Cfg.PasswordPolicy
+
+	local := strings.ToLower(gofakeit.LetterN(10))
+	tests := []struct {
+		name     string
+		enabled  bool
+		email    string
+		password string
+		rule     string
+	}{
+		{"too short", policy.MinLength > 3, gofakeit.Email(), "aA1", "min_length"},
+		{"too long", true, gofakeit.Email(), "aA1" + strings.Repeat("x", 70), "max_length"},
+		{"no upper case", policy.RequireUpper, gofakeit.Email(), "lower1case", "upper"},
+		{"no digit", policy.RequireDigit, gofakeit.Email(), "NoDigitsHere", "digit"},
+		{"contains email", policy.RejectEmail, local + "@example.com", "X1" + strings.ToUpper(local), "contains_email"},
+		{"breached", policy.BreachedFile != "", gofakeit.Email(), "P@ssw0rd!", "breached"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.enabled {
+				t.Skip("rule is disabled")
+			}
+			_, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+				Email:    tt.email,
+				Password: tt.password,
+			})
+			require.Error(t, err)
+			rules, fields := policyViolations(t, err)
+			assert.Contains(t, rules, tt.rule)
+			assert.Contains(t, fields, "password")
+		})
+	}
+
+	// nothing was registered, so the email is still free
+	email := gofakeit.Email()
+	_, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: "short"})
+	require.Error(t, err)
+	_, err = testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: suite.RandomFakePassword()})
+	require.NoError(t, err)
+}
+
+func TestPasswordPolicy_ChangePassword(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+	if !testSuite.Cfg.PasswordPolicy.RejectEmail {
+		t.Skip("contains_email rule is disabled")
+	}
+
+	local := strings.ToLower(gofakeit.LetterN(10))
+	email := local + "@example.com"
+	password := suite.RandomFakePassword()
+	_, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	_, err = testSuite.AuthClient.ChangePassword(ctx, &ssov1.ChangePasswordRequest{
+		Token:       respLogin.GetAccessToken(),
+		OldPassword: password,
+		NewPassword: "X1" + strings.ToUpper(local),
+	})
+	require.Error(t, err)
+	rules, fields := policyViolations(t, err)
+	assert.Contains(t, rules, "contains_email")
+	assert.Contains(t, fields, "new_password")
+
+	// rejected password didn't replace the old one
+	_, err = testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+}
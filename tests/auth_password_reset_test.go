@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"regexp"
+	ssov1 "sso/protos/proto/sso/gen"
+	"sso/tests/suite"
+	"testing"
+)
+
+// resetLinkToken returns token of the reset link emailed by file mailer.
+func resetLinkToken(t *testing.T, testSuite *suite.Suite, email string) string {
+	if testSuite.Cfg.Mailer.Driver != "file" {
+		t.Skip("reset links can be read only with file mailer")
+	}
+	body := findEmailWithSubject(t, testSuite.Cfg.Mailer.Dir, email, "Reset your password")
+	match := regexp.MustCompile(`\?token=([\w.-]+)`).FindStringSubmatch(body)
+	require.NotNil(t, match)
+	return match[1]
+}
+
+func TestPasswordReset_HappyPath(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	email := gofakeit.Email()
+	oldPassword := suite.RandomFakePassword()
+	_, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: oldPassword})
+	require.NoError(t, err)
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: oldPassword})
+	require.NoError(t, err)
+
+	respRequest, err := testSuite.AuthClient.RequestPasswordReset(ctx, &ssov1.RequestPasswordResetRequest{Email: email})
+	require.NoError(t, err)
+	assert.True(t, respRequest.GetSuccess())
+
+	newPassword := suite.RandomFakePassword()
+	token := resetLinkToken(t, testSuite, email)
+	_, err = testSuite.AuthClient.ResetPassword(ctx, &ssov1.ResetPasswordRequest{Token: token, NewPassword: newPassword})
+	require.NoError(t, err)
+
+	// links are single-use
+	_, err = testSuite.AuthClient.ResetPassword(ctx, &ssov1.ResetPasswordRequest{Token: token, NewPassword: newPassword})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// sessions started with the old password are revoked
+	_, err = testSuite.AuthClient.Refresh(ctx, &ssov1.RefreshRequest{RefreshToken: respLogin.GetRefreshToken()})
+	require.Error(t, err)
+
+	_, err = testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: oldPassword})
+	require.Error(t, err)
+	_, err = testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: newPassword})
+	require.NoError(t, err)
+}
+
+func TestPasswordReset_UnknownEmail(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	respRequest, err := testSuite.AuthClient.RequestPasswordReset(ctx, &ssov1.RequestPasswordResetRequest{
+		Email: gofakeit.Email(),
+	})
+	require.NoError(t, err)
+	assert.True(t, respRequest.GetSuccess())
+}
+
+func TestPasswordReset_VerificationTokenRejected(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	email := gofakeit.Email()
+	respReg, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: suite.RandomFakePassword()})
+	require.NoError(t, err)
+
+	token := testSuite.ActionToken("email_verification", respReg.GetUserId(), email)
+	_, err = testSuite.AuthClient.ResetPassword(ctx, &ssov1.ResetPasswordRequest{
+		Token:       token,
+		NewPassword: suite.RandomFakePassword(),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestPasswordReset_PasswordChanged_FailCase(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	email := gofakeit.Email()
+	oldPassword := suite.RandomFakePassword()
+	respReg, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: oldPassword})
+	require.NoError(t, err)
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: oldPassword})
+	require.NoError(t, err)
+
+	_, err = testSuite.AuthClient.RequestPasswordReset(ctx, &ssov1.RequestPasswordResetRequest{Email: email})
+	require.NoError(t, err)
+	token := resetLinkToken(t, testSuite, email)
+
+	// the link is useless once the password changes another way
+	_, err = testSuite.AuthClient.ChangePassword(ctx, &ssov1.ChangePasswordRequest{
+		Token:       respLogin.GetAccessToken(),
+		OldPassword: oldPassword,
+		NewPassword: suite.RandomFakePassword(),
+	})
+	require.NoError(t, err)
+	_, err = testSuite.AuthClient.ResetPassword(ctx, &ssov1.ResetPasswordRequest{
+		Token:       token,
+		NewPassword: suite.RandomFakePassword(),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// tokens without fingerprint of the password are rejected as well
+	_, err = testSuite.AuthClient.ResetPassword(ctx, &ssov1.ResetPasswordRequest{
+		Token:       testSuite.ActionToken("password_reset", respReg.GetUserId(), email),
+		NewPassword: suite.RandomFakePassword(),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,202 @@
+package tests
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	ssov1 "sso/protos/proto/sso/gen"
+	"sso/tests/suite"
+	"testing"
+)
+
+func TestRoles_GrantRevoke_HappyPath(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	respAdmin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "admin@test.com",
+		Password: "test",
+	})
+	require.NoError(t, err)
+	adminToken := respAdmin.GetAccessToken()
+
+	roleName := "calc-operator-" + gofakeit.UUID()
+	_, err = testSuite.AuthClient.CreateRole(ctx, &ssov1.CreateRoleRequest{
+		Token:       adminToken,
+		Name:        roleName,
+		Permissions: []string{"read:calc:tasks:*", "write:calc:tasks:*"},
+	})
+	require.NoError(t, err)
+
+	email := gofakeit.Email()
+	password := suite.RandomFakePassword()
+	respReg, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	_, err = testSuite.AuthClient.GrantRole(ctx, &ssov1.GrantRoleRequest{
+		Token:  adminToken,
+		UserId: respReg.GetUserId(),
+		Role:   roleName,
+	})
+	require.NoError(t, err)
+
+	// new tokens carry roles and permissions
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+	tokenParsed, err := jwt.Parse(respLogin.GetAccessToken(), func(token *jwt.Token) (any, error) {
+		return []byte(testSuite.Cfg.ServiceSecret), nil
+	})
+	require.NoError(t, err)
+	claims := tokenParsed.Claims.(jwt.MapClaims)
+	assert.Equal(t, []any{roleName}, claims["roles"])
+	assert.Equal(t, []any{"read:calc:tasks:*", "write:calc:tasks:*"}, claims["permissions"])
+
+	// users may query own permissions
+	respPermissions, err := testSuite.AuthClient.GetUserPermissions(ctx, &ssov1.GetUserPermissionsRequest{
+		Token:  respLogin.GetAccessToken(),
+		UserId: respReg.GetUserId(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{roleName}, respPermissions.GetRoles())
+
+	_, err = testSuite.AuthClient.RevokeRole(ctx, &ssov1.RevokeRoleRequest{
+		Token:  adminToken,
+		UserId: respReg.GetUserId(),
+		Role:   roleName,
+	})
+	require.NoError(t, err)
+	respPermissions, err = testSuite.AuthClient.GetUserPermissions(ctx, &ssov1.GetUserPermissionsRequest{
+		Token:  adminToken,
+		UserId: respReg.GetUserId(),
+	})
+	require.NoError(t, err)
+	assert.Empty(t, respPermissions.GetRoles())
+	assert.Empty(t, respPermissions.GetPermissions())
+}
+
+func TestRoles_AdminRole_IsAdminShim(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	respAdmin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "admin@test.com",
+		Password: "test",
+	})
+	require.NoError(t, err)
+
+	email := gofakeit.Email()
+	respReg, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: suite.RandomFakePassword(),
+	})
+	require.NoError(t, err)
+
+	_, err = testSuite.AuthClient.GrantRole(ctx, &ssov1.GrantRoleRequest{
+		Token:  respAdmin.GetAccessToken(),
+		UserId: respReg.GetUserId(),
+		Role:   "admin",
+	})
+	require.NoError(t, err)
+	respIsAdmin, err := testSuite.AuthClient.IsAdmin(ctx, &ssov1.IsAdminRequest{UserId: respReg.GetUserId()})
+	require.NoError(t, err)
+	assert.True(t, respIsAdmin.GetIsAdmin())
+
+	_, err = testSuite.AuthClient.RevokeRole(ctx, &ssov1.RevokeRoleRequest{
+		Token:  respAdmin.GetAccessToken(),
+		UserId: respReg.GetUserId(),
+		Role:   "admin",
+	})
+	require.NoError(t, err)
+	respIsAdmin, err = testSuite.AuthClient.IsAdmin(ctx, &ssov1.IsAdminRequest{UserId: respReg.GetUserId()})
+	require.NoError(t, err)
+	assert.False(t, respIsAdmin.GetIsAdmin())
+}
+
+func TestRoles_NotAdmin_FailCase(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "user@test.com",
+		Password: "test",
+	})
+	require.NoError(t, err)
+
+	_, err = testSuite.AuthClient.CreateRole(ctx, &ssov1.CreateRoleRequest{
+		Token: respLogin.GetAccessToken(),
+		Name:  "role-" + gofakeit.UUID(),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = testSuite.AuthClient.GrantRole(ctx, &ssov1.GrantRoleRequest{
+		Token:  respLogin.GetAccessToken(),
+		UserId: 1,
+		Role:   "admin",
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestRoles_TenantIsolation(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	respAdmin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "admin@test.com",
+		Password: "test",
+	})
+	require.NoError(t, err)
+	respAcmeAdmin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "admin@test.com",
+		Password: "test",
+		TenantId: acmeTenantID,
+	})
+	require.NoError(t, err)
+
+	roleName := "calc-operator-" + gofakeit.UUID()
+	_, err = testSuite.AuthClient.CreateRole(ctx, &ssov1.CreateRoleRequest{
+		Token:       respAdmin.GetAccessToken(),
+		Name:        roleName,
+		Permissions: []string{"*:*"},
+	})
+	require.NoError(t, err)
+
+	email := gofakeit.Email()
+	respReg, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: suite.RandomFakePassword(),
+		TenantId: acmeTenantID,
+	})
+	require.NoError(t, err)
+
+	// the role of the default tenant doesn't exist in acme
+	_, err = testSuite.AuthClient.GrantRole(ctx, &ssov1.GrantRoleRequest{
+		Token:  respAcmeAdmin.GetAccessToken(),
+		UserId: respReg.GetUserId(),
+		Role:   roleName,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// role names are unique within tenant only
+	_, err = testSuite.AuthClient.CreateRole(ctx, &ssov1.CreateRoleRequest{
+		Token:       respAcmeAdmin.GetAccessToken(),
+		Name:        roleName,
+		Permissions: []string{"read:calc:tasks:*"},
+	})
+	require.NoError(t, err)
+	_, err = testSuite.AuthClient.GrantRole(ctx, &ssov1.GrantRoleRequest{
+		Token:  respAcmeAdmin.GetAccessToken(),
+		UserId: respReg.GetUserId(),
+		Role:   roleName,
+	})
+	require.NoError(t, err)
+
+	respPermissions, err := testSuite.AuthClient.GetUserPermissions(ctx, &ssov1.GetUserPermissionsRequest{
+		Token:  respAcmeAdmin.GetAccessToken(),
+		UserId: respReg.GetUserId(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{roleName}, respPermissions.GetRoles())
+	assert.Equal(t, []string{"read:calc:tasks:*"}, respPermissions.GetPermissions())
+}
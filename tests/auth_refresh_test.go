@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	ssov1 "sso/protos/proto/sso/gen"
+	"sso/tests/suite"
+	"testing"
+)
+
+func TestRefresh_Rotation_HappyPath(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "user@test.com",
+		Password: "test",
+	})
+	require.NoError(t, err)
+
+	respRefresh, err := testSuite.AuthClient.Refresh(ctx, &ssov1.RefreshRequest{
+		RefreshToken: respLogin.GetRefreshToken(),
+	})
+	require.NoError(t, err)
+	require.NotEqual(t, respLogin.GetRefreshToken(), respRefresh.GetRefreshToken())
+
+	respRefresh, err = testSuite.AuthClient.Refresh(ctx, &ssov1.RefreshRequest{
+		RefreshToken: respRefresh.GetRefreshToken(),
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, respRefresh.GetAccessToken())
+}
+
+func TestRefresh_ReuseRevokesFamily_FailCase(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "user@test.com",
+		Password: "test",
+	})
+	require.NoError(t, err)
+	stolenToken := respLogin.GetRefreshToken()
+
+	// legitimate client rotates the token
+	respRefresh, err := testSuite.AuthClient.Refresh(ctx, &ssov1.RefreshRequest{
+		RefreshToken: stolenToken,
+	})
+	require.NoError(t, err)
+
+	// attacker replays the stolen token
+	_, err = testSuite.AuthClient.Refresh(ctx, &ssov1.RefreshRequest{
+		RefreshToken: stolenToken,
+	})
+	require.Error(t, err)
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// the whole family is revoked, descendants included
+	_, err = testSuite.AuthClient.Refresh(ctx, &ssov1.RefreshRequest{
+		RefreshToken: respRefresh.GetRefreshToken(),
+	})
+	require.Error(t, err)
+	_, err = testSuite.AuthClient.Validate(ctx, &ssov1.ValidateRequest{
+		Token: respRefresh.GetAccessToken(),
+	})
+	require.Error(t, err)
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/stretchr/testify/require"
 	ssov1 "sso/protos/proto/sso/gen"
 	"sso/tests/suite"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -42,7 +43,7 @@ func TestRegisterLogin_Login_HappyPath(t *testing.T) {
 	require.True(t, ok)
 
 	// check out token consists correct information
-	assert.Equal(t, respReg.GetUserId(), int64(claims["uid"].(float64)))
+	assert.Equal(t, strconv.FormatInt(respReg.GetUserId(), 10), claims["sub"].(string))
 	assert.Equal(t, email, claims["email"].(string))
 
 	// checking token expiration time might be only approximate
@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	ssov1 "sso/protos/proto/sso/gen"
+	"sso/tests/suite"
+	"testing"
+	"time"
+)
+
+func TestRevokeAllSessions_HappyPath(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	email := gofakeit.Email()
+	password := suite.RandomFakePassword()
+	respReg, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	// two devices
+	respLogin1, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+	respLogin2, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	respRevoke, err := testSuite.AuthClient.RevokeAllSessions(ctx, &ssov1.RevokeAllSessionsRequest{
+		Token:  respLogin1.GetAccessToken(),
+		UserId: respReg.GetUserId(),
+	})
+	require.NoError(t, err)
+	require.True(t, respRevoke.GetSuccess())
+
+	_, err = testSuite.AuthClient.Validate(ctx, &ssov1.ValidateRequest{Token: respLogin1.GetAccessToken()})
+	require.Error(t, err)
+	_, err = testSuite.AuthClient.Validate(ctx, &ssov1.ValidateRequest{Token: respLogin2.GetAccessToken()})
+	require.Error(t, err)
+	_, err = testSuite.AuthClient.Refresh(ctx, &ssov1.RefreshRequest{RefreshToken: respLogin2.GetRefreshToken()})
+	require.Error(t, err)
+
+	// tokens issued after revocation are valid
+	time.Sleep(time.Second)
+	respLogin3, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+	_, err = testSuite.AuthClient.Validate(ctx, &ssov1.ValidateRequest{Token: respLogin3.GetAccessToken()})
+	require.NoError(t, err)
+}
+
+func TestRevokeAllSessions_OtherUser_FailCase(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	email := gofakeit.Email()
+	password := suite.RandomFakePassword()
+	_, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	// regular user can't revoke sessions of the admin
+	_, err = testSuite.AuthClient.RevokeAllSessions(ctx, &ssov1.RevokeAllSessionsRequest{
+		Token:  respLogin.GetAccessToken(),
+		UserId: 1,
+	})
+	require.Error(t, err)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestListRevokeSession_HappyPath(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	email := gofakeit.Email()
+	password := suite.RandomFakePassword()
+	respReg, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	respLogin1, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+	respLogin2, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	respList, err := testSuite.AuthClient.ListSessions(ctx, &ssov1.ListSessionsRequest{
+		Token:  respLogin1.GetAccessToken(),
+		UserId: respReg.GetUserId(),
+	})
+	require.NoError(t, err)
+	require.Len(t, respList.GetSessions(), 2)
+
+	var otherSessionID string
+	for _, session := range respList.GetSessions() {
+		if !session.GetCurrent() {
+			otherSessionID = session.GetSessionId()
+		}
+	}
+	require.NotEmpty(t, otherSessionID)
+
+	respRevoke, err := testSuite.AuthClient.RevokeSession(ctx, &ssov1.RevokeSessionRequest{
+		Token:     respLogin1.GetAccessToken(),
+		SessionId: otherSessionID,
+	})
+	require.NoError(t, err)
+	require.True(t, respRevoke.GetSuccess())
+
+	// the second device is logged out, the first one still works
+	_, err = testSuite.AuthClient.Refresh(ctx, &ssov1.RefreshRequest{RefreshToken: respLogin2.GetRefreshToken()})
+	require.Error(t, err)
+	_, err = testSuite.AuthClient.Validate(ctx, &ssov1.ValidateRequest{Token: respLogin1.GetAccessToken()})
+	require.NoError(t, err)
+
+	respList, err = testSuite.AuthClient.ListSessions(ctx, &ssov1.ListSessionsRequest{
+		Token:  respLogin1.GetAccessToken(),
+		UserId: respReg.GetUserId(),
+	})
+	require.NoError(t, err)
+	require.Len(t, respList.GetSessions(), 1)
+	require.True(t, respList.GetSessions()[0].GetCurrent())
+}
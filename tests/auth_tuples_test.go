@@ -0,0 +1,233 @@
+package tests
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	ssov1 "sso/protos/proto/sso/gen"
+	"sso/tests/suite"
+	"strings"
+	"testing"
+)
+
+func TestTuples_CheckAndListObjects(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	respAdmin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "admin@test.com",
+		Password: "test",
+	})
+	require.NoError(t, err)
+
+	owner, member := "user:"+gofakeit.UUID(), "user:"+gofakeit.UUID()
+	ownedTask, sharedTask := "calc_task:"+gofakeit.UUID(), "calc_task:"+gofakeit.UUID()
+	project := "calc_project:" + gofakeit.UUID()
+	tuples := []*ssov1.RelationTuple{
+		{Object: ownedTask, Relation: "owner", Subject: owner},
+		{Object: project, Relation: "member", Subject: member},
+		{Object: sharedTask, Relation: "editor", Subject: project + "#member"},
+	}
+	_, err = testSuite.AuthClient.WriteTuples(ctx, &ssov1.WriteTuplesRequest{
+		Token:  respAdmin.GetAccessToken(),
+		Tuples: tuples,
+	})
+	require.NoError(t, err)
+
+	checks := []struct {
+		object   string
+		relation string
+		subject  string
+		allowed  bool
+	}{
+		// owner implies editor implies viewer
+		{object: ownedTask, relation: "viewer", subject: owner, allowed: true},
+		{object: ownedTask, relation: "editor", subject: owner, allowed: true},
+		// members of the project are editors of the shared task
+		{object: sharedTask, relation: "viewer", subject: member, allowed: true},
+		{object: sharedTask, relation: "owner", subject: member, allowed: false},
+		{object: ownedTask, relation: "viewer", subject: member, allowed: false},
+	}
+	for _, check := range checks {
+		respCheck, err := testSuite.AuthClient.Check(ctx, &ssov1.CheckRequest{
+			Token:    respAdmin.GetAccessToken(),
+			Object:   check.object,
+			Relation: check.relation,
+			Subject:  check.subject,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, check.allowed, respCheck.GetAllowed(), "%s#%s@%s", check.object, check.relation, check.subject)
+	}
+
+	respObjects, err := testSuite.AuthClient.ListObjects(ctx, &ssov1.ListObjectsRequest{
+		Token:      respAdmin.GetAccessToken(),
+		ObjectType: "calc_task",
+		Relation:   "viewer",
+		Subject:    member,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{sharedTask}, respObjects.GetObjects())
+
+	_, err = testSuite.AuthClient.DeleteTuples(ctx, &ssov1.DeleteTuplesRequest{
+		Token:  respAdmin.GetAccessToken(),
+		Tuples: tuples[1:2],
+	})
+	require.NoError(t, err)
+	respCheck, err := testSuite.AuthClient.Check(ctx, &ssov1.CheckRequest{
+		Token:    respAdmin.GetAccessToken(),
+		Object:   sharedTask,
+		Relation: "viewer",
+		Subject:  member,
+	})
+	require.NoError(t, err)
+	assert.False(t, respCheck.GetAllowed())
+}
+
+func TestTuples_FailCases(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "user@test.com",
+		Password: "test",
+	})
+	require.NoError(t, err)
+
+	_, err = testSuite.AuthClient.WriteTuples(ctx, &ssov1.WriteTuplesRequest{
+		Token:  respLogin.GetAccessToken(),
+		Tuples: []*ssov1.RelationTuple{{Object: "calc_task:1", Relation: "owner", Subject: "user:1"}},
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = testSuite.AuthClient.Check(ctx, &ssov1.CheckRequest{
+		Token:    respLogin.GetAccessToken(),
+		Object:   "calc_task",
+		Relation: "viewer",
+		Subject:  "user:1",
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = testSuite.AuthClient.Check(ctx, &ssov1.CheckRequest{
+		Object:   "calc_task:1",
+		Relation: "viewer",
+		Subject:  "user:1",
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = testSuite.AuthClient.ListObjects(ctx, &ssov1.ListObjectsRequest{
+		Token:      "bad token",
+		ObjectType: "calc_task",
+		Relation:   "viewer",
+		Subject:    "user:1",
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestTuples_TenantIsolation(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	respAdmin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "admin@test.com",
+		Password: "test",
+	})
+	require.NoError(t, err)
+
+	email, password := gofakeit.Email(), suite.RandomFakePassword()
+	_, err = testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+		TenantId: acmeTenantID,
+	})
+	require.NoError(t, err)
+	respAcme, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		TenantId: acmeTenantID,
+	})
+	require.NoError(t, err)
+
+	owner, task := "user:"+gofakeit.UUID(), "calc_task:"+gofakeit.UUID()
+	_, err = testSuite.AuthClient.WriteTuples(ctx, &ssov1.WriteTuplesRequest{
+		Token:  respAdmin.GetAccessToken(),
+		Tuples: []*ssov1.RelationTuple{{Object: task, Relation: "owner", Subject: owner}},
+	})
+	require.NoError(t, err)
+
+	// the tuple belongs to the default tenant and is invisible to acme
+	respCheck, err := testSuite.AuthClient.Check(ctx, &ssov1.CheckRequest{
+		Token:    respAcme.GetAccessToken(),
+		Object:   task,
+		Relation: "viewer",
+		Subject:  owner,
+	})
+	require.NoError(t, err)
+	assert.False(t, respCheck.GetAllowed())
+
+	respObjects, err := testSuite.AuthClient.ListObjects(ctx, &ssov1.ListObjectsRequest{
+		Token:      respAcme.GetAccessToken(),
+		ObjectType: "calc_task",
+		Relation:   "viewer",
+		Subject:    owner,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, respObjects.GetObjects())
+
+	respCheck, err = testSuite.AuthClient.Check(ctx, &ssov1.CheckRequest{
+		Token:    respAdmin.GetAccessToken(),
+		Object:   task,
+		Relation: "viewer",
+		Subject:  owner,
+	})
+	require.NoError(t, err)
+	assert.True(t, respCheck.GetAllowed())
+}
+
+func TestTuples_CheckSharedUserset(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	respAdmin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "admin@test.com",
+		Password: "test",
+	})
+	require.NoError(t, err)
+
+	member := "user:" + gofakeit.UUID()
+	task := "calc_task:" + gofakeit.UUID()
+	shared, inner := "calc_project:"+gofakeit.UUID(), "calc_project:"+gofakeit.UUID()
+
+	// the shared project is reached through a long chain first, too deep
+	// to get to the member, and then directly from the task
+	var tuples []*ssov1.RelationTuple
+	parent := task + "#editor"
+	for i := 0; i < 6; i++ {
+		project := "calc_project:" + gofakeit.UUID()
+		object, relation, _ := strings.Cut(parent, "#")
+		tuples = append(tuples, &ssov1.RelationTuple{Object: object, Relation: relation, Subject: project + "#member"})
+		parent = project + "#member"
+	}
+	object, relation, _ := strings.Cut(parent, "#")
+	tuples = append(tuples,
+		&ssov1.RelationTuple{Object: object, Relation: relation, Subject: shared + "#member"},
+		&ssov1.RelationTuple{Object: shared, Relation: "member", Subject: inner + "#member"},
+		&ssov1.RelationTuple{Object: inner, Relation: "member", Subject: member},
+		&ssov1.RelationTuple{Object: task, Relation: "editor", Subject: shared + "#member"},
+	)
+	_, err = testSuite.AuthClient.WriteTuples(ctx, &ssov1.WriteTuplesRequest{
+		Token:  respAdmin.GetAccessToken(),
+		Tuples: tuples,
+	})
+	require.NoError(t, err)
+
+	respCheck, err := testSuite.AuthClient.Check(ctx, &ssov1.CheckRequest{
+		Token:    respAdmin.GetAccessToken(),
+		Object:   task,
+		Relation: "viewer",
+		Subject:  member,
+	})
+	require.NoError(t, err)
+	assert.True(t, respCheck.GetAllowed())
+}
@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	ssov1 "sso/protos/proto/sso/gen"
+	"sso/tests/suite"
+	"testing"
+)
+
+func TestVerifyEmail_HappyPath(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	email := gofakeit.Email()
+	password := suite.RandomFakePassword()
+	respReg, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+	require.NoError(t, err)
+
+	respUserInfo := func() *ssov1.UserInfoResponse {
+		respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+		require.NoError(t, err)
+		resp, err := testSuite.AuthClient.UserInfo(ctx, &ssov1.UserInfoRequest{AccessToken: respLogin.GetAccessToken()})
+		require.NoError(t, err)
+		return resp
+	}
+	assert.False(t, respUserInfo().GetEmailVerified())
+
+	token := testSuite.ActionToken("email_verification", respReg.GetUserId(), email)
+	respVerify, err := testSuite.AuthClient.VerifyEmail(ctx, &ssov1.VerifyEmailRequest{Token: token})
+	require.NoError(t, err)
+	assert.True(t, respVerify.GetSuccess())
+	assert.True(t, respUserInfo().GetEmailVerified())
+
+	// links are single-use
+	_, err = testSuite.AuthClient.VerifyEmail(ctx, &ssov1.VerifyEmailRequest{Token: token})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestVerifyEmail_WrongEmail(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	respReg, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    gofakeit.Email(),
+		Password: suite.RandomFakePassword(),
+	})
+	require.NoError(t, err)
+
+	token := testSuite.ActionToken("email_verification", respReg.GetUserId(), gofakeit.Email())
+	_, err = testSuite.AuthClient.VerifyEmail(ctx, &ssov1.VerifyEmailRequest{Token: token})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestVerifyEmail_AccessTokenRejected(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "user@test.com",
+		Password: "test",
+	})
+	require.NoError(t, err)
+
+	_, err = testSuite.AuthClient.VerifyEmail(ctx, &ssov1.VerifyEmailRequest{Token: respLogin.GetAccessToken()})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestResendVerification_Throttled(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	email := gofakeit.Email()
+	_, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: suite.RandomFakePassword()})
+	require.NoError(t, err)
+
+	respResend, err := testSuite.AuthClient.ResendVerification(ctx, &ssov1.ResendVerificationRequest{Email: email})
+	require.NoError(t, err)
+	assert.True(t, respResend.GetSuccess())
+
+	_, err = testSuite.AuthClient.ResendVerification(ctx, &ssov1.ResendVerificationRequest{Email: email})
+	require.Error(t, err)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// unknown emails look the same as registered ones
+	respResend, err = testSuite.AuthClient.ResendVerification(ctx, &ssov1.ResendVerificationRequest{Email: gofakeit.Email()})
+	require.NoError(t, err)
+	assert.True(t, respResend.GetSuccess())
+}
+
+func TestRegister_InvalidEmail(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	_, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:    "not an email",
+		Password: suite.RandomFakePassword(),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
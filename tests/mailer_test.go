@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"context"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sso/internal/config"
+	"sso/internal/lib/mailer"
+	ssov1 "sso/protos/proto/sso/gen"
+	"sso/tests/suite"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// findEmail waits for email to the recipient written by file mailer.
+func findEmail(t *testing.T, dir string, to string) string {
+	return findEmailWithSubject(t, dir, to, "")
+}
+
+// findEmailWithSubject is findEmail skipping emails with another subject.
+func findEmailWithSubject(t *testing.T, dir string, to string, subject string) string {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+		for _, file := range files {
+			body, err := os.ReadFile(file)
+			if err == nil && strings.Contains(string(body), "To: "+to+"\r\n") &&
+				strings.Contains(string(body), "Subject: "+subject) {
+				return string(body)
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("no email to %s in %s", to, dir)
+	return ""
+}
+
+func TestMailer_VerificationEmail(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+	if testSuite.Cfg.Mailer.Driver != "file" {
+		t.Skip("emails can be read only with file mailer")
+	}
+
+	email := gofakeit.Email()
+	_, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: suite.RandomFakePassword()})
+	require.NoError(t, err)
+
+	body := findEmail(t, testSuite.Cfg.Mailer.Dir, email)
+	assert.Contains(t, body, "Subject: Confirm your email")
+	assert.Contains(t, body, testSuite.Cfg.OIDC.Issuer+"/sso/email/verify?token=")
+	assert.Contains(t, body, "Content-Type: text/html")
+}
+
+// countingMailer counts delivered messages.
+type countingMailer struct {
+	mu   sync.Mutex
+	sent int
+}
+
+func (m *countingMailer) Send(_ context.Context, _ mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent++
+	return nil
+}
+
+func TestMailer_SendAfterClose(t *testing.T) {
+	t.Parallel()
+
+	driver := &countingMailer{}
+	async := mailer.NewAsync(driver, slog.New(slog.NewTextHandler(io.Discard, nil)), config.MailerConfig{
+		QueueSize: 10,
+		Workers:   2,
+	})
+	require.NoError(t, async.Send(context.Background(), mailer.Message{To: gofakeit.Email()}))
+	async.Close()
+	// queued messages are delivered before Close returns
+	assert.Equal(t, 1, driver.sent)
+
+	// senders racing with shutdown get an error instead of a panic
+	err := async.Send(context.Background(), mailer.Message{To: gofakeit.Email()})
+	assert.ErrorIs(t, err, mailer.ErrClosed)
+	async.Close()
+}
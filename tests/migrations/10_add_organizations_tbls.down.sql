@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS organization_members;
+
+-- fails if the same email has been registered in several tenants
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS users_tenant_id_email_key;
+ALTER TABLE users
+    ADD CONSTRAINT users_email_key UNIQUE (email);
+ALTER TABLE users
+    DROP COLUMN IF EXISTS tenant_id;
+
+DROP TABLE IF EXISTS organizations;
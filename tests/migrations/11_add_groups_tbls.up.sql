@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS groups
+(
+    id         serial PRIMARY KEY,
+    tenant_id  INT         NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    name       TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (tenant_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS group_members
+(
+    group_id   INT         NOT NULL REFERENCES groups (id) ON DELETE CASCADE,
+    user_id    INT         NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (group_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_members_user_id ON group_members (user_id);
+
+-- members of child group are members of parent group as well
+CREATE TABLE IF NOT EXISTS group_subgroups
+(
+    parent_id  INT         NOT NULL REFERENCES groups (id) ON DELETE CASCADE,
+    child_id   INT         NOT NULL REFERENCES groups (id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (parent_id, child_id),
+    CHECK (parent_id <> child_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_subgroups_child_id ON group_subgroups (child_id);
@@ -0,0 +1,13 @@
+-- fails if the same tuple has been written in several tenants
+DROP INDEX IF EXISTS idx_relation_tuples_subject;
+CREATE INDEX IF NOT EXISTS idx_relation_tuples_subject ON relation_tuples (subject);
+
+ALTER TABLE relation_tuples
+    DROP CONSTRAINT IF EXISTS relation_tuples_pkey;
+ALTER TABLE relation_tuples
+    ADD PRIMARY KEY (object_type, object_id, relation, subject);
+ALTER TABLE relation_tuples
+    DROP COLUMN IF EXISTS tenant_id;
+
+ALTER TABLE apps
+    DROP COLUMN IF EXISTS tenant_id;
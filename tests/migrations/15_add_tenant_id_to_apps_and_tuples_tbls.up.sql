@@ -0,0 +1,14 @@
+-- apps belong to the tenant of the admin who registered them, relation
+-- tuples to the tenant of their writer, existing ones to the default tenant
+ALTER TABLE apps
+    ADD COLUMN IF NOT EXISTS tenant_id INT NOT NULL DEFAULT 1 REFERENCES organizations (id);
+
+ALTER TABLE relation_tuples
+    ADD COLUMN IF NOT EXISTS tenant_id INT NOT NULL DEFAULT 1 REFERENCES organizations (id);
+ALTER TABLE relation_tuples
+    DROP CONSTRAINT IF EXISTS relation_tuples_pkey;
+ALTER TABLE relation_tuples
+    ADD PRIMARY KEY (tenant_id, object_type, object_id, relation, subject);
+
+DROP INDEX IF EXISTS idx_relation_tuples_subject;
+CREATE INDEX IF NOT EXISTS idx_relation_tuples_subject ON relation_tuples (tenant_id, subject);
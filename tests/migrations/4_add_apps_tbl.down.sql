@@ -0,0 +1 @@
+DROP TABLE IF EXISTS apps;
\ No newline at end of file
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS apps
+(
+    id     serial PRIMARY KEY,
+    name   TEXT NOT NULL UNIQUE,
+    secret TEXT NOT NULL
+);
+
+INSERT INTO apps(name, secret)
+VALUES ('calculator', 'calculator-secret')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,6 @@
+ALTER TABLE apps
+    ADD COLUMN IF NOT EXISTS redirect_uris TEXT[] NOT NULL DEFAULT '{}';
+
+UPDATE apps
+SET redirect_uris = '{"http://localhost:8080/callback"}'
+WHERE name = 'calculator';
@@ -0,0 +1,11 @@
+-- client secrets are random high entropy strings, so sha256 is enough
+-- and keeps client authentication fast
+ALTER TABLE apps
+    ADD COLUMN IF NOT EXISTS secret_hash BYTEA;
+
+UPDATE apps
+SET secret_hash = sha256(convert_to(secret, 'UTF8'));
+
+ALTER TABLE apps
+    ALTER COLUMN secret_hash SET NOT NULL,
+    DROP COLUMN IF EXISTS secret;
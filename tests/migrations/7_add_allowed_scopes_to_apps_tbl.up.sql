@@ -0,0 +1,6 @@
+ALTER TABLE apps
+    ADD COLUMN IF NOT EXISTS allowed_scopes TEXT[] NOT NULL DEFAULT '{}';
+
+UPDATE apps
+SET allowed_scopes = '{"calc:read", "calc:write"}'
+WHERE name = 'calculator';
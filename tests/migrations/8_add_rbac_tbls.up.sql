@@ -0,0 +1,43 @@
+CREATE TABLE IF NOT EXISTS roles
+(
+    id          serial PRIMARY KEY,
+    name        TEXT NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions
+(
+    role_id  INT  NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    action   TEXT NOT NULL,
+    resource TEXT NOT NULL,
+    PRIMARY KEY (role_id, action, resource)
+);
+
+-- users are partitioned by email, so user_id can't reference them
+CREATE TABLE IF NOT EXISTS user_roles
+(
+    user_id INT NOT NULL,
+    role_id INT NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, role_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_roles_role_id ON user_roles (role_id);
+
+-- is_admin flag becomes the admin role with every permission
+INSERT INTO roles(name, description)
+VALUES ('admin', 'Full access')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions(role_id, action, resource)
+SELECT id, '*', '*'
+FROM roles
+WHERE name = 'admin'
+ON CONFLICT DO NOTHING;
+
+INSERT INTO user_roles(user_id, role_id)
+SELECT u.id, r.id
+FROM users u,
+     roles r
+WHERE u.is_admin
+  AND r.name = 'admin'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS relation_tuples
+(
+    object_type TEXT NOT NULL,
+    object_id   TEXT NOT NULL,
+    relation    TEXT NOT NULL,
+    subject     TEXT NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (object_type, object_id, relation, subject)
+);
+
+-- reverse lookups for ListObjects
+CREATE INDEX IF NOT EXISTS idx_relation_tuples_subject ON relation_tuples (subject);
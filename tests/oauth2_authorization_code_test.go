@@ -0,0 +1,186 @@
+package tests
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/url"
+	ssov1 "sso/protos/proto/sso/gen"
+	"sso/tests/suite"
+	"strings"
+	"testing"
+)
+
+const codeVerifier = "dBjftJeZ4CVP-mJ92K9R6tmEr4xCj3GhO0vTf3nQ-Zcm5z"
+
+// httpClient doesn't follow redirects, so redirects to the app can be checked
+var httpClient = &http.Client{
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func authorizationParams() url.Values {
+	return url.Values{
+		"response_type":         {"code"},
+		"client_id":             {suite.AppID},
+		"redirect_uri":          {suite.AppRedirectURI},
+		"scope":                 {"openid email"},
+		"state":                 {"xyz"},
+		"nonce":                 {"n-0S6_WzA2Mj"},
+		"code_challenge":        {codeChallenge(codeVerifier)},
+		"code_challenge_method": {"S256"},
+	}
+}
+
+// authorize logs in through the login page and returns authorization code.
+func authorize(t *testing.T, testSuite *suite.Suite) string {
+	form := authorizationParams()
+	form.Set("email", "user@test.com")
+	form.Set("password", "test")
+	resp, err := httpClient.PostForm(testSuite.Cfg.OIDC.Issuer+"/oauth2/authorize", form)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(location.String(), suite.AppRedirectURI))
+	assert.Equal(t, "xyz", location.Query().Get("state"))
+	require.NotEmpty(t, location.Query().Get("code"))
+	return location.Query().Get("code")
+}
+
+func exchangeCode(t *testing.T, testSuite *suite.Suite, code string, verifier string) (*http.Response, map[string]any) {
+	resp, err := httpClient.PostForm(testSuite.Cfg.OIDC.Issuer+"/oauth2/token", url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {suite.AppRedirectURI},
+		"client_id":     {suite.AppID},
+		"code_verifier": {verifier},
+	})
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body := map[string]any{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	return resp, body
+}
+
+func TestAuthorizationCode_HappyPath(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	resp, err := httpClient.Get(testSuite.Cfg.OIDC.Issuer + "/oauth2/authorize?" + authorizationParams().Encode())
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	code := authorize(t, testSuite)
+	resp, body := exchangeCode(t, testSuite, code, codeVerifier)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "Bearer", body["token_type"])
+	require.NotEmpty(t, body["refresh_token"])
+
+	_, err = testSuite.AuthClient.Validate(ctx, &ssov1.ValidateRequest{Token: body["access_token"].(string)})
+	require.NoError(t, err)
+
+	idToken, _, err := jwt.NewParser().ParseUnverified(body["id_token"].(string), jwt.MapClaims{})
+	require.NoError(t, err)
+	claims := idToken.Claims.(jwt.MapClaims)
+	assert.Equal(t, suite.AppID, claims["aud"])
+	assert.Equal(t, "n-0S6_WzA2Mj", claims["nonce"])
+
+	// authorization code is single use
+	resp, body = exchangeCode(t, testSuite, code, codeVerifier)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "invalid_grant", body["error"])
+}
+
+func TestAuthorizationCode_WrongVerifier_FailCase(t *testing.T) {
+	_, testSuite := suite.New(t)
+
+	code := authorize(t, testSuite)
+	resp, body := exchangeCode(t, testSuite, code, strings.Repeat("a", 43))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "invalid_grant", body["error"])
+}
+
+func TestAuthorizationCode_UnregisteredRedirect_FailCase(t *testing.T) {
+	_, testSuite := suite.New(t)
+
+	params := authorizationParams()
+	params.Set("redirect_uri", "https://evil.example.com/callback")
+	resp, err := httpClient.Get(testSuite.Cfg.OIDC.Issuer + "/oauth2/authorize?" + params.Encode())
+	require.NoError(t, err)
+	resp.Body.Close()
+	// never redirect to unregistered uri
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Location"))
+}
+
+func TestAuthorizationCode_InvalidCredentials_FailCase(t *testing.T) {
+	_, testSuite := suite.New(t)
+
+	form := authorizationParams()
+	form.Set("email", "user@test.com")
+	form.Set("password", "wrong password")
+	resp, err := httpClient.PostForm(testSuite.Cfg.OIDC.Issuer+"/oauth2/authorize", form)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Location"))
+}
+
+func refreshGrant(t *testing.T, testSuite *suite.Suite, form url.Values) (*http.Response, map[string]any) {
+	form.Set("grant_type", "refresh_token")
+	resp, err := httpClient.PostForm(testSuite.Cfg.OIDC.Issuer+"/oauth2/token", form)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body := map[string]any{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	return resp, body
+}
+
+func TestAuthorizationCode_RefreshGrant(t *testing.T) {
+	_, testSuite := suite.New(t)
+
+	_, body := exchangeCode(t, testSuite, authorize(t, testSuite), codeVerifier)
+	refreshToken := body["refresh_token"].(string)
+
+	// client_id is required, refresh token is bound to the client
+	resp, body := refreshGrant(t, testSuite, url.Values{"refresh_token": {refreshToken}})
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, "invalid_client", body["error"])
+
+	resp, body = refreshGrant(t, testSuite, url.Values{
+		"refresh_token": {refreshToken},
+		"client_id":     {"999999"},
+	})
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, "invalid_client", body["error"])
+
+	resp, body = refreshGrant(t, testSuite, url.Values{
+		"refresh_token": {refreshToken},
+		"client_id":     {suite.AppID},
+		"client_secret": {"wrong secret"},
+	})
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, "invalid_client", body["error"])
+
+	resp, body = refreshGrant(t, testSuite, url.Values{
+		"refresh_token": {refreshToken},
+		"client_id":     {suite.AppID},
+		"client_secret": {suite.AppSecret},
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEmpty(t, body["access_token"])
+	assert.NotEmpty(t, body["refresh_token"])
+}
@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"encoding/json"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"net/url"
+	ssov1 "sso/protos/proto/sso/gen"
+	"sso/tests/suite"
+	"strconv"
+	"testing"
+)
+
+func requestClientToken(t *testing.T, testSuite *suite.Suite, clientID string, clientSecret string) (int, map[string]any) {
+	resp, err := http.PostForm(testSuite.Cfg.OIDC.Issuer+"/oauth2/token", url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {clientID},
+		"client_secret": {clientSecret},
+	})
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body := map[string]any{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	return resp.StatusCode, body
+}
+
+func TestClientCredentials_HappyPath(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	code, body := requestClientToken(t, testSuite, suite.AppID, suite.AppSecret)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Bearer", body["token_type"])
+	assert.Nil(t, body["refresh_token"])
+	accessToken := body["access_token"].(string)
+
+	_, err := testSuite.AuthClient.Validate(ctx, &ssov1.ValidateRequest{Token: accessToken})
+	require.NoError(t, err)
+
+	respIntrospect, err := testSuite.AuthClient.Introspect(ctx, &ssov1.IntrospectRequest{
+		Token:        accessToken,
+		ClientId:     suite.AppID,
+		ClientSecret: suite.AppSecret,
+	})
+	require.NoError(t, err)
+	assert.True(t, respIntrospect.GetActive())
+	assert.Equal(t, suite.AppID, respIntrospect.GetSub())
+	assert.Equal(t, suite.AppID, respIntrospect.GetClientId())
+	assert.Empty(t, respIntrospect.GetEmail())
+
+	// app token doesn't belong to any user
+	_, err = testSuite.AuthClient.UserInfo(ctx, &ssov1.UserInfoRequest{AccessToken: accessToken})
+	require.Error(t, err)
+}
+
+func TestClientCredentials_WrongSecret_FailCase(t *testing.T) {
+	_, testSuite := suite.New(t)
+
+	code, body := requestClientToken(t, testSuite, suite.AppID, "wrong-secret")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, "invalid_client", body["error"])
+}
+
+func TestRegisterApp_HappyPath(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "admin@test.com",
+		Password: "test",
+	})
+	require.NoError(t, err)
+
+	respApp, err := testSuite.AuthClient.RegisterApp(ctx, &ssov1.RegisterAppRequest{
+		Token:        respLogin.GetAccessToken(),
+		Name:         gofakeit.AppName() + gofakeit.UUID(),
+		RedirectUris: []string{"https://app.example.com/callback"},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, respApp.GetClientSecret())
+
+	code, _ := requestClientToken(t, testSuite, strconv.FormatInt(respApp.GetClientId(), 10), respApp.GetClientSecret())
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestRegisterApp_NotAdmin_FailCase(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    "user@test.com",
+		Password: "test",
+	})
+	require.NoError(t, err)
+
+	_, err = testSuite.AuthClient.RegisterApp(ctx, &ssov1.RegisterAppRequest{
+		Token: respLogin.GetAccessToken(),
+		Name:  gofakeit.AppName() + gofakeit.UUID(),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"context"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"io"
+	"log/slog"
+	"net"
+	"sso/internal/config"
+	"sso/internal/lib/gateway"
+	"sso/internal/lib/ratelimit"
+	ssov1 "sso/protos/proto/sso/gen"
+	"sso/tests/suite"
+	"testing"
+	"time"
+)
+
+func TestRateLimit_PerUser(t *testing.T) {
+	ctx, testSuite := suite.New(t)
+
+	rule, ok := testSuite.Cfg.RateLimit.Methods["/auth.Auth/ExportMyData"]
+	if !testSuite.Cfg.RateLimit.Enabled || !ok || rule.Key != "user_id" {
+		t.Skip("ExportMyData isn't limited per user")
+	}
+	burst := rule.Burst
+	if burst <= 0 {
+		burst = rule.Requests
+	}
+
+	login := func() *ssov1.LoginResponse {
+		email := gofakeit.Email()
+		password := suite.RandomFakePassword()
+		_, err := testSuite.AuthClient.Register(ctx, &ssov1.RegisterRequest{Email: email, Password: password})
+		require.NoError(t, err)
+		respLogin, err := testSuite.AuthClient.Login(ctx, &ssov1.LoginRequest{Email: email, Password: password})
+		require.NoError(t, err)
+		return respLogin
+	}
+	first := login()
+
+	for i := 0; i < burst; i++ {
+		_, err := testSuite.AuthClient.ExportMyData(ctx, &ssov1.ExportMyDataRequest{Token: first.GetAccessToken()})
+		require.NoError(t, err)
+	}
+
+	var header metadata.MD
+	_, err := testSuite.AuthClient.ExportMyData(ctx, &ssov1.ExportMyDataRequest{
+		Token: first.GetAccessToken(),
+	}, grpc.Header(&header))
+	require.Error(t, err)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.NotEmpty(t, header.Get("retry-after"))
+
+	var retryInfo *errdetails.RetryInfo
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok {
+			retryInfo = info
+		}
+	}
+	require.NotNil(t, retryInfo)
+	assert.Positive(t, retryInfo.GetRetryDelay().AsDuration())
+
+	// buckets are per user, calls of others aren't affected
+	second := login()
+	_, err = testSuite.AuthClient.ExportMyData(ctx, &ssov1.ExportMyDataRequest{Token: second.GetAccessToken()})
+	require.NoError(t, err)
+}
+
+func TestRateLimit_ForwardedForOfUntrustedPeer(t *testing.T) {
+	const method = "/auth.Auth/Login"
+	proxies := gateway.MustTrustedProxies([]string{"127.0.0.1/32", "10.0.0.0/8"})
+	limiter := ratelimit.New(
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		config.RateLimitConfig{
+			Enabled: true,
+			Backend: "memory",
+			Methods: map[string]config.RateLimitRule{
+				method: {Requests: 1, Per: time.Minute, Key: ratelimit.KeyIP},
+			},
+		},
+		nil, nil, proxies,
+	)
+	interceptor := limiter.UnaryServerInterceptor()
+	call := func(peerIP string, forwardedFor string) error {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{
+			Addr: &net.TCPAddr{IP: net.ParseIP(peerIP), Port: 50000},
+		})
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-forwarded-for", forwardedFor))
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method},
+			func(ctx context.Context, req any) (any, error) { return nil, nil })
+		return err
+	}
+
+	// the header of a client is ignored, every spoofed address is limited
+	// by the address of the peer
+	require.NoError(t, call("203.0.113.7", "198.51.100.1"))
+	err := call("203.0.113.7", "198.51.100.2")
+	require.Error(t, err)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// trusted proxies pass the client, addresses left of the first
+	// untrusted one are set by the client
+	require.NoError(t, call("127.0.0.1", "198.51.100.3"))
+	require.NoError(t, call("127.0.0.1", "198.51.100.1, 198.51.100.4, 10.0.0.2"))
+	err = call("127.0.0.1", "198.51.100.2, 198.51.100.4, 10.0.0.2")
+	require.Error(t, err)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
@@ -5,3 +5,26 @@ go test auth_login_test.go
 go test auth_register_login_test.go
 go test auth_is_admin_test.go
 go test auth_register_test.go
+go test auth_jwks_test.go
+go test auth_refresh_test.go
+go test auth_sessions_test.go
+go test auth_introspect_test.go
+go test auth_oidc_test.go
+go test oauth2_authorization_code_test.go
+go test oauth2_client_credentials_test.go
+go test auth_audience_test.go
+go test auth_rbac_test.go
+go test auth_check_permission_test.go
+go test auth_tuples_test.go
+go test auth_organizations_test.go
+go test auth_groups_test.go
+go test auth_verify_email_test.go
+go test mailer_test.go
+go test auth_password_reset_test.go
+go test auth_change_password_test.go
+go test auth_change_email_test.go
+go test auth_account_test.go
+go test admin_users_test.go
+go test auth_lockout_test.go
+go test rate_limit_test.go
+go test auth_password_policy_test.go
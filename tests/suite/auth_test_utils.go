@@ -1,9 +1,20 @@
 package suite
 
-import "github.com/brianvoe/gofakeit/v6"
+import (
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"time"
+)
 
 const (
 	PasswordDefaultLen = 10
+	// app seeded by tests/migrations
+	AppID          = "1"
+	AppSecret      = "calculator-secret"
+	AppRedirectURI = "http://localhost:8080/callback"
 )
 
 func RandomFakePassword() string {
@@ -16,3 +27,35 @@ func RandomFakePassword() string {
 		PasswordDefaultLen,
 	)
 }
+
+// ActionToken signs token of an emailed link ("email_verification",
+// "password_reset") the way the service does, so tests don't need a mailbox.
+func (s *Suite) ActionToken(action string, userID int64, email string) string {
+	return s.signActionToken(action, userID, email, nil)
+}
+
+// EmailChangeToken signs token of the link confirming change of email.
+func (s *Suite) EmailChangeToken(userID int64, email string, newEmail string) string {
+	return s.signActionToken("email_change", userID, email, jwt.MapClaims{"new_email": newEmail})
+}
+
+func (s *Suite) signActionToken(action string, userID int64, email string, extra jwt.MapClaims) string {
+	claims := jwt.MapClaims{
+		"token_type": action,
+		"iss":        s.Cfg.OIDC.Issuer,
+		"sub":        strconv.FormatInt(userID, 10),
+		"email":      email,
+		"tenant_id":  1,
+		"iat":        time.Now().Unix(),
+		"jti":        uuid.NewString(),
+		"exp":        time.Now().Add(time.Hour).Unix(),
+	}
+	for name, value := range extra {
+		claims[name] = value
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = "default"
+	signed, err := token.SignedString([]byte(s.Cfg.ServiceSecret))
+	require.NoError(s.T, err)
+	return signed
+}
@@ -23,9 +23,10 @@ const (
 )
 
 type Suite struct {
-	*testing.T                  // потребуется для вызова методов *testing.T внутри Suite
-	Cfg        *config.Config   // Конфигурация приложения
-	AuthClient ssov1.AuthClient // Клиент для взаимодействия с grpc_transport - сервером
+	*testing.T                    // потребуется для вызова методов *testing.T внутри Suite
+	Cfg         *config.Config    // Конфигурация приложения
+	AuthClient  ssov1.AuthClient  // Клиент для взаимодействия с grpc_transport - сервером
+	AdminClient ssov1.AdminClient // client of user management service
 }
 
 var tracer = otel.Tracer("testing client")
@@ -78,9 +79,10 @@ func New(t *testing.T) (context.Context, *Suite) {
 	}
 
 	return ctx, &Suite{
-		T:          t,
-		Cfg:        cfg,
-		AuthClient: ssov1.NewAuthClient(cc),
+		T:           t,
+		Cfg:         cfg,
+		AuthClient:  ssov1.NewAuthClient(cc),
+		AdminClient: ssov1.NewAdminClient(cc),
 	}
 }
 